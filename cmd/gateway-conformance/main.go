@@ -2,38 +2,84 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"testing"
+	"time"
 
+	"github.com/ipfs/gateway-conformance/tests"
 	"github.com/ipfs/gateway-conformance/tooling"
+	"github.com/ipfs/gateway-conformance/tooling/baseline"
+	"github.com/ipfs/gateway-conformance/tooling/builder"
 	"github.com/ipfs/gateway-conformance/tooling/car"
+	"github.com/ipfs/gateway-conformance/tooling/catalog"
+	"github.com/ipfs/gateway-conformance/tooling/config"
+	"github.com/ipfs/gateway-conformance/tooling/dashboard"
+	"github.com/ipfs/gateway-conformance/tooling/diff"
 	"github.com/ipfs/gateway-conformance/tooling/dnslink"
 	"github.com/ipfs/gateway-conformance/tooling/fixtures"
+	"github.com/ipfs/gateway-conformance/tooling/har"
+	"github.com/ipfs/gateway-conformance/tooling/manifest"
+	"github.com/ipfs/gateway-conformance/tooling/mutate"
+	"github.com/ipfs/gateway-conformance/tooling/provision"
+	"github.com/ipfs/gateway-conformance/tooling/report"
+	"github.com/ipfs/gateway-conformance/tooling/results"
+	"github.com/ipfs/gateway-conformance/tooling/runner"
 	specPresets "github.com/ipfs/gateway-conformance/tooling/specs"
+	"github.com/ipfs/gateway-conformance/tooling/test"
+	"github.com/ipfs/gateway-conformance/tooling/test2json"
+	"github.com/ipfs/gateway-conformance/tooling/watch"
 	"github.com/urfave/cli/v2"
 )
 
 type out struct {
 	Writer io.Writer
-	Filter func(s string) bool
+	// Console receives the lines matching Filter. It is captured before the
+	// test run since os.Stdout is redirected to Writer while tests run.
+	Console io.Writer
+	Filter  func(s string) bool
 }
 
 func (o out) Write(p []byte) (n int, err error) {
 	if o.Filter != nil {
 		for _, line := range strings.Split(string(p), "\n") {
 			if o.Filter(line) {
-				os.Stdout.Write([]byte(fmt.Sprintf("%s\n", line)))
+				o.Console.Write([]byte(fmt.Sprintf("%s\n", line)))
 			}
 		}
 	}
 	return o.Writer.Write(p)
 }
 
+// writeFile creates the file at path, and its parent directories, and fills
+// it using write.
+func writeFile(path string, write func(w io.Writer) error) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = write(f)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
 func copyFiles(inputPaths []string, outputDirectoryPath string) error {
 	err := os.MkdirAll(outputDirectoryPath, 0755)
 	if err != nil {
@@ -99,6 +145,41 @@ func main() {
 						Usage:   "The path where the JSON test report should be generated.",
 						Value:   "",
 					},
+					&cli.StringFlag{
+						Name:    "results-output",
+						Aliases: []string{"results"},
+						Usage:   "The path where the structured JSON results, one entry per test and check, should be generated. See tooling/results/schema.json for the format.",
+						Value:   "",
+					},
+					&cli.StringFlag{
+						Name:    "har-output",
+						Aliases: []string{"har"},
+						Usage:   "The path where every request and response of the run should be recorded, in the HAR 1.2 format supported by browser devtools.",
+						Value:   "",
+					},
+					&cli.IntFlag{
+						Name:  "har-body-limit",
+						Usage: "The maximum number of bytes of a request or response body recorded in the HAR file.",
+						Value: 64 * 1024,
+					},
+					&cli.StringFlag{
+						Name:    "junit-output",
+						Aliases: []string{"xml"},
+						Usage:   "The path where the JUnit XML test report should be generated.",
+						Value:   "",
+					},
+					&cli.StringFlag{
+						Name:    "html-output",
+						Aliases: []string{"html"},
+						Usage:   "The path where the one-page HTML test report should be generated.",
+						Value:   "",
+					},
+					&cli.StringFlag{
+						Name:    "markdown-output",
+						Aliases: []string{"markdown", "md"},
+						Usage:   "The path where the summary Markdown test report should be generated.",
+						Value:   "",
+					},
 					&cli.StringFlag{
 						Name:    "job-url",
 						Aliases: []string{},
@@ -111,6 +192,183 @@ func main() {
 						Usage:   "Adjust the scope of tests to run. Accepts a 'spec' (test only this spec), a '+spec' (test also this immature spec), or a '-spec' (do not test this mature spec). Available spec presets: " + strings.Join(getAvailableSpecPresets(), ","),
 						Value:   "",
 					},
+					&cli.StringFlag{
+						Name:    "run",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_RUN"},
+						Usage:   "Only run the tests whose name or path matches this regular expression.",
+					},
+					&cli.StringFlag{
+						Name:    "skip",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_SKIP"},
+						Usage:   "Do not run the tests whose name or path matches this regular expression.",
+					},
+					&cli.StringSliceFlag{
+						Name:    "group",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_GROUP"},
+						Usage:   "Only run the tests of this group, one of " + strings.Join(tests.Groups, ", ") + ". Can be repeated.",
+					},
+					&cli.StringSliceFlag{
+						Name:    "spec-url",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_SPEC_URL"},
+						Usage:   "Only run the tests with a spec URL starting with this prefix, e.g. https://specs.ipfs.tech/http-gateways/trustless-gateway/. Can be repeated.",
+					},
+					&cli.IntFlag{
+						Name:  "parallel",
+						Usage: "The maximum number of tests to run concurrently. Tests that share state with other tests always run sequentially.",
+						Value: 1,
+					},
+					&cli.DurationFlag{
+						Name:    "request-timeout",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_REQUEST_TIMEOUT"},
+						Usage:   "The maximum duration of a single request, including reading the response body.",
+						Value:   test.DefaultClientConfig.RequestTimeout,
+					},
+					&cli.DurationFlag{
+						Name:    "connect-timeout",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_CONNECT_TIMEOUT"},
+						Usage:   "The maximum duration of establishing a connection to the gateway.",
+						Value:   test.DefaultClientConfig.ConnectTimeout,
+					},
+					&cli.IntFlag{
+						Name:    "retries",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_RETRIES"},
+						Usage:   "The number of times a request that failed with a network error, such as a timeout or a reset connection, is retried. Requests that got a response are never retried.",
+						Value:   test.DefaultClientConfig.Retries,
+					},
+					&cli.DurationFlag{
+						Name:    "retry-backoff",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_RETRY_BACKOFF"},
+						Usage:   "The delay before the first retry of a request, doubled with every retry.",
+						Value:   test.DefaultClientConfig.RetryBackoff,
+					},
+					&cli.IntFlag{
+						Name:    "max-idle-conns",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_MAX_IDLE_CONNS"},
+						Usage:   "The maximum number of idle connections kept open to the gateway and reused across requests.",
+						Value:   test.DefaultClientConfig.MaxIdleConns,
+					},
+					&cli.StringFlag{
+						Name:    "http-version",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_HTTP_VERSION"},
+						Usage:   "The HTTP version every request must use, one of " + strings.Join(test.HTTPVersions, ", ") + ". By default, HTTP/1.1 is used, or HTTP/2 when the gateway negotiates it over TLS.",
+						Value:   "",
+					},
+					&cli.StringFlag{
+						Name:    "ca-cert",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_CA_CERT"},
+						Usage:   "The path of a PEM bundle of certificate authorities to trust in addition to the system ones.",
+						Value:   "",
+					},
+					&cli.StringFlag{
+						Name:    "client-cert",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_CLIENT_CERT"},
+						Usage:   "The path of the PEM client certificate presented to gateways that require mutual TLS. Requires --client-key.",
+						Value:   "",
+					},
+					&cli.StringFlag{
+						Name:    "client-key",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_CLIENT_KEY"},
+						Usage:   "The path of the PEM private key of the client certificate.",
+						Value:   "",
+					},
+					&cli.BoolFlag{
+						Name:    "insecure",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_INSECURE"},
+						Usage:   "Skip the verification of the gateway TLS certificate.",
+						Value:   false,
+					},
+					&cli.StringFlag{
+						Name:    "tls-server-name",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_TLS_SERVER_NAME"},
+						Usage:   "The server name sent with SNI and used to verify the gateway TLS certificate, instead of the host of the request.",
+						Value:   "",
+					},
+					&cli.StringFlag{
+						Name:    "auth-token",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_AUTH_TOKEN"},
+						Usage:   "A bearer token sent in the Authorization header of every request.",
+						Value:   "",
+					},
+					&cli.StringFlag{
+						Name:    "auth-basic",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_AUTH_BASIC"},
+						Usage:   "The 'username:password' credentials sent with basic authentication in every request.",
+						Value:   "",
+					},
+					&cli.StringFlag{
+						Name:    "auth-header",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_AUTH_HEADER"},
+						Usage:   "A custom 'Name: value' header carrying the credentials of every request, e.g. an API key.",
+						Value:   "",
+					},
+					&cli.StringSliceFlag{
+						Name:    "header",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_HEADER"},
+						Usage:   "A 'Name: value' header sent with every request, unless the test sets it. Can be repeated.",
+					},
+					&cli.StringFlag{
+						Name:    "auth-command",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_AUTH_COMMAND"},
+						Usage:   "A shell command printing a bearer token sent in the Authorization header of every request. The command runs again when the token expires.",
+						Value:   "",
+					},
+					&cli.DurationFlag{
+						Name:    "auth-command-ttl",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_AUTH_COMMAND_TTL"},
+						Usage:   "How long a token printed by --auth-command is used before the command runs again. JWTs are refreshed before their expiry, if earlier.",
+						Value:   5 * time.Minute,
+					},
+					&cli.StringFlag{
+						Name:  "record",
+						Usage: "The directory where every response of the gateway is recorded, so that the run can be replayed later with --replay.",
+						Value: "",
+					},
+					&cli.StringFlag{
+						Name:  "replay",
+						Usage: "The directory of responses recorded with --record. The responses are served from there, no request is sent to the gateway.",
+						Value: "",
+					},
+					&cli.StringFlag{
+						Name:    "baseline",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_BASELINE"},
+						Usage:   "The path of a YAML or JSON file listing the tests expected to fail, see the baseline command. The run passes when only these tests fail.",
+					},
+					&cli.StringFlag{
+						Name:    "config",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_CONFIG"},
+						Usage:   "The path of the YAML or TOML file holding the profiles selected with --profile. Defaults to gateway-conformance.yaml, or gateway-conformance.toml when it does not exist.",
+						Value:   config.DefaultPath,
+					},
+					&cli.StringFlag{
+						Name:    "profile",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_PROFILE"},
+						Usage:   "The name of the profile of the --config file to use. Flags and environment variables take precedence over the settings of the profile. Defaults to the default profile of the file, if any.",
+						Value:   "",
+					},
+					&cli.BoolFlag{
+						Name:    "watch",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_WATCH"},
+						Usage:   "Run the tests again every time the gateway restarts, or the --watch-trigger file changes, and print the checks whose outcome flipped. The tests that failed run first. Reports are not generated in watch mode.",
+						Value:   false,
+					},
+					&cli.StringFlag{
+						Name:    "watch-health",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_WATCH_HEALTH"},
+						Usage:   "The URL, or the path on the gateway URL, polled to detect that the gateway restarted: the tests run again when it answers after it stopped answering. Defaults to the gateway URL.",
+						Value:   "",
+					},
+					&cli.StringFlag{
+						Name:    "watch-trigger",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_WATCH_TRIGGER"},
+						Usage:   "The path of a file whose modification re-runs the tests, instead of polling the gateway, e.g. touched by the build script of the gateway.",
+						Value:   "",
+					},
+					&cli.DurationFlag{
+						Name:    "watch-interval",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_WATCH_INTERVAL"},
+						Usage:   "How often the gateway, or the --watch-trigger file, is checked in watch mode.",
+						Value:   time.Second,
+					},
 					&cli.BoolFlag{
 						Name:  "verbose",
 						Usage: "Prints all the output to the console.",
@@ -118,18 +376,21 @@ func main() {
 					},
 				},
 				Action: func(cctx *cli.Context) error {
-					env := os.Environ()
+					profileArgs, err := applyProfile(cctx)
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("⚠️ %s", err), 2)
+					}
+
 					verbose := cctx.Bool("verbose")
 					specs := cctx.String("specs")
 
 					// Handle Gateway Endpoint URL
 					gatewayURL := cctx.String("gateway-url")
 					if gatewayURL != "" {
-						envGwURL := fmt.Sprintf("GATEWAY_URL=%s", gatewayURL)
 						if verbose {
-							fmt.Println(envGwURL)
+							fmt.Printf("GATEWAY_URL=%s\n", gatewayURL)
 						}
-						env = append(env, envGwURL)
+						os.Setenv("GATEWAY_URL", gatewayURL)
 					} else {
 						return cli.Exit("⚠️ GATEWAY_URL (or --gateway-url) with the endpoint to receive HTTP requests has to be set", 2)
 					}
@@ -137,38 +398,88 @@ func main() {
 					// Handle Subdomain URL
 					subdomainGatewayURL := cctx.String("subdomain-url")
 					if subdomainGatewayURL != "" {
-						// If set, pass to `go test` via env
-						envSubdomainGwURL := fmt.Sprintf("SUBDOMAIN_GATEWAY_URL=%s", subdomainGatewayURL)
+						// If set, pass to the test suite via env
 						if verbose {
-							fmt.Println(envSubdomainGwURL)
+							fmt.Printf("SUBDOMAIN_GATEWAY_URL=%s\n", subdomainGatewayURL)
 						}
-						env = append(env, envSubdomainGwURL)
+						os.Setenv("SUBDOMAIN_GATEWAY_URL", subdomainGatewayURL)
 					} else if isSubdomainPresetEnabled(specs) {
 						// If not set, check if `specs` is not set to explicitly disable it,
 						// provide user with a meaningful error
 						return cli.Exit("⚠️ SUBDOMAIN_GATEWAY_URL (or --subdomain-url) must be set when 'subdomain-gateway' tests are enabled. Set the URL and try again, or disable related tests by passing --specs -subdomain-gateway", 2)
 					}
 
+					tooling.JobURL = cctx.String("job-url")
+					test.Parallel = cctx.Int("parallel")
+					filter, err := testFilter(cctx)
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("⚠️ %s", err), 2)
+					}
+					test.Select(filter)
+					var expected *baseline.Baseline
+					if path := cctx.String("baseline"); path != "" {
+						expected, err = baseline.Read(path)
+						if err != nil {
+							return cli.Exit(fmt.Sprintf("⚠️ %s", err), 2)
+						}
+					}
+					if cctx.String("har-output") != "" {
+						test.RecordHAR(cctx.Int("har-body-limit"))
+					}
+					auth, err := authenticator(cctx)
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("⚠️ %s", err), 2)
+					}
+					headers, err := requestHeaders(cctx)
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("⚠️ %s", err), 2)
+					}
+
+					err = test.Configure(test.ClientConfig{
+						RequestTimeout: cctx.Duration("request-timeout"),
+						ConnectTimeout: cctx.Duration("connect-timeout"),
+						Retries:        cctx.Int("retries"),
+						RetryBackoff:   cctx.Duration("retry-backoff"),
+						MaxIdleConns:   cctx.Int("max-idle-conns"),
+						HTTPVersion:    cctx.String("http-version"),
+						CACert:         cctx.String("ca-cert"),
+						ClientCert:     cctx.String("client-cert"),
+						ClientKey:      cctx.String("client-key"),
+						Insecure:       cctx.Bool("insecure"),
+						TLSServerName:  cctx.String("tls-server-name"),
+						Auth:           auth,
+						Headers:        headers,
+						RecordDir:      cctx.String("record"),
+						ReplayDir:      cctx.String("replay"),
+					})
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("⚠️ %s", err), 2)
+					}
+
 					// Set other parameters
-					args := []string{"test", "./tests", "-test.v=test2json"}
+					args := []string{"-test.v=test2json"}
 					if specs != "" {
 						args = append(args, fmt.Sprintf("-specs=%s", specs))
 					}
 
-					ldFlag := fmt.Sprintf("-ldflags=-X github.com/ipfs/gateway-conformance/tooling.Version=%s -X github.com/ipfs/gateway-conformance/tooling.JobURL=%s", tooling.Version, cctx.String("job-url"))
-					args = append(args, ldFlag)
-
+					args = append(args, profileArgs...)
 					args = append(args, cctx.Args().Slice()...)
 
-					fmt.Println("go " + strings.Join(args, " "))
+					if verbose {
+						fmt.Println("gateway-conformance " + strings.Join(args, " "))
+					}
+
+					if cctx.Bool("watch") {
+						return watchTests(cctx, filter, args)
+					}
 
 					// Execute tests against URLs
 					output := &bytes.Buffer{}
-					cmd := exec.Command("go", args...)
-					cmd.Dir = tooling.Home()
-					cmd.Env = env
-					cmd.Stdout = out{
-						Writer: output,
+					json := &bytes.Buffer{}
+					events := test2json.NewConverter(json, "Gateway Tests")
+					stdout := out{
+						Writer:  io.MultiWriter(output, events),
+						Console: os.Stdout,
 						Filter: func(line string) bool {
 							return verbose ||
 								strings.HasPrefix(line, "\u0016FAIL") ||
@@ -176,15 +487,39 @@ func main() {
 								strings.HasPrefix(line, "\u0016PASS")
 						},
 					}
-					cmd.Stderr = os.Stderr
 
 					fmt.Println("Running tests...")
 					fmt.Println()
-					testErr := cmd.Run()
+					code, err := runner.Run(stdout, tests.All, args)
+					if err != nil {
+						return err
+					}
+					err = events.Close()
+					if err != nil {
+						return err
+					}
+
+					testEvents, err := test2json.ReadEvents(bytes.NewReader(json.Bytes()))
+					if err != nil {
+						return err
+					}
+					if test.Parallel > 1 {
+						// Concurrent tests interleave their events, put them
+						// back in order so that the JSON report is stable.
+						testEvents = test2json.Sort(testEvents)
+						json.Reset()
+						err = test2json.WriteEvents(json, testEvents)
+						if err != nil {
+							return err
+						}
+					}
 					fmt.Println("\nDONE!")
 					fmt.Println()
 
-					if testErr != nil {
+					var testErr error
+					if code != 0 {
+						testErr = cli.Exit(fmt.Sprintf("tests failed with exit code %d", code), code)
+
 						fmt.Println("\nLooking for details...")
 						fmt.Println()
 						strOutput := output.String()
@@ -206,32 +541,81 @@ func main() {
 						fmt.Println()
 					}
 
+					summary := results.Summarize(test.Results(), specPresets.All())
+					fmt.Println("\nConformance summary:")
+					fmt.Println()
+					err = results.WriteSummary(os.Stdout, summary)
+					if err != nil {
+						return err
+					}
+					fmt.Println()
+
 					jsonOutput := cctx.String("json-output")
 					if jsonOutput != "" {
-						json := &bytes.Buffer{}
-						cmd = exec.Command("go", "tool", "test2json", "-p", "Gateway Tests", "-t")
-						cmd.Env = env
-						cmd.Stdin = output
-						cmd.Stdout = json
-						cmd.Stderr = os.Stderr
-
 						fmt.Println("\nGenerating JSON report...")
-						err := cmd.Run()
+						err = writeFile(jsonOutput, func(w io.Writer) error {
+							_, err := w.Write(json.Bytes())
+							return err
+						})
 						if err != nil {
 							return err
 						}
-						// create directory if it doesn't exist
-						err = os.MkdirAll(filepath.Dir(jsonOutput), 0755)
+						fmt.Println("DONE!")
+						fmt.Println()
+					}
+
+					resultsOutput := cctx.String("results-output")
+					if resultsOutput != "" {
+						fmt.Println("\nGenerating structured results...")
+						err = writeFile(resultsOutput, func(w io.Writer) error {
+							return results.Write(w, results.Report{
+								Version:             tooling.Version,
+								JobURL:              tooling.JobURL,
+								GatewayURL:          gatewayURL,
+								SubdomainGatewayURL: subdomainGatewayURL,
+								HTTPVersion:         cctx.String("http-version"),
+								Tests:               test.Results(),
+								Summary:             &summary,
+							})
+						})
 						if err != nil {
 							return err
 						}
-						// write jsonOutput to json file
-						f, err := os.Create(jsonOutput)
+						fmt.Println("DONE!")
+						fmt.Println()
+					}
+
+					harOutput := cctx.String("har-output")
+					if harOutput != "" {
+						fmt.Println("\nGenerating HAR file...")
+						err = writeFile(harOutput, func(w io.Writer) error {
+							return har.Write(w, test.HAR())
+						})
 						if err != nil {
 							return err
 						}
-						defer f.Close()
-						_, err = f.Write(json.Bytes())
+						fmt.Println("DONE!")
+						fmt.Println()
+					}
+
+					results := report.FromEvents(testEvents)
+
+					for _, output := range []struct {
+						name  string
+						path  string
+						write func(io.Writer, *report.Report) error
+					}{
+						{"JUnit XML", cctx.String("junit-output"), report.WriteJUnit},
+						{"HTML", cctx.String("html-output"), report.WriteHTML},
+						{"Markdown", cctx.String("markdown-output"), report.WriteMarkdown},
+					} {
+						if output.path == "" {
+							continue
+						}
+						fmt.Printf("\nGenerating %s report...\n", output.name)
+						err = writeFile(output.path, func(w io.Writer) error {
+							return output.write(w, results)
+						})
 						if err != nil {
 							return err
 						}
@@ -239,109 +623,797 @@ func main() {
 						fmt.Println()
 					}
 
+					if expected != nil {
+						res := baseline.Compare(*expected, results)
+						fmt.Printf("\nBaseline: %d expected failures, %d regressions, %d passing\n", len(res.Expected), len(res.Regressions), len(res.Passing))
+						for _, t := range res.Regressions {
+							fmt.Printf("  ❌ %s failed, it is not in the baseline\n", t)
+						}
+						for _, e := range res.Passing {
+							fmt.Printf("  ⚠️ %s passed, remove it from the baseline\n", e.Test)
+						}
+						fmt.Println()
+
+						switch {
+						case !res.OK():
+							testErr = cli.Exit(fmt.Sprintf("%d tests failed outside of the baseline", len(res.Regressions)), 1)
+						case code != 0 && len(res.Expected) > 0:
+							// Only tests of the baseline failed.
+							testErr = nil
+						}
+					}
+
 					return testErr
 				},
 			},
 			{
-				Name:    "extract-fixtures",
-				Aliases: []string{"e"},
-				Usage:   "Extract gateway testing fixtures that are used by the conformance test suite",
+				Name:  "baseline",
+				Usage: "Generate a baseline of the tests expected to fail from the JSON report of a previous run",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:     "directory",
-						Aliases:  []string{"dir"},
-						Usage:    "The directory to extract the fixtures to",
+						Name:     "input",
+						Aliases:  []string{"i"},
+						Usage:    "The path of the JSON report, see the --json-output flag of the test command.",
 						Required: true,
 					},
-					&cli.BoolFlag{
-						Name:  "merged",
-						Usage: "Merge the CAR fixtures into a single CAR file",
-						Value: false,
-					},
-					&cli.BoolFlag{
-						Name:  "car",
-						Usage: "Include CAR fixtures",
-						Value: true,
-					},
-					&cli.BoolFlag{
-						Name:  "ipns",
-						Usage: "Include IPNS Record fixtures",
-						Value: true,
+					&cli.StringFlag{
+						Name:     "output",
+						Aliases:  []string{"o"},
+						Usage:    "The path of the baseline, written as JSON when it ends with .json, as YAML otherwise. The entries already in the file covering a failing test are kept.",
+						Required: true,
 					},
-					&cli.BoolFlag{
-						Name:  "dnslink",
-						Usage: "Include DNSLink fixtures",
-						Value: true,
+					&cli.StringFlag{
+						Name:  "reason",
+						Usage: "The reason of the tests added to the baseline.",
+						Value: "Known failure",
 					},
 				},
 				Action: func(cctx *cli.Context) error {
-					directory := cctx.String("directory")
-
-					err := os.MkdirAll(directory, 0755)
+					f, err := os.Open(cctx.String("input"))
 					if err != nil {
 						return err
 					}
-
-					fxs, err := fixtures.List()
+					defer f.Close()
+					events, err := test2json.ReadEvents(f)
 					if err != nil {
 						return err
 					}
 
-					// IPNS Records
-					if cctx.Bool("ipns") {
-						err = copyFiles(fxs.IPNSRecords, directory)
+					output := cctx.String("output")
+					var previous *baseline.Baseline
+					if _, err := os.Stat(output); err == nil {
+						previous, err = baseline.Read(output)
 						if err != nil {
 							return err
 						}
 					}
 
-					// DNSLink fixtures as YAML, JSON, and IPNS_NS_MAP env variable
-					if cctx.Bool("dnslink") {
-						err = copyFiles(fxs.ConfigFiles, directory)
-						if err != nil {
-							return err
-						}
-						err = dnslink.MergeJSON(fxs.ConfigFiles, filepath.Join(directory, "dnslinks.json"))
-						if err != nil {
-							return err
-						}
-						err = dnslink.MergeNsMapEnv(fxs.ConfigFiles, filepath.Join(directory, "dnslinks.IPFS_NS_MAP"))
-						if err != nil {
-							return err
-						}
+					b := baseline.FromReport(report.FromEvents(events), previous, cctx.String("reason"))
+					fmt.Printf("Writing %d expected failures to %s\n", len(b.Failures), output)
+					return writeFile(output, func(w io.Writer) error {
+						return baseline.Write(w, output, b)
+					})
+				},
+			},
+			{
+				Name:      "diff",
+				Usage:     "Compare the reports of two runs and list the tests whose outcome changed, exits with 1 when a test that passed now fails",
+				ArgsUsage: "OLD NEW",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "The path of the Markdown diff, printed when not set.",
+					},
+				},
+				Action: func(cctx *cli.Context) error {
+					if cctx.NArg() != 2 {
+						return cli.Exit("⚠️ expected the paths of the old and new JSON reports", 2)
+					}
+					old, err := diff.Read(cctx.Args().Get(0))
+					if err != nil {
+						return err
+					}
+					new, err := diff.Read(cctx.Args().Get(1))
+					if err != nil {
+						return err
 					}
 
-					if cctx.Bool("car") {
-						if cctx.Bool("merged") {
-							// All .car fixtures merged into a single .car file
-							err = car.Merge(fxs.CarFiles, filepath.Join(directory, "fixtures.car"))
-							if err != nil {
-								return err
-							}
-							// TODO: when https://github.com/ipfs/specs/issues/369 has been completed,
-							// implement merge support to include the IPNS records in the car file.
-						} else {
-							// Copy .car fixtures as -is
-							err = copyFiles(fxs.CarFiles, directory)
-							if err != nil {
-								return err
-							}
-						}
+					d := diff.Compare(old, new)
+					if cctx.String("output") == "" {
+						err = diff.WriteMarkdown(os.Stdout, d)
+					} else {
+						err = writeFile(cctx.String("output"), func(w io.Writer) error {
+							return diff.WriteMarkdown(w, d)
+						})
+					}
+					if err != nil {
+						return err
 					}
 
+					if d.HasRegressions() {
+						return cli.Exit(fmt.Sprintf("%d tests that passed now fail", len(d.NewlyFailing)), 1)
+					}
 					return nil
 				},
 			},
-		},
-	}
+			{
+				Name:      "aggregate",
+				Usage:     "Compare the JSON reports of many implementations in a single table, with a column per implementation",
+				ArgsUsage: "PATH...",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "by",
+						Usage: "The rows of the table, one of test (a row per top-level test) or spec (a row per spec URL).",
+						Value: "test",
+					},
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "The output format, one of markdown, html or json.",
+						Value:   "markdown",
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "The path of the table, printed when not set.",
+					},
+				},
+				Action: func(cctx *cli.Context) error {
+					var table func(runs []dashboard.Run) dashboard.Table
+					switch cctx.String("by") {
+					case "test":
+						table = dashboard.ByTest
+					case "spec":
+						table = dashboard.BySpec
+					default:
+						return cli.Exit(fmt.Sprintf("⚠️ unknown rows %q, expected test or spec", cctx.String("by")), 2)
+					}
 
-	if err := app.Run(os.Args); err != nil {
-		log.Fatal(err)
-	}
-}
+					var write func(w io.Writer, t dashboard.Table) error
+					switch cctx.String("format") {
+					case "markdown":
+						write = dashboard.WriteMarkdown
+					case "html":
+						write = dashboard.WriteHTML
+					case "json":
+						write = dashboard.WriteJSON
+					default:
+						return cli.Exit(fmt.Sprintf("⚠️ unknown format %q, expected markdown, html or json", cctx.String("format")), 2)
+					}
 
-func getAvailableSpecPresets() []string {
-	var presets []string
+					if cctx.NArg() == 0 {
+						return cli.Exit("⚠️ expected the paths of the JSON reports, or of directories holding them", 2)
+					}
+					runs, err := dashboard.Load(cctx.Args().Slice()...)
+					if err != nil {
+						return err
+					}
+
+					t := table(runs)
+					if cctx.String("output") == "" {
+						return write(os.Stdout, t)
+					}
+					return writeFile(cctx.String("output"), func(w io.Writer) error {
+						return write(w, t)
+					})
+				},
+			},
+			{
+				Name:      "dashboard",
+				Usage:     "Generate the content of the Hugo dashboard website from the JSON reports of many implementations",
+				ArgsUsage: "PATH...",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "The root of the Hugo website.",
+						Value:   "www",
+					},
+					&cli.BoolFlag{
+						Name:  "github-details",
+						Usage: "Fetch the commit, branch and date of the runs with a GitHub Actions job URL from the GitHub API. Set GH_TOKEN to raise the rate limit.",
+					},
+				},
+				Action: func(cctx *cli.Context) error {
+					if cctx.NArg() == 0 {
+						return cli.Exit("⚠️ expected the paths of the JSON reports, or of directories holding them", 2)
+					}
+					runs, err := dashboard.Load(cctx.Args().Slice()...)
+					if err != nil {
+						return err
+					}
+
+					site := dashboard.Site{Dir: cctx.String("output")}
+					if cctx.Bool("github-details") {
+						site.Details = dashboard.GitHubDetails(os.Getenv("GH_TOKEN"), func(err error) {
+							fmt.Fprintf(os.Stderr, "⚠️ %s\n", err)
+						})
+					}
+					if err := site.Write(runs); err != nil {
+						return err
+					}
+
+					fmt.Printf("Dashboard content for %d runs written to %s\n", len(runs), site.Dir)
+					return nil
+				},
+			},
+			{
+				Name:      "list",
+				Usage:     "List the tests of the suite, with their specs and expectations, without sending any request",
+				ArgsUsage: "[-- test arguments]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "The output format, one of table or json.",
+						Value:   "table",
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "The path of the catalog, printed when not set.",
+					},
+					&cli.StringFlag{
+						Name:  "gateway-url",
+						Usage: "The gateway URL used in the request templates that contain it.",
+						Value: "http://127.0.0.1:8080",
+					},
+					&cli.StringFlag{
+						Name:  "subdomain-url",
+						Usage: "The Subdomain URL used in the request templates that contain it.",
+						Value: "http://example.com",
+					},
+				},
+				Action: func(cctx *cli.Context) error {
+					var write func(w io.Writer, c catalog.Catalog) error
+					switch cctx.String("format") {
+					case "table":
+						write = catalog.WriteTable
+					case "json":
+						write = catalog.Write
+					default:
+						return cli.Exit(fmt.Sprintf("⚠️ unknown format %q, expected table or json", cctx.String("format")), 2)
+					}
+
+					os.Setenv("GATEWAY_URL", cctx.String("gateway-url"))
+					os.Setenv("SUBDOMAIN_GATEWAY_URL", cctx.String("subdomain-url"))
+					test.ListOnly = true
+
+					args := cctx.Args().Slice()
+					code, err := runner.Run(io.Discard, tests.All, args)
+					if err != nil {
+						return err
+					}
+					if code != 0 {
+						return cli.Exit(fmt.Sprintf("listing tests failed with exit code %d", code), code)
+					}
+
+					c := catalog.Catalog{Version: tooling.Version, Tests: test.Catalog()}
+					if cctx.String("output") == "" {
+						return write(os.Stdout, c)
+					}
+					return writeFile(cctx.String("output"), func(w io.Writer) error {
+						return write(w, c)
+					})
+				},
+			},
+			{
+				Name:  "provision",
+				Usage: "Load the fixtures into the gateway to test, and check that it serves them",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "backend",
+						Usage:   "Where the fixtures are loaded: 'kubo' for the RPC API of a Kubo node, 'http' for a blockstore with an HTTP API, or 'dir' for a local directory.",
+						Value:   "kubo",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_PROVISION_BACKEND"},
+					},
+					&cli.StringFlag{
+						Name:    "kubo-api",
+						Usage:   "The URL of the RPC API of the Kubo node, with the kubo backend.",
+						Value:   "http://127.0.0.1:5001",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_KUBO_API"},
+					},
+					&cli.StringFlag{
+						Name:    "block-url",
+						Usage:   "The URL every block is sent to with the http backend, '{cid}' is replaced with the CID of the block, e.g. https://blocks.example.com/{cid}.",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_BLOCK_URL"},
+					},
+					&cli.StringFlag{
+						Name:    "ipns-url",
+						Usage:   "The URL every IPNS record is sent to with the http backend, '{name}' is replaced with the IPNS name of the record. IPNS records are not loaded when unset.",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_IPNS_URL"},
+					},
+					&cli.StringFlag{
+						Name:  "method",
+						Usage: "The method of the requests of the http backend, PUT or POST.",
+						Value: http.MethodPut,
+					},
+					&cli.StringSliceFlag{
+						Name:    "header",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_HEADER"},
+						Usage:   "A 'Name: value' header sent with every request of the http backend, e.g. credentials. Can be repeated.",
+					},
+					&cli.StringFlag{
+						Name:    "directory",
+						Aliases: []string{"dir"},
+						Usage:   "The directory the dir backend writes the blocks and IPNS records to.",
+					},
+					&cli.StringFlag{
+						Name:  "car",
+						Usage: "A merged CAR file written by extract-fixtures --merged --metadata, loaded with its IPNS records instead of the fixtures of the tool.",
+					},
+					&cli.StringFlag{
+						Name:    "gateway-url",
+						EnvVars: []string{"GATEWAY_URL"},
+						Aliases: []string{"url", "g"},
+						Usage:   "The URL of the gateway to test. Once loaded, the roots of the CAR fixtures are requested from the gateway until it serves them. Not checked when unset.",
+					},
+					&cli.DurationFlag{
+						Name:  "verify-timeout",
+						Usage: "How long to wait for the gateway to serve the roots of the CAR fixtures.",
+						Value: time.Minute,
+					},
+				},
+				Action: func(cctx *cli.Context) error {
+					client := &http.Client{Timeout: time.Minute}
+
+					var backend provision.Backend
+					switch cctx.String("backend") {
+					case "kubo":
+						backend = provision.Kubo{API: cctx.String("kubo-api"), Client: client}
+					case "http":
+						if cctx.String("block-url") == "" {
+							return cli.Exit("⚠️ --block-url must be set with the http backend", 2)
+						}
+						headers, err := requestHeaders(cctx)
+						if err != nil {
+							return cli.Exit(fmt.Sprintf("⚠️ %s", err), 2)
+						}
+						backend = provision.HTTP{
+							BlockURL: cctx.String("block-url"),
+							IPNSURL:  cctx.String("ipns-url"),
+							Method:   cctx.String("method"),
+							Header:   headers,
+							Client:   client,
+						}
+					case "dir":
+						if cctx.String("directory") == "" {
+							return cli.Exit("⚠️ --directory must be set with the dir backend", 2)
+						}
+						backend = provision.Dir{Path: cctx.String("directory")}
+					default:
+						return cli.Exit(fmt.Sprintf("⚠️ unknown backend %q, expected one of kubo, http, dir", cctx.String("backend")), 2)
+					}
+
+					var carFiles []string
+					var stats provision.Stats
+					var err error
+					if merged := cctx.String("car"); merged != "" {
+						carFiles = []string{merged}
+						fmt.Printf("Loading %s...\n", merged)
+						stats, err = provision.Merged(cctx.Context, backend, merged)
+						if err != nil {
+							return err
+						}
+					} else {
+						fxs, err := fixtures.List()
+						if err != nil {
+							return err
+						}
+						carFiles = fxs.CarFiles
+						fmt.Printf("Loading %d CAR files and %d IPNS records...\n", len(fxs.CarFiles), len(fxs.IPNSRecords))
+						stats, err = provision.Fixtures(cctx.Context, backend, fxs)
+						if err != nil {
+							return err
+						}
+					}
+					fmt.Printf("Loaded %d CAR files and %d IPNS records, skipped %d invalid IPNS records.\n", stats.CARs, stats.IPNSRecords, len(stats.Invalid))
+
+					gatewayURL := cctx.String("gateway-url")
+					if gatewayURL == "" {
+						fmt.Println("Set --gateway-url to check that the gateway serves the fixtures.")
+						return nil
+					}
+
+					roots, err := provision.Roots(carFiles)
+					if err != nil {
+						return err
+					}
+					fmt.Printf("Checking that %s serves the %d roots of the CAR files...\n", gatewayURL, len(roots))
+					missing := provision.Verify(cctx.Context, client, gatewayURL, roots, cctx.Duration("verify-timeout"))
+					if len(missing) > 0 {
+						for _, root := range missing {
+							fmt.Printf("  ❌ %s\n", root)
+						}
+						return cli.Exit(fmt.Sprintf("the gateway does not serve %d roots", len(missing)), 1)
+					}
+					fmt.Println("DONE!")
+					return nil
+				},
+			},
+			{
+				Name:  "mutate",
+				Usage: "Run a reverse proxy injecting a fault in the responses of a gateway",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "upstream",
+						Usage:    "The URL of the gateway to proxy.",
+						Required: true,
+						EnvVars:  []string{"GATEWAY_CONFORMANCE_UPSTREAM"},
+					},
+					&cli.StringFlag{
+						Name:     "mutation",
+						Usage:    fmt.Sprintf("The mutation to apply, one of %s or drop-header:NAME.", strings.Join(mutate.Names(), ", ")),
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:    "listen",
+						Aliases: []string{"l"},
+						Usage:   "The address the proxy listens on.",
+						Value:   "127.0.0.1:8081",
+					},
+				},
+				Action: func(cctx *cli.Context) error {
+					upstream, err := url.Parse(cctx.String("upstream"))
+					if err != nil {
+						return err
+					}
+					m, err := mutate.Get(cctx.String("mutation"))
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("⚠️ %s", err), 2)
+					}
+
+					fmt.Printf("Proxying %s on http://%s, %s\n", upstream, cctx.String("listen"), m.Description)
+					return http.ListenAndServe(cctx.String("listen"), mutate.NewProxy(upstream, m))
+				},
+			},
+			{
+				Name:      "mutate-report",
+				Usage:     "Run the suite once per mutation and report which tests catch each of them",
+				ArgsUsage: "[-- test arguments]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "upstream",
+						Usage:    "The URL of a gateway passing the suite.",
+						Required: true,
+						EnvVars:  []string{"GATEWAY_CONFORMANCE_UPSTREAM"},
+					},
+					&cli.StringFlag{
+						Name:    "subdomain-url",
+						Usage:   "The Subdomain URL of the gateway, its port is replaced with the port of the proxy so that subdomain requests are mutated too.",
+						Value:   "http://example.com",
+						EnvVars: []string{"SUBDOMAIN_GATEWAY_URL"},
+					},
+					&cli.StringSliceFlag{
+						Name:  "mutation",
+						Usage: "A mutation to apply, see the mutate command. Can be repeated. Defaults to every mutation.",
+					},
+					&cli.StringFlag{
+						Name:  "specs",
+						Usage: "The specs to test, see the test command.",
+					},
+					&cli.IntFlag{
+						Name:  "parallel",
+						Usage: "The maximum number of tests to run concurrently.",
+						Value: 1,
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "The path of the Markdown report, printed when not set.",
+					},
+				},
+				Action: func(cctx *cli.Context) error {
+					upstream, err := url.Parse(cctx.String("upstream"))
+					if err != nil {
+						return err
+					}
+					subdomainURL, err := url.Parse(cctx.String("subdomain-url"))
+					if err != nil || subdomainURL.Hostname() == "" {
+						return cli.Exit(fmt.Sprintf("⚠️ --subdomain-url must be the URL of the subdomain gateway, got %q", cctx.String("subdomain-url")), 2)
+					}
+
+					names := cctx.StringSlice("mutation")
+					if len(names) == 0 {
+						names = mutate.Names()
+					}
+					var mutations []mutate.Mutation
+					for _, name := range names {
+						m, err := mutate.Get(name)
+						if err != nil {
+							return cli.Exit(fmt.Sprintf("⚠️ %s", err), 2)
+						}
+						mutations = append(mutations, m)
+					}
+
+					fmt.Println("Running tests without mutation...")
+					baseline, err := runMutated(cctx, upstream, subdomainURL, mutate.Mutation{Name: "none"})
+					if err != nil {
+						return err
+					}
+
+					var outcomes []mutate.Outcome
+					for _, m := range mutations {
+						fmt.Printf("Running tests with %s...\n", m.Name)
+						mutated, err := runMutated(cctx, upstream, subdomainURL, m)
+						if err != nil {
+							outcomes = append(outcomes, mutate.Outcome{Mutation: m, Err: err})
+							continue
+						}
+						outcomes = append(outcomes, mutate.Compare(m, baseline, mutated))
+					}
+					fmt.Println("\nDONE!")
+					fmt.Println()
+
+					if cctx.String("output") == "" {
+						return mutate.WriteMarkdown(os.Stdout, outcomes)
+					}
+					return writeFile(cctx.String("output"), func(w io.Writer) error {
+						return mutate.WriteMarkdown(w, outcomes)
+					})
+				},
+			},
+			{
+				Name:    "extract-fixtures",
+				Aliases: []string{"e"},
+				Usage:   "Extract gateway testing fixtures that are used by the conformance test suite",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "directory",
+						Aliases:  []string{"dir"},
+						Usage:    "The directory to extract the fixtures to",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "merged",
+						Usage: "Merge the CAR fixtures into a single CAR file",
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:  "metadata",
+						Usage: "With --merged, store the IPNS Record and DNSLink fixtures in the merged CAR file too",
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:  "car",
+						Usage: "Include CAR fixtures",
+						Value: true,
+					},
+					&cli.BoolFlag{
+						Name:  "ipns",
+						Usage: "Include IPNS Record fixtures",
+						Value: true,
+					},
+					&cli.BoolFlag{
+						Name:  "dnslink",
+						Usage: "Include DNSLink fixtures",
+						Value: true,
+					},
+				},
+				Action: func(cctx *cli.Context) error {
+					directory := cctx.String("directory")
+
+					err := os.MkdirAll(directory, 0755)
+					if err != nil {
+						return err
+					}
+
+					fxs, err := fixtures.List()
+					if err != nil {
+						return err
+					}
+
+					// IPNS Records
+					if cctx.Bool("ipns") {
+						err = copyFiles(fxs.IPNSRecords, directory)
+						if err != nil {
+							return err
+						}
+					}
+
+					// DNSLink fixtures as YAML, JSON, and IPNS_NS_MAP env variable
+					if cctx.Bool("dnslink") {
+						err = copyFiles(fxs.ConfigFiles, directory)
+						if err != nil {
+							return err
+						}
+						err = dnslink.MergeJSON(fxs.ConfigFiles, filepath.Join(directory, "dnslinks.json"))
+						if err != nil {
+							return err
+						}
+						err = dnslink.MergeNsMapEnv(fxs.ConfigFiles, filepath.Join(directory, "dnslinks.IPFS_NS_MAP"))
+						if err != nil {
+							return err
+						}
+					}
+
+					if cctx.Bool("car") {
+						if cctx.Bool("merged") {
+							// All .car fixtures merged into a single .car file
+							if cctx.Bool("metadata") {
+								meta, err := fixturesMetadata(fxs)
+								if err != nil {
+									return err
+								}
+								err = car.MergeWithMetadata(fxs.CarFiles, meta, filepath.Join(directory, "fixtures.car"))
+								if err != nil {
+									return err
+								}
+							} else {
+								err = car.Merge(fxs.CarFiles, filepath.Join(directory, "fixtures.car"))
+								if err != nil {
+									return err
+								}
+							}
+						} else {
+							// Copy .car fixtures as -is
+							err = copyFiles(fxs.CarFiles, directory)
+							if err != nil {
+								return err
+							}
+						}
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "verify-fixtures",
+				Usage: "Verify the fixtures against their manifest: hashes, block CIDs, IPNS signatures and the tests using them",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "manifest",
+						Usage: "The path of the manifest. Defaults to manifest.json in the fixtures directory.",
+					},
+					&cli.BoolFlag{
+						Name:  "update",
+						Usage: "Write the manifest of the current fixtures instead of verifying them. Requires the source of the tests.",
+					},
+				},
+				Action: func(cctx *cli.Context) error {
+					dir := fixtures.Dir()
+					fxs, err := fixtures.List()
+					if err != nil {
+						return err
+					}
+					manifestPath := cctx.String("manifest")
+					if manifestPath == "" {
+						manifestPath = filepath.Join(dir, manifest.FileName)
+					}
+
+					// The tests using the fixtures are found in their
+					// source, when it is available.
+					var refs *manifest.References
+					testsDir := filepath.Join(tooling.Home(), "tests")
+					if _, err := os.Stat(testsDir); err == nil {
+						paths, err := manifest.Paths(dir, fxs)
+						if err != nil {
+							return err
+						}
+						refs, err = manifest.FindReferences(testsDir, paths)
+						if err != nil {
+							return err
+						}
+					}
+
+					if cctx.Bool("update") {
+						if refs == nil {
+							return cli.Exit(fmt.Sprintf("⚠️ the source of the tests is not available in %s", testsDir), 2)
+						}
+						m, err := manifest.Generate(dir, fxs, refs)
+						if err != nil {
+							return err
+						}
+						err = writeFile(manifestPath, func(w io.Writer) error {
+							return manifest.Write(w, m)
+						})
+						if err != nil {
+							return err
+						}
+						fmt.Printf("Wrote the manifest of %d fixtures to %s\n", len(m.Fixtures), manifestPath)
+						return nil
+					}
+
+					m, err := manifest.Read(manifestPath)
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("⚠️ %s, write it with --update", err), 2)
+					}
+					if refs == nil {
+						fmt.Println("The source of the tests is not available, the tests using the fixtures are not checked.")
+					}
+
+					failed := 0
+					for _, p := range manifest.Verify(dir, fxs, m, refs) {
+						if p.Warning {
+							fmt.Printf("⚠️ %s\n", p)
+							continue
+						}
+						fmt.Printf("❌ %s\n", p)
+						failed++
+					}
+					if failed > 0 {
+						return cli.Exit(fmt.Sprintf("%d problems found in the fixtures", failed), 1)
+					}
+					fmt.Printf("✅ %d CAR files, %d IPNS records and %d DNSLink configs match the manifest\n",
+						len(fxs.CarFiles), len(fxs.IPNSRecords), len(fxs.ConfigFiles))
+					return nil
+				},
+			},
+			{
+				Name:  "generate-fixtures",
+				Usage: "Rebuild the CAR fixtures from their " + builder.Extension + " descriptions",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "dir",
+						Usage: "The directory of the descriptions, searched recursively. Defaults to the fixtures of the source tree.",
+					},
+					&cli.BoolFlag{
+						Name:  "check",
+						Usage: "Check that the CAR files match their descriptions instead of writing them.",
+					},
+				},
+				Action: func(cctx *cli.Context) error {
+					// The descriptions are not embedded in the binary,
+					// they are only found in the source tree.
+					dir := cctx.String("dir")
+					if dir == "" {
+						dir = filepath.Join(tooling.Home(), "fixtures")
+					}
+					paths, err := builder.List(dir)
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("⚠️ %s", err), 2)
+					}
+					if len(paths) == 0 {
+						return cli.Exit(fmt.Sprintf("⚠️ no %s descriptions found in %s", builder.Extension, dir), 2)
+					}
+
+					changed := 0
+					for _, path := range paths {
+						data, err := builder.BuildFile(path)
+						if err != nil {
+							return cli.Exit(fmt.Sprintf("⚠️ %s", err), 2)
+						}
+						carPath := builder.CARPath(path)
+						current, err := os.ReadFile(carPath)
+						if err == nil && bytes.Equal(current, data) {
+							continue
+						}
+						changed++
+						if cctx.Bool("check") {
+							fmt.Printf("❌ %s does not match its description\n", carPath)
+							continue
+						}
+						if err := os.WriteFile(carPath, data, 0o644); err != nil {
+							return err
+						}
+						fmt.Printf("Generated %s\n", carPath)
+					}
+
+					if cctx.Bool("check") {
+						if changed > 0 {
+							return cli.Exit(fmt.Sprintf("%d CAR files do not match their descriptions, rebuild them with generate-fixtures", changed), 1)
+						}
+						fmt.Printf("✅ %d CAR files match their descriptions\n", len(paths))
+						return nil
+					}
+					fmt.Printf("%d of %d CAR files changed\n", changed, len(paths))
+					if changed > 0 {
+						fmt.Println("Update the manifest with verify-fixtures --update")
+					}
+					return nil
+				},
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func getAvailableSpecPresets() []string {
+	var presets []string
 	for _, preset := range specPresets.All() {
 		var p string
 		if preset.IsEnabled() && !preset.IsMature() {
@@ -383,3 +1455,311 @@ func isSubdomainPresetEnabled(specs string) bool {
 	// (empty or only with -/+ entries)
 	return !manualList
 }
+
+// testFilter returns the test.Filter selected by the --run, --skip, --group
+// and --spec-url flags.
+func testFilter(cctx *cli.Context) (test.Filter, error) {
+	groups := cctx.StringSlice("group")
+	for _, group := range groups {
+		if group != "" && !slices.ContainsFunc(tests.Groups, func(g string) bool { return strings.EqualFold(g, group) }) {
+			return test.Filter{}, fmt.Errorf("unknown group %q, expected one of %s", group, strings.Join(tests.Groups, ", "))
+		}
+	}
+	return test.NewFilter(cctx.String("run"), cctx.String("skip"), groups, cctx.StringSlice("spec-url"))
+}
+
+// authenticator returns the Authenticator selected by the auth flags, or nil
+// when none is set.
+func authenticator(cctx *cli.Context) (test.Authenticator, error) {
+	var auths []test.Authenticator
+
+	if token := cctx.String("auth-token"); token != "" {
+		auths = append(auths, test.BearerToken(token))
+	}
+	if basic := cctx.String("auth-basic"); basic != "" {
+		username, password, ok := strings.Cut(basic, ":")
+		if !ok {
+			return nil, fmt.Errorf("--auth-basic must be in the form 'username:password'")
+		}
+		auths = append(auths, test.BasicAuth(username, password))
+	}
+	if header := cctx.String("auth-header"); header != "" {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("--auth-header must be in the form 'Name: value'")
+		}
+		auths = append(auths, test.HeaderAuth(strings.TrimSpace(name), strings.TrimSpace(value)))
+	}
+	if command := cctx.String("auth-command"); command != "" {
+		auths = append(auths, test.TokenCommand(command, cctx.Duration("auth-command-ttl")))
+	}
+
+	switch len(auths) {
+	case 0:
+		return nil, nil
+	case 1:
+		// Fail early when the credentials cannot be obtained.
+		if _, _, err := auths[0].Header(); err != nil {
+			return nil, err
+		}
+		return auths[0], nil
+	default:
+		return nil, fmt.Errorf("only one of --auth-token, --auth-basic, --auth-header and --auth-command can be set")
+	}
+}
+
+// watchTests runs the tests, then runs them again every time the gateway
+// restarts, or the trigger file changes. The tests that failed in the previous
+// run run first, and the checks that flipped are printed as soon as their test
+// finishes.
+func watchTests(cctx *cli.Context, filter test.Filter, args []string) error {
+	interval := cctx.Duration("watch-interval")
+	var restarts <-chan struct{}
+	var waiting string
+	if trigger := cctx.String("watch-trigger"); trigger != "" {
+		restarts = watch.File(cctx.Context, trigger, interval)
+		waiting = fmt.Sprintf("Waiting for %s to change...", trigger)
+	} else {
+		health, err := healthURL(cctx.String("gateway-url"), cctx.String("watch-health"))
+		if err != nil {
+			return cli.Exit(fmt.Sprintf("⚠️ %s", err), 2)
+		}
+		restarts = watch.Health(cctx.Context, test.Client(), health, interval)
+		waiting = fmt.Sprintf("Waiting for the gateway to restart, polling %s...", health)
+	}
+
+	// os.Stdout is redirected while the tests run.
+	console := os.Stdout
+	var output io.Writer = io.Discard
+	if cctx.Bool("verbose") {
+		output = console
+	}
+
+	tracker := watch.NewTracker()
+	test.OnResult(func(r results.Test) {
+		for _, flip := range tracker.Add(r) {
+			fmt.Fprintf(console, "  %s\n", flip)
+		}
+	})
+	defer test.OnResult(nil)
+
+	for i := 1; ; i++ {
+		failing := tracker.Failing()
+		test.ResetResults()
+
+		fmt.Printf("Run #%d...\n", i)
+		if err := watchPass(output, tests.All, filter, args, failing); err != nil {
+			return err
+		}
+
+		flips := tracker.Next()
+		summary := results.Summarize(test.Results(), specPresets.All())
+		fmt.Printf("\nRun #%d: %d passed, %d failed, %d skipped, score %.1f%%\n", i, summary.Pass, summary.Fail, summary.Skip, summary.Score)
+		if i > 1 {
+			fixed := slices.DeleteFunc(slices.Clone(flips), func(f watch.Flip) bool { return !f.Fixed() })
+			fmt.Printf("%d checks now pass, %d checks now fail since run #%d\n", len(fixed), len(flips)-len(fixed), i-1)
+			for _, flip := range flips {
+				fmt.Printf("  %s\n", flip)
+			}
+		}
+		if failing := tracker.Failing(); len(failing) > 0 {
+			fmt.Println("Failing tests:")
+			for _, path := range failing {
+				fmt.Printf("  - %s\n", path)
+			}
+		}
+		fmt.Println()
+
+		fmt.Println(waiting)
+		select {
+		case _, ok := <-restarts:
+			if !ok {
+				return nil
+			}
+		case <-cctx.Context.Done():
+			return nil
+		}
+		fmt.Println()
+	}
+}
+
+// watchPass runs the tests selected by filter once, the failing ones first.
+// Every run of the pass starts from the given filter and arguments, nothing
+// is left over from the previous pass.
+func watchPass(output io.Writer, all []testing.InternalTest, filter test.Filter, args []string, failing []string) error {
+	defer test.Select(filter)
+	run := func(all []testing.InternalTest, f test.Filter) error {
+		test.Select(f)
+		_, err := runner.Run(output, all, args)
+		return err
+	}
+
+	if len(failing) == 0 {
+		return run(all, filter)
+	}
+
+	fmt.Printf("Running the %d failing tests first...\n", len(failing))
+	first := filter
+	first.Paths = failing
+	var failingTests []testing.InternalTest
+	for _, t := range all {
+		if slices.Contains(watch.TopLevel(failing), t.Name) {
+			failingTests = append(failingTests, t)
+		}
+	}
+	if err := run(failingTests, first); err != nil {
+		return err
+	}
+
+	fmt.Println("Running the other tests...")
+	rest := filter
+	rest.SkipPaths = failing
+	return run(all, rest)
+}
+
+// healthURL returns the URL polled to detect that the gateway restarted,
+// health is either a URL or a path on the gateway URL.
+func healthURL(gatewayURL, health string) (string, error) {
+	if strings.Contains(health, "://") {
+		return health, nil
+	}
+	u, err := url.Parse(gatewayURL)
+	if err != nil {
+		return "", err
+	}
+	if health != "" {
+		u = u.JoinPath(health)
+	}
+	return u.String(), nil
+}
+
+// fixturesMetadata returns the IPNS records and DNSLinks of fxs, to store them
+// in a merged CAR file.
+func fixturesMetadata(fxs *fixtures.Fixtures) (car.Metadata, error) {
+	meta := car.Metadata{IPNSRecords: map[string][]byte{}}
+	for _, file := range fxs.IPNSRecords {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return meta, err
+		}
+		meta.IPNSRecords[fixtures.IPNSName(file)] = data
+	}
+
+	links, err := dnslink.Aggregate(fxs.ConfigFiles)
+	if err != nil {
+		return meta, err
+	}
+	meta.DNSLinks = links.Domains
+	return meta, nil
+}
+
+// requestHeaders returns the headers set with --header.
+func requestHeaders(cctx *cli.Context) (http.Header, error) {
+	// The values of slice flags are split on commas, put back the values
+	// containing commas, e.g. "Cache-Control: no-cache, no-store".
+	var values []string
+	for _, v := range cctx.StringSlice("header") {
+		if len(values) > 0 && !strings.Contains(v, ":") {
+			values[len(values)-1] += ", " + v
+			continue
+		}
+		values = append(values, v)
+	}
+
+	headers := http.Header{}
+	for _, header := range values {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("--header must be in the form 'Name: value', got %q", header)
+		}
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+// applyProfile sets the flags of the test command that are not set on the
+// command line, nor with environment variables, to the settings of the
+// selected profile of the --config file. It returns the args of the profile.
+func applyProfile(cctx *cli.Context) ([]string, error) {
+	path := cctx.String("config")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) && !cctx.IsSet("config") {
+		if _, err := os.Stat(config.DefaultTOMLPath); err == nil {
+			path = config.DefaultTOMLPath
+		} else if !cctx.IsSet("profile") {
+			return nil, nil
+		}
+	}
+	c, err := config.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	profile, ok, err := c.Profile(cctx.String("profile"))
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	for name, values := range profile.Settings {
+		if !slices.ContainsFunc(cctx.Command.Flags, func(f cli.Flag) bool { return slices.Contains(f.Names(), name) }) || name == "config" || name == "profile" {
+			return nil, fmt.Errorf("invalid configuration %s: unknown setting %q", path, name)
+		}
+		if cctx.IsSet(name) {
+			continue
+		}
+		for _, value := range values {
+			if err := cctx.Set(name, value); err != nil {
+				return nil, fmt.Errorf("invalid configuration %s: %s: %w", path, name, err)
+			}
+		}
+	}
+	return profile.Args, nil
+}
+
+// runMutated runs the test command against a proxy applying the mutation to
+// the responses of upstream, and returns the results of the run. The subdomain
+// URL is moved to the port of the proxy, so that no request bypasses it.
+func runMutated(cctx *cli.Context, upstream, subdomainURL *url.URL, m mutate.Mutation) (*results.Report, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Handler: mutate.NewProxy(upstream, m)}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	dir, err := os.MkdirTemp("", "gateway-conformance-mutate-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	resultsOutput := filepath.Join(dir, "results.json")
+
+	proxied := *subdomainURL
+	proxied.Host = net.JoinHostPort(subdomainURL.Hostname(), fmt.Sprint(l.Addr().(*net.TCPAddr).Port))
+	args := []string{
+		"test",
+		"--gateway-url", "http://" + l.Addr().String(),
+		"--subdomain-url", proxied.String(),
+		"--results-output", resultsOutput,
+		"--specs", cctx.String("specs"),
+		"--parallel", fmt.Sprint(cctx.Int("parallel")),
+		"--",
+	}
+	args = append(args, cctx.Args().Slice()...)
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	// The suite fails when the mutation is caught, the results tell
+	// whether it did run.
+	output, runErr := exec.Command(exe, args...).CombinedOutput()
+
+	f, err := os.Open(resultsOutput)
+	if err != nil {
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		lines = lines[max(0, len(lines)-10):]
+		return nil, fmt.Errorf("running the tests: %v\n%s", runErr, strings.Join(lines, "\n"))
+	}
+	defer f.Close()
+	return results.Read(f)
+}
@@ -1,7 +1,16 @@
 package main
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
 	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Test cases for isSubdomainPresetEnabled function
@@ -65,3 +74,43 @@ func TestIsSubdomainPresetEnabled(t *testing.T) {
 		})
 	}
 }
+
+func TestWatchPasses(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+	}))
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+
+	get := func(path string) func(t *testing.T) {
+		return func(t *testing.T) {
+			test.RunWithSpecs(t, test.SugarTests{{
+				Name:     "GET " + path,
+				Request:  test.Request().Path(path),
+				Response: test.Expect().Status(200),
+			}})
+		}
+	}
+	all := []testing.InternalTest{{Name: "TestA", F: get("/a")}, {Name: "TestB", F: get("/b")}}
+
+	passes := []struct {
+		filter  test.Filter
+		args    []string
+		failing []string
+		want    []string
+	}{
+		{args: []string{"-skip", "TestA"}, failing: []string{"TestB/GET_%2Fb"}, want: []string{"/b"}},
+		// Neither the arguments nor the filters of the previous pass apply.
+		{filter: test.Filter{Run: regexp.MustCompile("TestA")}, want: []string{"/a"}},
+		{want: []string{"/a", "/b"}},
+	}
+	for i, pass := range passes {
+		paths = nil
+		require.NoError(t, watchPass(io.Discard, all, pass.filter, pass.args, pass.failing))
+		assert.Equal(t, pass.want, paths, "pass %d", i+1)
+	}
+}
@@ -0,0 +1,8 @@
+// Package fixtures embeds the test fixtures, so the gateway-conformance binary
+// can run the suite without a checkout of this repository.
+package fixtures
+
+import "embed"
+
+//go:embed *.car */*.car */*.ipns-record */*.yml manifest.json
+var FS embed.FS
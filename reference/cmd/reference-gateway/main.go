@@ -0,0 +1,92 @@
+// Command reference-gateway serves the conformance fixtures with the
+// reference gateway. It is a module of its own, so that the dependencies of
+// the gateway are not part of the gateway-conformance CLI.
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/ipfs/gateway-conformance/reference/gateway"
+	"github.com/ipfs/gateway-conformance/tooling"
+	"github.com/ipfs/gateway-conformance/tooling/fixtures"
+	"github.com/urfave/cli/v2"
+)
+
+func main() {
+	app := &cli.App{
+		Name:    "reference-gateway",
+		Usage:   "Reference gateways serving the fixtures of the gateway test suite",
+		Version: tooling.Version,
+		Commands: []*cli.Command{
+			{
+				Name:  "serve",
+				Usage: "Serve the fixtures with a minimal reference gateway, to run the suite without provisioning one",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "listen",
+						Aliases: []string{"l"},
+						Usage:   "The address the gateway listens on.",
+						Value:   "127.0.0.1:8080",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_LISTEN"},
+					},
+					&cli.StringSliceFlag{
+						Name:    "subdomain-host",
+						Usage:   "A host served as a subdomain gateway, e.g. the host of the --subdomain-url given to the test command. Can be repeated.",
+						Value:   cli.NewStringSlice(gateway.DefaultSubdomainHosts...),
+						EnvVars: []string{"GATEWAY_CONFORMANCE_SUBDOMAIN_HOST"},
+					},
+				},
+				Action: func(cctx *cli.Context) error {
+					fxs, err := fixtures.List()
+					if err != nil {
+						return err
+					}
+
+					handler, err := gateway.New(fxs, cctx.StringSlice("subdomain-host"))
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("Serving %d CAR files, %d IPNS records and %d DNSLink configs on http://%s\n",
+						len(fxs.CarFiles), len(fxs.IPNSRecords), len(fxs.ConfigFiles), cctx.String("listen"))
+					return http.ListenAndServe(cctx.String("listen"), handler)
+				},
+			},
+			{
+				Name:  "fixtures-backend",
+				Usage: "Serve the fixtures over the Trustless Gateway protocol, as the remote backend of the gateway under test",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "listen",
+						Aliases: []string{"l"},
+						Usage:   "The address the backend listens on.",
+						Value:   "127.0.0.1:8082",
+						EnvVars: []string{"GATEWAY_CONFORMANCE_LISTEN"},
+					},
+				},
+				Action: func(cctx *cli.Context) error {
+					fxs, err := fixtures.List()
+					if err != nil {
+						return err
+					}
+
+					handler, err := gateway.NewTrustless(fxs)
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("Serving the blocks of %d CAR files and %d IPNS records over the Trustless Gateway protocol on http://%s\n",
+						len(fxs.CarFiles), len(fxs.IPNSRecords), cctx.String("listen"))
+					return http.ListenAndServe(cctx.String("listen"), handler)
+				},
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
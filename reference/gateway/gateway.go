@@ -0,0 +1,222 @@
+// Package gateway is a minimal reference gateway serving the conformance
+// fixtures with the boxo gateway implementation. It is used to run the suite
+// without provisioning an external gateway.
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ipfs/boxo/blockservice"
+	"github.com/ipfs/boxo/blockstore"
+	"github.com/ipfs/boxo/exchange/offline"
+	"github.com/ipfs/boxo/gateway"
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/boxo/namesys"
+	offlinerouting "github.com/ipfs/boxo/routing/offline"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	logging "github.com/ipfs/go-log"
+	carblockstore "github.com/ipld/go-car/v2/blockstore"
+	"github.com/libp2p/go-libp2p/core/routing"
+
+	"github.com/ipfs/gateway-conformance/tooling/dnslink"
+	"github.com/ipfs/gateway-conformance/tooling/fixtures"
+)
+
+var log = logging.Logger("conformance")
+
+// DefaultSubdomainHosts are the hosts served as subdomain gateways, matching
+// the configuration in kubo-config.example.sh.
+var DefaultSubdomainHosts = []string{"example.com", "localhost"}
+
+// New returns a gateway serving the CAR files, IPNS records and DNSLinks of
+// the given fixtures. Requests for the subdomainHosts, and their subdomains,
+// are served as a subdomain gateway; any other host is a path gateway, or a
+// DNSLink gateway when it has a DNSLink fixture.
+func New(fxs *fixtures.Fixtures, subdomainHosts []string) (http.Handler, error) {
+	backend, err := newBackend(fxs)
+	if err != nil {
+		return nil, err
+	}
+
+	config := gateway.Config{
+		DeserializedResponses: true,
+		PublicGateways:        map[string]*gateway.PublicGateway{},
+	}
+	for _, host := range subdomainHosts {
+		config.PublicGateways[host] = &gateway.PublicGateway{
+			Paths:                 []string{"/ipfs", "/ipns"},
+			UseSubdomains:         true,
+			InlineDNSLink:         true,
+			DeserializedResponses: true,
+		}
+	}
+
+	handler := gateway.NewHandler(config, backend)
+	mux := http.NewServeMux()
+	mux.Handle("/ipfs/", handler)
+	mux.Handle("/ipns/", handler)
+
+	return tunnel(gateway.NewHeaders(nil).ApplyCors().Wrap(gateway.NewHostnameHandler(config, backend, mux))), nil
+}
+
+// NewTrustless returns a Trustless Gateway serving the blocks and CAR files
+// of the given fixtures, and their IPNS records. It only answers verifiable
+// responses, raw blocks, CARs and IPNS records, so that a gateway under test
+// can use it as its remote backend.
+func NewTrustless(fxs *fixtures.Fixtures) (http.Handler, error) {
+	backend, err := newBackend(fxs)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := gateway.NewHandler(gateway.Config{DeserializedResponses: false}, backend)
+	mux := http.NewServeMux()
+	mux.Handle("/ipfs/", handler)
+	mux.Handle("/ipns/", handler)
+	return mux, nil
+}
+
+// newBackend returns a backend holding the blocks of the CAR files and the
+// valid IPNS records of the fixtures, and resolving their DNSLinks.
+func newBackend(fxs *fixtures.Fixtures) (gateway.IPFSBackend, error) {
+	ctx := context.Background()
+
+	bs := blockstore.NewIdStore(blockstore.NewBlockstore(dssync.MutexWrap(datastore.NewMapDatastore())))
+	for _, file := range fxs.CarFiles {
+		if err := loadCAR(ctx, bs, file); err != nil {
+			return nil, fmt.Errorf("loading %s: %w", file, err)
+		}
+	}
+
+	router := offlinerouting.NewOfflineRouter(dssync.MutexWrap(datastore.NewMapDatastore()), ipns.Validator{})
+	for _, file := range fxs.IPNSRecords {
+		if err := loadIPNSRecord(ctx, router, file); err != nil {
+			// Some fixtures are invalid on purpose, a gateway must not
+			// accept them.
+			log.Debugf("skipping IPNS record %s: %v", file, err)
+		}
+	}
+
+	links, err := dnslink.Aggregate(fxs.ConfigFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, err := namesys.NewNameSystem(router, namesys.WithDNSResolver(dnsResolver(links.Domains)))
+	if err != nil {
+		return nil, err
+	}
+
+	return gateway.NewBlocksBackend(
+		blockservice.New(bs, offline.Exchange(bs)),
+		gateway.WithValueStore(router),
+		gateway.WithNameSystem(ns),
+	)
+}
+
+// tunnel serves the requests sent through an HTTP CONNECT tunnel with the
+// gateway itself, whatever host the tunnel targets.
+func tunnel(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if _, err := conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+			conn.Close()
+			return
+		}
+
+		// Serve returns once the listener is exhausted, the connection
+		// itself is served until the client closes it.
+		_ = (&http.Server{Handler: next}).Serve(&connListener{conn: conn})
+	})
+}
+
+// connListener is a net.Listener accepting a single connection.
+type connListener struct {
+	conn net.Conn
+	once sync.Once
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	var conn net.Conn
+	l.once.Do(func() { conn = l.conn })
+	if conn == nil {
+		return nil, net.ErrClosed
+	}
+	return conn, nil
+}
+
+func (l *connListener) Close() error   { return nil }
+func (l *connListener) Addr() net.Addr { return l.conn.LocalAddr() }
+
+func loadCAR(ctx context.Context, bs blockstore.Blockstore, file string) error {
+	car, err := carblockstore.OpenReadOnly(file, carblockstore.UseWholeCIDs(true))
+	if err != nil {
+		return err
+	}
+	defer car.Close()
+
+	cids, err := car.AllKeysChan(ctx)
+	if err != nil {
+		return err
+	}
+
+	for c := range cids {
+		block, err := car.Get(ctx, c)
+		if err != nil {
+			return err
+		}
+		if err := bs.Put(ctx, block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadIPNSRecord stores the record under the name found in its file name, see
+// fixtures.List.
+func loadIPNSRecord(ctx context.Context, vs routing.ValueStore, file string) error {
+	name, err := ipns.NameFromString(fixtures.IPNSName(file))
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	return vs.PutValue(ctx, string(name.RoutingKey()), data)
+}
+
+// dnsResolver answers the DNSLink TXT lookups from the fixtures, it never
+// reaches the network.
+type dnsResolver map[string]string
+
+func (r dnsResolver) LookupIPAddr(_ context.Context, name string) ([]net.IPAddr, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r dnsResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	domain := strings.TrimSuffix(strings.TrimPrefix(name, "_dnslink."), ".")
+	if path, ok := r[domain]; ok {
+		return []string{"dnslink=" + path}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
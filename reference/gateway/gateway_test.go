@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling/car"
+	"github.com/ipfs/gateway-conformance/tooling/fixtures"
+	"github.com/ipfs/gateway-conformance/tooling/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateway(t *testing.T) {
+	fxs, err := fixtures.List()
+	require.NoError(t, err)
+	handler, err := New(fxs, DefaultSubdomainHosts)
+	require.NoError(t, err)
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	fixture := car.MustOpenUnixfsCar("subdomain_gateway/fixtures.car")
+	hello := string(fixture.MustGetRawData("hello-CIDv1"))
+	cidV1 := fixture.MustGetCid("hello-CIDv1")
+	cidV0 := fixture.MustGetCid("hello-CIDv0")
+	cidV0to1 := fixture.MustGetCid("hello-CIDv0to1")
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	tests := []struct {
+		name        string
+		path        string
+		host        string
+		accept      string
+		status      int
+		contentType string
+		body        string
+		location    string
+	}{
+		{name: "path", path: "/ipfs/" + cidV1, status: 200, body: hello},
+		{name: "raw block", path: "/ipfs/" + cidV1, accept: "application/vnd.ipld.raw", status: 200, contentType: "application/vnd.ipld.raw"},
+		{name: "CAR", path: "/ipfs/" + cidV1, accept: "application/vnd.ipld.car", status: 200, contentType: "application/vnd.ipld.car"},
+		{name: "IPNS record", path: "/ipns/k51qzi5uqu5dit2ku9mutlfgwyz8u730on38kd10m97m36bjt66my99hb6103f", accept: "application/vnd.ipfs.ipns-record", status: 200, contentType: "application/vnd.ipfs.ipns-record"},
+		{name: "invalid IPNS record", path: "/ipns/k51qzi5uqu5diamp7qnnvs1p1gzmku3eijkeijs3418j23j077zrkok63xdm8c", accept: "application/vnd.ipfs.ipns-record", status: 500},
+		{name: "subdomain redirect", path: "/ipfs/" + cidV0, host: "example.com", status: 301, location: "http://" + cidV0to1 + ".ipfs.example.com/"},
+		{name: "subdomain", path: "/", host: cidV1 + ".ipfs.example.com", status: 200, body: hello},
+		{name: "DNSLink", path: "/", host: "dnslink-test.example.org", status: 200, body: hello},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+			require.NoError(t, err)
+			req.Host = tt.host
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			res, err := client.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.status, res.StatusCode, string(body))
+			assert.True(t, strings.HasPrefix(res.Header.Get("Content-Type"), tt.contentType), res.Header.Get("Content-Type"))
+			if tt.body != "" {
+				assert.Equal(t, tt.body, string(body))
+			}
+			assert.Equal(t, tt.location, res.Header.Get("Location"))
+			assert.Equal(t, "*", res.Header.Get("Access-Control-Allow-Origin"))
+		})
+	}
+
+	t.Run("proxy tunnel", func(t *testing.T) {
+		res, err := test.NewProxyTunnelClient(srv.URL).Get("http://" + cidV1 + ".ipfs.example.com/")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, 200, res.StatusCode)
+		assert.Equal(t, hello, string(body))
+	})
+}
+
+func TestTrustless(t *testing.T) {
+	fxs, err := fixtures.List()
+	require.NoError(t, err)
+	handler, err := NewTrustless(fxs)
+	require.NoError(t, err)
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	cidV1 := car.MustOpenUnixfsCar("subdomain_gateway/fixtures.car").MustGetCid("hello-CIDv1")
+
+	tests := []struct {
+		name        string
+		path        string
+		status      int
+		contentType string
+	}{
+		{name: "raw block", path: "/ipfs/" + cidV1 + "?format=raw", status: 200, contentType: "application/vnd.ipld.raw"},
+		{name: "CAR", path: "/ipfs/" + cidV1 + "?format=car", status: 200, contentType: "application/vnd.ipld.car"},
+		{name: "IPNS record", path: "/ipns/k51qzi5uqu5dit2ku9mutlfgwyz8u730on38kd10m97m36bjt66my99hb6103f?format=ipns-record", status: 200, contentType: "application/vnd.ipfs.ipns-record"},
+		{name: "deserialized", path: "/ipfs/" + cidV1, status: 406},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(srv.URL + tt.path)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.status, res.StatusCode, string(body))
+			assert.True(t, strings.HasPrefix(res.Header.Get("Content-Type"), tt.contentType), res.Header.Get("Content-Type"))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package gateway
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tests"
+	"github.com/ipfs/gateway-conformance/tooling/fixtures"
+	"github.com/ipfs/gateway-conformance/tooling/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCatalogListsEveryTestThatRuns runs the suite against the reference
+// gateway, then lists it: every test that runs must be listed.
+func TestCatalogListsEveryTestThatRuns(t *testing.T) {
+	fxs, err := fixtures.List()
+	require.NoError(t, err)
+	handler, err := New(fxs, DefaultSubdomainHosts)
+	require.NoError(t, err)
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+	t.Setenv("SUBDOMAIN_GATEWAY_URL", "http://example.com")
+
+	all := func(t *testing.T) {
+		for _, tt := range tests.All {
+			t.Run(tt.Name, tt.F)
+		}
+	}
+
+	t.Run("run", all)
+	var ran []string
+	for _, r := range test.Results() {
+		if path, ok := strings.CutPrefix(r.Path, t.Name()+"/run/"); ok {
+			ran = append(ran, path)
+		}
+	}
+
+	defer func(listOnly bool) { test.ListOnly = listOnly }(test.ListOnly)
+	test.ListOnly = true
+
+	t.Run("list", all)
+	var listed []string
+	for _, e := range test.Catalog() {
+		if path, ok := strings.CutPrefix(e.Path, t.Name()+"/list/"); ok {
+			listed = append(listed, path)
+		}
+	}
+
+	require.NotEmpty(t, ran)
+	assert.ElementsMatch(t, ran, listed)
+}
@@ -33,3 +33,15 @@ const (
 	GroupTar        = "Tar"
 	GroupUnixFS     = "UnixFS"
 )
+
+// Groups lists every group a test of the suite can belong to.
+var Groups = []string{
+	GroupSubdomains,
+	GroupCORS,
+	GroupIPNS,
+	GroupDNSLink,
+	GroupJSONCbor,
+	GroupBlockCar,
+	GroupTar,
+	GroupUnixFS,
+}
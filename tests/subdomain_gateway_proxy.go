@@ -19,15 +19,15 @@ var (
 	CIDv0    = fixture.MustGetCid("hello-CIDv0")
 	CIDv0to1 = fixture.MustGetCid("hello-CIDv0to1")
 	//CIDv1_TOO_LONG = fixture.MustGetCid("hello-CIDv1_TOO_LONG")
+)
 
+func TestProxyGatewaySubdomains(t *testing.T) {
 	// the gateway endpoint is used as HTTP proxy
-	gatewayAsProxyURL = GatewayURL().String()
+	gatewayAsProxyURL := GatewayURL().String()
 
 	// run against origins explicitly passed via --subdomain-url
-	s = SubdomainGatewayURL()
-)
+	s := SubdomainGatewayURL()
 
-func TestProxyGatewaySubdomains(t *testing.T) {
 	tests := SugarTests{
 		{
 			Name: "request for {CID}.ipfs.example.com should return expected payload",
@@ -81,6 +81,12 @@ func TestProxyGatewaySubdomains(t *testing.T) {
 }
 
 func TestProxyTunnelGatewaySubdomains(t *testing.T) {
+	// the gateway endpoint is used as HTTP proxy
+	gatewayAsProxyURL := GatewayURL().String()
+
+	// run against origins explicitly passed via --subdomain-url
+	s := SubdomainGatewayURL()
+
 	tests := SugarTests{
 		{
 			Name: "request for {CID}.ipfs.example.com should return expected payload",
@@ -0,0 +1,46 @@
+package tests
+
+import "testing"
+
+// All lists every test of the conformance suite, in the order `go test` used
+// to run them. New tests MUST be registered here to be picked up by the
+// `gateway-conformance test` command.
+var All = []testing.InternalTest{
+	{Name: "TestDNSLinkGatewayUnixFSDirectoryListing", F: TestDNSLinkGatewayUnixFSDirectoryListing},
+	{Name: "TestMetadata", F: TestMetadata},
+	{Name: "TestCors", F: TestCors},
+	{Name: "TestGatewayJsonCbor", F: TestGatewayJsonCbor},
+	{Name: "TestDagPbConversion", F: TestDagPbConversion},
+	{Name: "TestPlainCodec", F: TestPlainCodec},
+	{Name: "TestPathing", F: TestPathing},
+	{Name: "TestNativeDag", F: TestNativeDag},
+	{Name: "TestGatewayJSONCborAndIPNS", F: TestGatewayJSONCborAndIPNS},
+	{Name: "TestGatewayIPNSPath", F: TestGatewayIPNSPath},
+	{Name: "TestRedirectCanonicalIPNS", F: TestRedirectCanonicalIPNS},
+	{Name: "TestGatewayBlock", F: TestGatewayBlock},
+	{Name: "TestTar", F: TestTar},
+	{Name: "TestUnixFSDirectoryListing", F: TestUnixFSDirectoryListing},
+	{Name: "TestGatewayCache", F: TestGatewayCache},
+	{Name: "TestGatewayCacheWithIPNS", F: TestGatewayCacheWithIPNS},
+	{Name: "TestGatewaySymlink", F: TestGatewaySymlink},
+	{Name: "TestGatewayUnixFSFileRanges", F: TestGatewayUnixFSFileRanges},
+	{Name: "TestPathGatewayMiscellaneous", F: TestPathGatewayMiscellaneous},
+	{Name: "TestRedirectsFileSupport", F: TestRedirectsFileSupport},
+	{Name: "TestRedirectsFileSupportWithDNSLink", F: TestRedirectsFileSupportWithDNSLink},
+	{Name: "TestRedirectsFileWithIfNoneMatchHeader", F: TestRedirectsFileWithIfNoneMatchHeader},
+	{Name: "TestUnixFSDirectoryListingOnSubdomainGateway", F: TestUnixFSDirectoryListingOnSubdomainGateway},
+	{Name: "TestGatewaySubdomains", F: TestGatewaySubdomains},
+	{Name: "TestGatewaySubdomainAndIPNS", F: TestGatewaySubdomainAndIPNS},
+	{Name: "TestSubdomainGatewayDNSLinkInlining", F: TestSubdomainGatewayDNSLinkInlining},
+	{Name: "TestProxyGatewaySubdomains", F: TestProxyGatewaySubdomains},
+	{Name: "TestProxyTunnelGatewaySubdomains", F: TestProxyTunnelGatewaySubdomains},
+	{Name: "TestTrustlessCarPathing", F: TestTrustlessCarPathing},
+	{Name: "TestTrustlessCarDagScopeBlock", F: TestTrustlessCarDagScopeBlock},
+	{Name: "TestTrustlessCarDagScopeEntity", F: TestTrustlessCarDagScopeEntity},
+	{Name: "TestTrustlessCarDagScopeAll", F: TestTrustlessCarDagScopeAll},
+	{Name: "TestTrustlessCarEntityBytes", F: TestTrustlessCarEntityBytes},
+	{Name: "TestTrustlessCarOrderAndDuplicates", F: TestTrustlessCarOrderAndDuplicates},
+	{Name: "TestGatewayIPNSRecord", F: TestGatewayIPNSRecord},
+	{Name: "TestTrustlessRaw", F: TestTrustlessRaw},
+	{Name: "TestTrustlessRawRanges", F: TestTrustlessRawRanges},
+}
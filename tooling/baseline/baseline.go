@@ -0,0 +1,185 @@
+// Package baseline lists the tests a gateway is known to fail, so that the
+// suite can pass on gateways that only partially conform, while still
+// catching regressions.
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ipfs/gateway-conformance/tooling/report"
+	"gopkg.in/yaml.v3"
+)
+
+// Baseline lists the tests expected to fail.
+type Baseline struct {
+	Failures []Entry `json:"failures" yaml:"failures"`
+}
+
+// Entry is a test expected to fail. It matches the test with the given path
+// and all of its subtests.
+type Entry struct {
+	// Test is the full path of the test, as reported by the test command,
+	// e.g. "TestTrustlessCarPathing/GET_CAR_with_format=car".
+	Test   string `json:"test" yaml:"test"`
+	Reason string `json:"reason" yaml:"reason"`
+	// Issue is an optional link to the issue tracking the failure.
+	Issue string `json:"issue,omitempty" yaml:"issue,omitempty"`
+}
+
+// Matches reports whether the entry covers the test with the given path.
+func (e Entry) Matches(test string) bool {
+	return test == e.Test || strings.HasPrefix(test, e.Test+"/")
+}
+
+// Read parses a baseline file. JSON is a subset of YAML, so both formats are
+// read the same way.
+func Read(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var b Baseline
+	if err := yaml.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid baseline %s: %w", path, err)
+	}
+	for i, e := range b.Failures {
+		if e.Test == "" {
+			return nil, fmt.Errorf("invalid baseline %s: entry %d has no test", path, i)
+		}
+	}
+	return &b, nil
+}
+
+// Write writes the baseline as JSON when path ends with .json, as YAML
+// otherwise.
+func Write(w io.Writer, path string, b Baseline) error {
+	if b.Failures == nil {
+		b.Failures = []Entry{}
+	}
+
+	if filepath.Ext(path) == ".json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(b)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(b); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// FromReport returns a baseline with an entry per failing subtest of the
+// top-level tests, or per failing top-level test without failing subtests.
+// The entries of previous, when set, covering a failing test are kept as is,
+// reason is used for the new ones.
+func FromReport(r *report.Report, previous *Baseline, reason string) Baseline {
+	failed := map[string]bool{}
+	for _, t := range r.Tests {
+		if t.Outcome == report.Fail {
+			failed[t.Name] = true
+		}
+	}
+
+	var b Baseline
+	for _, t := range r.Tests {
+		depth := len(t.Path())
+		if !failed[t.Name] || depth > 2 || depth == 1 && hasFailedChild(r, failed, t.Name) {
+			continue
+		}
+
+		entry := Entry{Test: t.Name, Reason: reason}
+		if previous != nil {
+			i := slices.IndexFunc(previous.Failures, func(e Entry) bool { return e.Matches(t.Name) })
+			if i >= 0 {
+				entry = previous.Failures[i]
+			}
+		}
+		if !slices.Contains(b.Failures, entry) {
+			b.Failures = append(b.Failures, entry)
+		}
+	}
+	return b
+}
+
+func hasFailedChild(r *report.Report, failed map[string]bool, name string) bool {
+	for _, child := range r.Children(name) {
+		if failed[child.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+// Result compares the outcome of a run with a baseline.
+type Result struct {
+	// Regressions lists the failing tests not covered by the baseline.
+	Regressions []string
+	// Expected lists the entries covering a failing test.
+	Expected []Entry
+	// Passing lists the entries whose tests ran and passed, they should be
+	// removed from the baseline.
+	Passing []Entry
+}
+
+// OK reports whether every failing test is covered by the baseline.
+func (r Result) OK() bool {
+	return len(r.Regressions) == 0
+}
+
+// Compare returns the failing tests of the run not covered by the baseline,
+// and the entries of the baseline that no longer fail. Only the innermost
+// failing tests are compared, their parents fail with them.
+func Compare(b Baseline, r *report.Report) Result {
+	failed := map[string]bool{}
+	for _, t := range r.Tests {
+		if t.Outcome == report.Fail {
+			failed[t.Name] = true
+		}
+	}
+
+	var res Result
+	covered := make([]bool, len(b.Failures))
+	ran := make([]bool, len(b.Failures))
+	for _, t := range r.Tests {
+		for i, e := range b.Failures {
+			if e.Matches(t.Name) && t.Outcome != report.Skip {
+				ran[i] = true
+			}
+		}
+
+		if !failed[t.Name] || hasFailedChild(r, failed, t.Name) {
+			continue
+		}
+
+		expected := false
+		for i, e := range b.Failures {
+			if e.Matches(t.Name) {
+				covered[i] = true
+				expected = true
+			}
+		}
+		if !expected {
+			res.Regressions = append(res.Regressions, t.Name)
+		}
+	}
+
+	for i, e := range b.Failures {
+		switch {
+		case covered[i]:
+			res.Expected = append(res.Expected, e)
+		case ran[i]:
+			res.Passing = append(res.Passing, e)
+		}
+	}
+	return res
+}
@@ -0,0 +1,100 @@
+package baseline
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func run(outcomes ...string) *report.Report {
+	r := &report.Report{}
+	for i := 0; i < len(outcomes); i += 2 {
+		r.Tests = append(r.Tests, &report.Test{Name: outcomes[i], Outcome: outcomes[i+1]})
+	}
+	return r
+}
+
+func TestFromReport(t *testing.T) {
+	r := run(
+		"TestA", report.Fail,
+		"TestA/one", report.Fail,
+		"TestA/one/Status_code", report.Fail,
+		"TestA/two", report.Pass,
+		"TestB", report.Fail,
+		"TestC", report.Pass,
+		"TestE", report.Fail,
+		"TestE/one", report.Fail,
+		"TestE/two", report.Fail,
+	)
+	previous := &Baseline{Failures: []Entry{
+		{Test: "TestA/one", Reason: "not implemented", Issue: "https://example.com/issues/1"},
+		{Test: "TestD", Reason: "removed"},
+		{Test: "TestE", Reason: "not supported"},
+	}}
+
+	b := FromReport(r, previous, "new")
+	assert.Equal(t, []Entry{
+		{Test: "TestA/one", Reason: "not implemented", Issue: "https://example.com/issues/1"},
+		{Test: "TestB", Reason: "new"},
+		{Test: "TestE", Reason: "not supported"},
+	}, b.Failures)
+}
+
+func TestCompare(t *testing.T) {
+	b := Baseline{Failures: []Entry{
+		{Test: "TestA/one", Reason: "known"},
+		{Test: "TestA/two", Reason: "fixed"},
+		{Test: "TestB", Reason: "not run"},
+	}}
+
+	res := Compare(b, run(
+		"TestA", report.Fail,
+		"TestA/one", report.Fail,
+		"TestA/one/Status_code", report.Fail,
+		"TestA/two", report.Pass,
+		"TestA/two/Status_code", report.Pass,
+		"TestA/three", report.Fail,
+		"TestA/three/Body", report.Fail,
+		"TestB", report.Skip,
+	))
+	assert.False(t, res.OK())
+	assert.Equal(t, []string{"TestA/three/Body"}, res.Regressions)
+	assert.Equal(t, []Entry{b.Failures[0]}, res.Expected)
+	assert.Equal(t, []Entry{b.Failures[1]}, res.Passing)
+
+	res = Compare(b, run(
+		"TestA", report.Fail,
+		"TestA/one", report.Fail,
+	))
+	assert.True(t, res.OK())
+}
+
+func TestReadWrite(t *testing.T) {
+	b := Baseline{Failures: []Entry{
+		{Test: "TestA/one", Reason: "not implemented", Issue: "https://example.com/issues/1"},
+		{Test: "TestB", Reason: "known"},
+	}}
+
+	for _, name := range []string{"baseline.yml", "baseline.json"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			var buf bytes.Buffer
+			require.NoError(t, Write(&buf, path, b))
+			require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+
+			read, err := Read(path)
+			require.NoError(t, err)
+			assert.Equal(t, b, *read)
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "invalid.yml")
+	require.NoError(t, os.WriteFile(path, []byte("failures:\n  - reason: no test\n"), 0644))
+	_, err := Read(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,707 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sort"
+	"strings"
+
+	chunker "github.com/ipfs/boxo/chunker"
+	"github.com/ipfs/boxo/ipld/merkledag"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+	"github.com/ipfs/boxo/ipld/unixfs/hamt"
+	"github.com/ipfs/boxo/ipld/unixfs/importer/balanced"
+	"github.com/ipfs/boxo/ipld/unixfs/importer/helpers"
+	"github.com/ipfs/boxo/ipld/unixfs/importer/trickle"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	carv2 "github.com/ipld/go-car/v2"
+	"github.com/ipld/go-car/v2/storage"
+	dagpb "github.com/ipld/go-codec-dagpb"
+	"github.com/ipld/go-ipld-prime"
+	_ "github.com/ipld/go-ipld-prime/codec/dagcbor"
+	_ "github.com/ipld/go-ipld-prime/codec/dagjson"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/multiformats/go-multicodec"
+	"github.com/multiformats/go-multihash"
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults of the options, matching `ipfs add`.
+const (
+	DefaultChunkSize     = 256 * 1024
+	DefaultHAMTThreshold = 256 * 1024
+	DefaultHAMTFanout    = 256
+)
+
+// BuildFile returns the CAR file of the description at path.
+func BuildFile(path string) ([]byte, error) {
+	d, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := Build(&buf, d); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Build writes the CAR file of the description to w. Its blocks are written
+// in the depth-first order of the DAGs of the roots, every block once.
+func Build(w io.Writer, d *Description) error {
+	b := &builder{
+		ctx:     context.Background(),
+		dag:     newDAG(),
+		nodes:   d.Nodes,
+		built:   map[string]link{},
+		options: d.Options,
+	}
+
+	var roots []cid.Cid
+	for i, root := range d.Roots {
+		l, err := b.node(d.Options, root)
+		if err != nil {
+			return fmt.Errorf("root %d: %w", i, err)
+		}
+		roots = append(roots, l.Cid)
+	}
+	return b.dag.writeCAR(w, roots)
+}
+
+// link is a built node.
+type link struct {
+	Cid cid.Cid
+	// Size is the total size of the DAG of the node, as in the links of
+	// UnixFS directories.
+	Size uint64
+}
+
+type builder struct {
+	ctx   context.Context
+	dag   *dag
+	nodes map[string]*Node
+	// built holds the named nodes already built, building is set while
+	// a named node is being built, to detect cycles.
+	built    map[string]link
+	building []string
+	// options are the options of the description, the named nodes are
+	// built with them.
+	options Options
+}
+
+func (b *builder) node(parent Options, n *Node) (link, error) {
+	if n.Ref != "" {
+		return b.named(n.Ref)
+	}
+
+	opts := parent.inherit(n.Options)
+	switch {
+	case n.File != nil:
+		return b.file(opts, n.File)
+	case n.Directory != nil:
+		return b.directory(opts, n.Directory)
+	case n.Symlink != nil:
+		data, err := ft.SymlinkData(*n.Symlink)
+		if err != nil {
+			return link{}, err
+		}
+		return b.protoNode(opts, merkledag.NodeWithData(data))
+	case n.DagCBOR.Kind != 0:
+		return b.ipldNode(opts, n, cid.DagCBOR, &n.DagCBOR)
+	case n.DagJSON.Kind != 0:
+		return b.ipldNode(opts, n, cid.DagJSON, &n.DagJSON)
+	case n.DagPB != nil:
+		return b.dagPB(opts, n.DagPB)
+	default:
+		codec, err := n.codec(cid.Raw)
+		if err != nil {
+			return link{}, err
+		}
+		return b.block(opts, codec, []byte(*n.Raw))
+	}
+}
+
+// codec returns the multicodec of the CID of n, def unless set.
+func (n *Node) codec(def uint64) (uint64, error) {
+	if n.Codec == "" {
+		return def, nil
+	}
+	var code multicodec.Code
+	if err := code.Set(n.Codec); err != nil {
+		return 0, err
+	}
+	return uint64(code), nil
+}
+
+func (b *builder) named(name string) (link, error) {
+	if l, ok := b.built[name]; ok {
+		return l, nil
+	}
+	for _, building := range b.building {
+		if building == name {
+			return link{}, fmt.Errorf("@%s refers to itself", name)
+		}
+	}
+	n, ok := b.nodes[name]
+	if !ok {
+		return link{}, fmt.Errorf("unknown node @%s", name)
+	}
+
+	b.building = append(b.building, name)
+	defer func() { b.building = b.building[:len(b.building)-1] }()
+
+	// Named nodes may be used in several places, they do not inherit the
+	// options of the parents.
+	l, err := b.node(b.options, n)
+	if err != nil {
+		return link{}, fmt.Errorf("@%s: %w", name, err)
+	}
+	b.built[name] = l
+	return l, nil
+}
+
+// prefix returns the CID prefix of the nodes of opts with the given codec.
+func prefix(opts Options, codec uint64) (cid.Prefix, error) {
+	version := or(opts.CIDVersion, 0)
+	hash := or(opts.Hash, "sha2-256")
+	code, ok := multihash.Names[hash]
+	if !ok {
+		return cid.Prefix{}, fmt.Errorf("unknown hash %q", hash)
+	}
+	if code != multihash.SHA2_256 {
+		version = 1
+	}
+	if version == 0 && codec != cid.DagProtobuf {
+		return cid.Prefix{}, fmt.Errorf("CIDv0 only supports dag-pb nodes, set cid-version: 1")
+	}
+	return cid.Prefix{Version: uint64(version), Codec: codec, MhType: code, MhLength: -1}, nil
+}
+
+func (b *builder) file(opts Options, f *File) (link, error) {
+	pb, err := prefix(opts, cid.DagProtobuf)
+	if err != nil {
+		return link{}, err
+	}
+
+	var r io.Reader = strings.NewReader(strings.Repeat(f.Text, max(f.Repeat, 1)))
+	switch {
+	case f.Base64 != "":
+		data, err := base64.StdEncoding.DecodeString(f.Base64)
+		if err != nil {
+			return link{}, err
+		}
+		r = bytes.NewReader(data)
+	case f.Size > 0:
+		r = io.LimitReader(newRandom(f.Seed), int64(f.Size))
+	}
+
+	params := helpers.DagBuilderParams{
+		Dagserv:    b.dag,
+		RawLeaves:  or(opts.RawLeaves, pb.Version == 1),
+		Maxlinks:   or(opts.MaxLinks, helpers.DefaultLinksPerBlock),
+		CidBuilder: pb,
+	}
+	db, err := params.New(chunker.NewSizeSplitter(r, int64(or(opts.ChunkSize, DefaultChunkSize))))
+	if err != nil {
+		return link{}, err
+	}
+
+	var nd format.Node
+	switch layout := or(opts.Layout, "balanced"); layout {
+	case "balanced":
+		nd, err = balanced.Layout(db)
+	case "trickle":
+		nd, err = trickle.Layout(db)
+	default:
+		return link{}, fmt.Errorf("unknown layout %q, expected balanced or trickle", layout)
+	}
+	if err != nil {
+		return link{}, err
+	}
+	return nodeLink(nd)
+}
+
+func (b *builder) directory(opts Options, entries map[string]*Node) (link, error) {
+	pb, err := prefix(opts, cid.DagProtobuf)
+	if err != nil {
+		return link{}, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	links := make([]*format.Link, 0, len(names))
+	estimatedSize := 0
+	for _, name := range names {
+		l, err := b.node(opts, entries[name])
+		if err != nil {
+			return link{}, fmt.Errorf("%s: %w", name, err)
+		}
+		links = append(links, &format.Link{Name: name, Cid: l.Cid, Size: l.Size})
+		// The estimation of boxo, see productionLinkSize in
+		// ipld/unixfs/io.
+		estimatedSize += len(name) + l.Cid.ByteLen()
+	}
+
+	if estimatedSize < or(opts.HAMTThreshold, DefaultHAMTThreshold) {
+		nd := merkledag.NodeWithData(ft.FolderPBData())
+		for _, l := range links {
+			if err := nd.AddRawLink(l.Name, l); err != nil {
+				return link{}, err
+			}
+		}
+		return b.protoNode(opts, nd)
+	}
+
+	shard, err := hamt.NewShard(b.dag, or(opts.HAMTFanout, DefaultHAMTFanout))
+	if err != nil {
+		return link{}, err
+	}
+	shard.SetCidBuilder(pb)
+	for _, l := range links {
+		if err := shard.SetLink(b.ctx, l.Name, l); err != nil {
+			return link{}, err
+		}
+	}
+	nd, err := shard.Node()
+	if err != nil {
+		return link{}, err
+	}
+	return nodeLink(nd)
+}
+
+// dagPB stores a dag-pb node assembled from its UnixFS data and links. It is
+// encoded with go-codec-dagpb, which unlike merkledag can leave out the size
+// of the links.
+func (b *builder) dagPB(opts Options, d *DagPB) (link, error) {
+	var data []byte
+	switch d.Data.Type {
+	case "file":
+		fsn := ft.NewFSNode(ft.TFile)
+		for _, size := range d.Data.BlockSizes {
+			fsn.AddBlockSize(size)
+		}
+		var err error
+		data, err = fsn.GetBytes()
+		if err != nil {
+			return link{}, err
+		}
+	case "directory":
+		data = ft.FolderPBData()
+	default:
+		return link{}, fmt.Errorf("unknown UnixFS type %q, expected file or directory", d.Data.Type)
+	}
+
+	type pbLink struct {
+		name string
+		link
+		noSize bool
+	}
+	var links []pbLink
+	var linksSize uint64
+	for i, l := range d.Links {
+		target := link{}
+		if l.Node != nil {
+			var err error
+			target, err = b.node(opts, l.Node)
+			if err != nil {
+				return link{}, fmt.Errorf("link %d: %w", i, err)
+			}
+		} else {
+			c, err := cid.Parse(l.CID)
+			if err != nil {
+				return link{}, fmt.Errorf("link %d: %w", i, err)
+			}
+			target.Cid = c
+		}
+		if l.Size != nil {
+			target.Size = *l.Size
+		}
+		if l.NoSize {
+			target.Size = 0
+		}
+		links = append(links, pbLink{name: l.Name, link: target, noSize: l.NoSize})
+		linksSize += target.Size
+	}
+
+	node, err := qp.BuildMap(basicnode.Prototype.Any, 2, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "Data", qp.Bytes(data))
+		qp.MapEntry(ma, "Links", qp.List(int64(len(links)), func(la datamodel.ListAssembler) {
+			for _, l := range links {
+				qp.ListEntry(la, qp.Map(3, func(ma datamodel.MapAssembler) {
+					qp.MapEntry(ma, "Hash", qp.Link(cidlink.Link{Cid: l.Cid}))
+					qp.MapEntry(ma, "Name", qp.String(l.name))
+					if !l.noSize {
+						qp.MapEntry(ma, "Tsize", qp.Int(int64(l.Size)))
+					}
+				}))
+			}
+		}))
+	})
+	if err != nil {
+		return link{}, err
+	}
+	var buf bytes.Buffer
+	if err := dagpb.Encode(node, &buf); err != nil {
+		return link{}, err
+	}
+
+	l, err := b.block(opts, cid.DagProtobuf, buf.Bytes())
+	if err != nil {
+		return link{}, err
+	}
+	// The size of a dag-pb DAG is the size of its root and of its links,
+	// as in merkledag.
+	l.Size += linksSize
+	return l, nil
+}
+
+// protoNode stores a dag-pb node.
+func (b *builder) protoNode(opts Options, nd *merkledag.ProtoNode) (link, error) {
+	pb, err := prefix(opts, cid.DagProtobuf)
+	if err != nil {
+		return link{}, err
+	}
+	if err := nd.SetCidBuilder(pb); err != nil {
+		return link{}, err
+	}
+	if err := b.dag.Add(b.ctx, nd); err != nil {
+		return link{}, err
+	}
+	return nodeLink(nd)
+}
+
+func nodeLink(nd format.Node) (link, error) {
+	size, err := nd.Size()
+	if err != nil {
+		return link{}, err
+	}
+	return link{Cid: nd.Cid(), Size: size}, nil
+}
+
+// block stores a block with the given codec.
+func (b *builder) block(opts Options, codec uint64, data []byte) (link, error) {
+	p, err := prefix(opts, codec)
+	if err != nil {
+		return link{}, err
+	}
+	c, err := p.Sum(data)
+	if err != nil {
+		return link{}, err
+	}
+	blk, err := blocks.NewBlockWithCid(data, c)
+	if err != nil {
+		return link{}, err
+	}
+	b.dag.put(blk)
+	return link{Cid: c, Size: uint64(len(data))}, nil
+}
+
+// ipldNode stores the node n, of value encoded with the DAG-CBOR or DAG-JSON
+// encoding.
+func (b *builder) ipldNode(opts Options, n *Node, encoding uint64, value *yaml.Node) (link, error) {
+	codec, err := n.codec(encoding)
+	if err != nil {
+		return link{}, err
+	}
+	node, err := b.value(opts, value)
+	if err != nil {
+		return link{}, err
+	}
+	var buf bytes.Buffer
+	encoder, err := cidlink.DefaultLinkSystem().EncoderChooser(cidlink.LinkPrototype{Prefix: cid.Prefix{Codec: encoding}})
+	if err != nil {
+		return link{}, err
+	}
+	if err := encoder(node, &buf); err != nil {
+		return link{}, err
+	}
+	return b.block(opts, codec, buf.Bytes())
+}
+
+// value converts a YAML value to an IPLD node, see Node.DagCBOR.
+func (b *builder) value(opts Options, value *yaml.Node) (datamodel.Node, error) {
+	if value.Kind == yaml.AliasNode {
+		value = value.Alias
+	}
+	switch value.Kind {
+	case yaml.ScalarNode:
+		var v any
+		if err := value.Decode(&v); err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case nil:
+			return datamodel.Null, nil
+		case bool:
+			return basicnode.NewBool(v), nil
+		case int:
+			return basicnode.NewInt(int64(v)), nil
+		case float64:
+			return basicnode.NewFloat(v), nil
+		default:
+			return basicnode.NewString(value.Value), nil
+		}
+	case yaml.SequenceNode:
+		nb := basicnode.Prototype.List.NewBuilder()
+		la, err := nb.BeginList(int64(len(value.Content)))
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range value.Content {
+			v, err := b.value(opts, item)
+			if err != nil {
+				return nil, err
+			}
+			if err := la.AssembleValue().AssignNode(v); err != nil {
+				return nil, err
+			}
+		}
+		if err := la.Finish(); err != nil {
+			return nil, err
+		}
+		return nb.Build(), nil
+	case yaml.MappingNode:
+		if len(value.Content) == 2 && value.Content[0].Value == "/" {
+			return b.slash(opts, value.Content[1])
+		}
+		nb := basicnode.Prototype.Map.NewBuilder()
+		ma, err := nb.BeginMap(int64(len(value.Content) / 2))
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(value.Content); i += 2 {
+			v, err := b.value(opts, value.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if err := ma.AssembleKey().AssignString(value.Content[i].Value); err != nil {
+				return nil, err
+			}
+			if err := ma.AssembleValue().AssignNode(v); err != nil {
+				return nil, err
+			}
+		}
+		if err := ma.Finish(); err != nil {
+			return nil, err
+		}
+		return nb.Build(), nil
+	}
+	return nil, fmt.Errorf("line %d: unsupported value", value.Line)
+}
+
+// slash converts the value of a {"/": value} map, a link or bytes.
+func (b *builder) slash(opts Options, value *yaml.Node) (datamodel.Node, error) {
+	if value.Kind == yaml.MappingNode && len(value.Content) == 2 && value.Content[0].Value == "bytes" {
+		data, err := base64.RawStdEncoding.DecodeString(value.Content[1].Value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", value.Line, err)
+		}
+		return basicnode.NewBytes(data), nil
+	}
+
+	var target Node
+	if value.Kind == yaml.ScalarNode && value.Value != "" && value.Value[0] != '@' {
+		c, err := cid.Parse(value.Value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", value.Line, err)
+		}
+		return basicnode.NewLink(cidlink.Link{Cid: c}), nil
+	}
+	if err := value.Decode(&target); err != nil {
+		return nil, err
+	}
+	l, err := b.node(opts, &target)
+	if err != nil {
+		return nil, err
+	}
+	return basicnode.NewLink(cidlink.Link{Cid: l.Cid}), nil
+}
+
+var _ format.DAGService = (*dag)(nil)
+
+// dag holds the built blocks in memory.
+type dag struct {
+	blocks map[cid.Cid]blocks.Block
+}
+
+func newDAG() *dag {
+	return &dag{blocks: map[cid.Cid]blocks.Block{}}
+}
+
+func (d *dag) put(b blocks.Block) {
+	d.blocks[b.Cid()] = b
+}
+
+func (d *dag) Get(_ context.Context, c cid.Cid) (format.Node, error) {
+	b, ok := d.blocks[c]
+	if !ok {
+		return nil, format.ErrNotFound{Cid: c}
+	}
+	if c.Prefix().Codec == cid.Raw {
+		return merkledag.DecodeRawBlock(b)
+	}
+	return merkledag.DecodeProtobufBlock(b)
+}
+
+func (d *dag) GetMany(ctx context.Context, cids []cid.Cid) <-chan *format.NodeOption {
+	out := make(chan *format.NodeOption, len(cids))
+	for _, c := range cids {
+		nd, err := d.Get(ctx, c)
+		out <- &format.NodeOption{Node: nd, Err: err}
+	}
+	close(out)
+	return out
+}
+
+func (d *dag) Add(_ context.Context, nd format.Node) error {
+	d.put(nd)
+	return nil
+}
+
+func (d *dag) AddMany(ctx context.Context, nds []format.Node) error {
+	for _, nd := range nds {
+		d.put(nd)
+	}
+	return nil
+}
+
+func (d *dag) Remove(_ context.Context, c cid.Cid) error {
+	delete(d.blocks, c)
+	return nil
+}
+
+func (d *dag) RemoveMany(_ context.Context, cids []cid.Cid) error {
+	for _, c := range cids {
+		delete(d.blocks, c)
+	}
+	return nil
+}
+
+// writeCAR writes a CARv1 file with the DAGs of the roots, in depth-first
+// order.
+func (d *dag) writeCAR(w io.Writer, roots []cid.Cid) error {
+	car, err := storage.NewWritable(w, roots, carv2.WriteAsCarV1(true), carv2.UseWholeCIDs(true))
+	if err != nil {
+		return err
+	}
+
+	lsys := cidlink.DefaultLinkSystem()
+	lsys.StorageReadOpener = func(_ ipld.LinkContext, l ipld.Link) (io.Reader, error) {
+		b, ok := d.blocks[l.(cidlink.Link).Cid]
+		if !ok {
+			return nil, fmt.Errorf("missing block %s", l)
+		}
+		return bytes.NewReader(b.RawData()), nil
+	}
+
+	written := map[cid.Cid]bool{}
+	var walk func(c cid.Cid) error
+	walk = func(c cid.Cid) error {
+		if written[c] {
+			return nil
+		}
+		written[c] = true
+		b, ok := d.blocks[c]
+		if !ok {
+			// Links to CIDs that are not described, e.g. to
+			// make a DAG with missing blocks.
+			return nil
+		}
+		if err := car.Put(context.Background(), c.KeyString(), b.RawData()); err != nil {
+			return err
+		}
+
+		switch c.Prefix().Codec {
+		case cid.DagProtobuf, cid.DagCBOR, cid.DagJSON:
+		default:
+			// The other blocks, e.g. raw, have no links.
+			return nil
+		}
+		nd, err := lsys.Load(ipld.LinkContext{}, cidlink.Link{Cid: c}, basicnode.Prototype.Any)
+		if err != nil {
+			return err
+		}
+		for _, l := range links(nd) {
+			if err := walk(l); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if err := walk(root); err != nil {
+			return err
+		}
+	}
+	return car.Finalize()
+}
+
+// links returns the links of nd, in the order of a traversal.
+func links(nd datamodel.Node) []cid.Cid {
+	switch nd.Kind() {
+	case datamodel.Kind_Link:
+		l, _ := nd.AsLink()
+		return []cid.Cid{l.(cidlink.Link).Cid}
+	case datamodel.Kind_Map:
+		var found []cid.Cid
+		for it := nd.MapIterator(); !it.Done(); {
+			_, v, err := it.Next()
+			if err != nil {
+				break
+			}
+			found = append(found, links(v)...)
+		}
+		return found
+	case datamodel.Kind_List:
+		var found []cid.Cid
+		for it := nd.ListIterator(); !it.Done(); {
+			_, v, err := it.Next()
+			if err != nil {
+				break
+			}
+			found = append(found, links(v)...)
+		}
+		return found
+	}
+	return nil
+}
+
+// random generates the pseudo-random content of files, the same for a given
+// seed on every platform and version of Go.
+type random struct {
+	rng *rand.ChaCha8
+	buf [8]byte
+	n   int
+}
+
+func newRandom(seed uint64) *random {
+	var s [32]byte
+	binary.LittleEndian.PutUint64(s[:], seed)
+	return &random{rng: rand.NewChaCha8(s)}
+}
+
+func (r *random) Read(p []byte) (int, error) {
+	for i := range p {
+		if r.n == 0 {
+			binary.LittleEndian.PutUint64(r.buf[:], r.rng.Uint64())
+			r.n = len(r.buf)
+		}
+		p[i] = r.buf[len(r.buf)-r.n]
+		r.n--
+	}
+	return len(p), nil
+}
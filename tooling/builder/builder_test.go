@@ -0,0 +1,206 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/ipfs/boxo/ipld/merkledag"
+	ft "github.com/ipfs/boxo/ipld/unixfs"
+	pb "github.com/ipfs/boxo/ipld/unixfs/pb"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	carv2 "github.com/ipld/go-car/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func build(t *testing.T, description string) []byte {
+	t.Helper()
+	var d Description
+	require.NoError(t, yaml.Unmarshal([]byte(description), &d))
+	var buf bytes.Buffer
+	require.NoError(t, Build(&buf, &d))
+	return buf.Bytes()
+}
+
+// readCAR returns the roots and the blocks of a CAR file, in order.
+func readCAR(t *testing.T, data []byte) ([]cid.Cid, []blocks.Block) {
+	t.Helper()
+	r, err := carv2.NewBlockReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	var blks []blocks.Block
+	for {
+		b, err := r.Next()
+		if err == io.EOF {
+			return r.Roots, blks
+		}
+		require.NoError(t, err)
+		blks = append(blks, b)
+	}
+}
+
+func decode(t *testing.T, b blocks.Block) *merkledag.ProtoNode {
+	t.Helper()
+	nd, err := merkledag.DecodeProtobuf(b.RawData())
+	require.NoError(t, err)
+	return nd
+}
+
+func TestFixturesAreReproducible(t *testing.T) {
+	paths, err := List("../../fixtures")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			want, err := os.ReadFile(CARPath(path))
+			require.NoError(t, err)
+			got, err := BuildFile(path)
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(want, got), "%s differs from its description", CARPath(path))
+		})
+	}
+}
+
+func TestRandomFiles(t *testing.T) {
+	description := `
+cid-version: 1
+roots:
+  - file: {size: 3072, seed: 1}
+    chunk-size: 1024
+`
+	first := build(t, description)
+	assert.Equal(t, first, build(t, description))
+
+	_, blks := readCAR(t, first)
+	require.Len(t, blks, 4)
+	assert.Len(t, decode(t, blks[0]).Links(), 3)
+	for _, b := range blks[1:] {
+		assert.Len(t, b.RawData(), 1024)
+	}
+
+	other := build(t, `
+cid-version: 1
+roots:
+  - file: {size: 3072, seed: 2}
+    chunk-size: 1024
+`)
+	assert.NotEqual(t, first, other)
+}
+
+func TestOptions(t *testing.T) {
+	_, blks := readCAR(t, build(t, `
+roots:
+  - directory:
+      v0.txt: {file: "hello\n"}
+      v1:
+        cid-version: 1
+        directory:
+          hello.txt: {file: "hello\n"}
+          dag-pb.txt: {file: "hello\n", raw-leaves: false}
+`))
+	require.Len(t, blks, 5)
+	// The directories, then the files in the order of their names.
+	assert.Equal(t, uint64(0), blks[0].Cid().Version())
+	assert.Equal(t, "QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN", blks[1].Cid().String())
+	assert.Equal(t, uint64(1), blks[2].Cid().Version())
+	assert.Equal(t, "bafybeiffndsajwhk3lwjewwdxqntmjm4b5wxaaanokonsggenkbw6slwk4", blks[3].Cid().String())
+	assert.Equal(t, "bafkreicysg23kiwv34eg2d7qweipxwosdo2py4ldv42nbauguluen5v6am", blks[4].Cid().String())
+}
+
+func TestHAMTThreshold(t *testing.T) {
+	description := `
+cid-version: 1
+hamt-threshold: %d
+roots:
+  - directory:
+      a.txt: {file: "a"}
+      b.txt: {file: "b"}
+`
+	for threshold, want := range map[int]pb.Data_DataType{0: ft.THAMTShard, 1000: ft.TDirectory} {
+		_, blks := readCAR(t, build(t, fmt.Sprintf(description, threshold)))
+		nd := decode(t, blks[0])
+		fsn, err := ft.FSNodeFromBytes(nd.Data())
+		require.NoError(t, err)
+		assert.Equal(t, want, fsn.Type(), "threshold %d", threshold)
+	}
+}
+
+func TestDagCBORLinks(t *testing.T) {
+	data := build(t, `
+cid-version: 1
+roots:
+  - dag-cbor:
+      file: {"/": "@hello"}
+      inline: {"/": {dag-json: {bytes: {"/": {bytes: aGVsbG8}}}}}
+      cid: {"/": bafkreicysg23kiwv34eg2d7qweipxwosdo2py4ldv42nbauguluen5v6am}
+nodes:
+  hello:
+    file: "hello\n"
+`)
+	roots, blks := readCAR(t, data)
+	require.Len(t, roots, 1)
+	assert.Equal(t, uint64(cid.DagCBOR), roots[0].Prefix().Codec)
+
+	var codecs []uint64
+	for _, b := range blks {
+		codecs = append(codecs, b.Cid().Prefix().Codec)
+	}
+	// The linked CID is not part of the description, it is not written.
+	assert.Equal(t, []uint64{cid.DagCBOR, cid.Raw, cid.DagJSON}, codecs)
+}
+
+func TestDagPB(t *testing.T) {
+	description := `
+cid-version: 1
+roots:
+  - dag-pb:
+      data: {type: file, blocksizes: [4, 1024, 5]}
+      links:
+        - node: {file: {text: ab, repeat: 2}}
+        - {cid: bafkreicysg23kiwv34eg2d7qweipxwosdo2py4ldv42nbauguluen5v6am, size: %s}
+        - node: {file: {base64: aGVsbG8=}}
+`
+	roots, blks := readCAR(t, build(t, fmt.Sprintf(description, "none")))
+	require.Len(t, roots, 1)
+	// The linked CID is not part of the description, it is not written.
+	require.Len(t, blks, 3)
+	assert.Equal(t, roots[0], blks[0].Cid())
+	assert.Equal(t, "abab", string(blks[1].RawData()))
+	assert.Equal(t, "hello", string(blks[2].RawData()))
+
+	nd := decode(t, blks[0])
+	require.Len(t, nd.Links(), 3)
+	assert.Equal(t, uint64(4), nd.Links()[0].Size)
+	assert.Equal(t, "bafkreicysg23kiwv34eg2d7qweipxwosdo2py4ldv42nbauguluen5v6am", nd.Links()[1].Cid.String())
+	fsn, err := ft.FSNodeFromBytes(nd.Data())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1033), fsn.FileSize())
+
+	// A size of 0 is recorded in the link, "none" leaves it out.
+	_, sized := readCAR(t, build(t, fmt.Sprintf(description, "0")))
+	assert.Len(t, sized[0].RawData(), len(blks[0].RawData())+2)
+}
+
+func TestInvalidDescriptions(t *testing.T) {
+	for description, message := range map[string]string{
+		"roots: [{file: a, raw: b}]":                             "exactly one of",
+		"roots: [{raw: a}]":                                      "CIDv0 only supports dag-pb",
+		"roots: ['@a']\nnodes: {a: {dag-cbor: {'/': '@a'}}}":     "@a refers to itself",
+		"roots: ['@missing']":                                    "unknown node @missing",
+		"roots: [{file: a, codec: json}]":                        "codec is only supported",
+		"roots: [{dag-pb: {links: [{cid: a, node: {raw: a}}]}}]": "exactly one of node or cid",
+		"roots: [{dag-pb: {data: {type: symlink}}}]":             "unknown UnixFS type",
+	} {
+		var d Description
+		err := yaml.Unmarshal([]byte(description), &d)
+		if err == nil {
+			err = Build(&bytes.Buffer{}, &d)
+		}
+		assert.ErrorContains(t, err, message, description)
+	}
+}
@@ -0,0 +1,287 @@
+// Package builder generates fixture CAR files from declarative descriptions
+// of their content: UnixFS files, directories, HAMT sharded directories and
+// symlinks, and DAG-CBOR, DAG-JSON and raw blocks. The same description always
+// produces the same CAR file, byte for byte.
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Extension is the extension of the description files, the CAR file of
+// foo.car.yaml is foo.car.
+const Extension = ".car.yaml"
+
+// Description describes the content of a CAR file.
+//
+//	cid-version: 1
+//	roots:
+//	  - directory:
+//	      hello.txt: {file: "hello world\n"}
+//	      ascii.txt: "@ascii"
+//	      big.bin: {file: {size: 3072, seed: 1}, chunk-size: 1024}
+//	nodes:
+//	  ascii: {file: "hello application/vnd.ipld.car\n"}
+//
+// The options of the description, such as cid-version, apply to every node,
+// and the options of a node apply to its children.
+type Description struct {
+	Options `yaml:",inline"`
+	// Roots are the roots of the CAR file, its blocks are the blocks of the
+	// DAGs of the roots, in depth-first order, like `ipfs dag export`.
+	Roots []*Node `yaml:"roots"`
+	// Nodes are named nodes, referenced as "@name" where a node is
+	// expected, or as {"/": "@name"} links in DAG-CBOR and DAG-JSON
+	// nodes. They are built with the options of the description.
+	Nodes map[string]*Node `yaml:"nodes"`
+}
+
+// Options control how the nodes are encoded. Unset options are inherited
+// from the parent node, and default to the behaviour of `ipfs add`.
+type Options struct {
+	// CIDVersion is the version of the CIDs, 0 by default.
+	CIDVersion *int `yaml:"cid-version"`
+	// Hash is the multihash function, "sha2-256" by default. Other
+	// functions imply CIDv1.
+	Hash *string `yaml:"hash"`
+	// RawLeaves stores the data of files in raw blocks, by default with
+	// CIDv1.
+	RawLeaves *bool `yaml:"raw-leaves"`
+	// ChunkSize is the size of the chunks of files, 262144 by default.
+	ChunkSize *int `yaml:"chunk-size"`
+	// MaxLinks is the maximum number of links of a file node, 174 by
+	// default.
+	MaxLinks *int `yaml:"max-links"`
+	// Layout is the layout of the DAG of files, "balanced" by default, or
+	// "trickle".
+	Layout *string `yaml:"layout"`
+	// HAMTThreshold is the estimated size of a directory, the sum of the
+	// lengths of the names and CIDs of its entries, from which it is
+	// sharded, 262144 by default. 0 always shards.
+	HAMTThreshold *int `yaml:"hamt-threshold"`
+	// HAMTFanout is the width of the HAMT shards, 256 by default.
+	HAMTFanout *int `yaml:"hamt-fanout"`
+}
+
+// Node describes a single node, and its children. Exactly one of its kinds
+// is set, or Ref.
+type Node struct {
+	Options `yaml:",inline"`
+	// Ref is the name of a node of Description.Nodes.
+	Ref string `yaml:"-"`
+
+	File *File `yaml:"file"`
+	// Directory maps the names of the entries to their nodes.
+	Directory map[string]*Node `yaml:"directory"`
+	// Symlink is the target of a UnixFS symlink.
+	Symlink *string `yaml:"symlink"`
+	// DagCBOR and DagJSON are the values of a DAG-CBOR or DAG-JSON node.
+	// Maps with a single "/" key are links: {"/": "bafy..."}, {"/": "@name"}
+	// or {"/": <node>}, or bytes: {"/": {"bytes": "<base64>"}}.
+	DagCBOR yaml.Node `yaml:"dag-cbor"`
+	DagJSON yaml.Node `yaml:"dag-json"`
+	// Raw is the content of a raw block.
+	Raw *string `yaml:"raw"`
+	// DagPB is a dag-pb node assembled by hand, for DAGs the other kinds
+	// do not produce.
+	DagPB *DagPB `yaml:"dag-pb"`
+	// Codec is the multicodec of the CID of a DAG-CBOR, DAG-JSON or raw
+	// node, when it is not the codec of its kind, e.g. "cbor" for a CBOR
+	// block that is also valid DAG-CBOR, or "json" for a raw JSON text.
+	Codec string `yaml:"codec"`
+}
+
+// File is the content of a UnixFS file: a text, repeated Repeat times when
+// set, binary content encoded in Base64, or Size pseudo-random bytes
+// generated from Seed.
+type File struct {
+	Text   string `yaml:"text"`
+	Repeat int    `yaml:"repeat"`
+	Base64 string `yaml:"base64"`
+	Size   int    `yaml:"size"`
+	Seed   uint64 `yaml:"seed"`
+}
+
+// DagPB is a dag-pb node with the given UnixFS data and links, in this
+// order. Links may point to CIDs that are not described, so that their
+// blocks are missing from the CAR file.
+//
+//	dag-pb:
+//	  data: {type: file, blocksizes: [1024, 1024]}
+//	  links:
+//	    - node: "@first"
+//	    - {cid: QmSNLTo6Wv9dfroVaw7MFYjLqf9ho7PKrgsjdzYDtv8h1W, size: 1035}
+type DagPB struct {
+	Data  UnixFSData   `yaml:"data"`
+	Links []*DagPBLink `yaml:"links"`
+}
+
+// UnixFSData is the UnixFS data of a DagPB node.
+type UnixFSData struct {
+	// Type is "file" or "directory".
+	Type string `yaml:"type"`
+	// BlockSizes are the sizes of the data of the children of a file.
+	BlockSizes []uint64 `yaml:"blocksizes"`
+}
+
+// DagPBLink is a link of a DagPB node, to a node or to a CID.
+type DagPBLink struct {
+	Name string
+	Node *Node
+	CID  string
+	// Size is the size recorded in the link, the size of the DAG of Node
+	// by default, 0 for a CID. "size: none" leaves the size out of the
+	// link, NoSize is then set.
+	Size   *uint64
+	NoSize bool
+}
+
+// Read parses the description at path.
+func Read(path string) (*Description, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var d Description
+	if err := yaml.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("invalid description %s: %w", path, err)
+	}
+	if len(d.Roots) == 0 {
+		return nil, fmt.Errorf("invalid description %s: no roots", path)
+	}
+	return &d, nil
+}
+
+// List returns the paths of the descriptions found in dir and its
+// subdirectories.
+func List(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(path, Extension) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// CARPath returns the path of the CAR file of the description at path.
+func CARPath(path string) string {
+	return strings.TrimSuffix(path, Extension) + ".car"
+}
+
+// UnmarshalYAML reads a node, or a "@name" reference.
+func (n *Node) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if !strings.HasPrefix(value.Value, "@") {
+			return fmt.Errorf("line %d: expected a node or a @name reference, got %q", value.Line, value.Value)
+		}
+		n.Ref = strings.TrimPrefix(value.Value, "@")
+		return nil
+	}
+
+	type node Node
+	if err := value.Decode((*node)(n)); err != nil {
+		return err
+	}
+
+	kinds := 0
+	for _, set := range []bool{n.File != nil, n.Directory != nil, n.Symlink != nil, n.DagCBOR.Kind != 0, n.DagJSON.Kind != 0, n.Raw != nil, n.DagPB != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("line %d: a node must be exactly one of file, directory, symlink, dag-cbor, dag-json, raw or dag-pb", value.Line)
+	}
+	if n.Codec != "" && n.DagCBOR.Kind == 0 && n.DagJSON.Kind == 0 && n.Raw == nil {
+		return fmt.Errorf("line %d: codec is only supported by dag-cbor, dag-json and raw nodes", value.Line)
+	}
+	return nil
+}
+
+// UnmarshalYAML reads a link, to exactly one of a node or a CID.
+func (l *DagPBLink) UnmarshalYAML(value *yaml.Node) error {
+	var link struct {
+		Name string    `yaml:"name"`
+		Node *Node     `yaml:"node"`
+		CID  string    `yaml:"cid"`
+		Size yaml.Node `yaml:"size"`
+	}
+	if err := value.Decode(&link); err != nil {
+		return err
+	}
+	if (link.Node == nil) == (link.CID == "") {
+		return fmt.Errorf("line %d: a link must have exactly one of node or cid", value.Line)
+	}
+
+	*l = DagPBLink{Name: link.Name, Node: link.Node, CID: link.CID}
+	switch {
+	case link.Size.Kind == 0:
+	case link.Size.Value == "none":
+		l.NoSize = true
+	default:
+		var size uint64
+		if err := link.Size.Decode(&size); err != nil {
+			return err
+		}
+		l.Size = &size
+	}
+	return nil
+}
+
+// UnmarshalYAML reads a file from a text, or from a map with its text,
+// base64 content, or size and seed.
+func (f *File) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		f.Text = value.Value
+		return nil
+	}
+	type file File
+	return value.Decode((*file)(f))
+}
+
+// inherit returns the options of a child node, o overridden by the options
+// set on the child.
+func (o Options) inherit(child Options) Options {
+	if child.CIDVersion != nil {
+		o.CIDVersion = child.CIDVersion
+	}
+	if child.Hash != nil {
+		o.Hash = child.Hash
+	}
+	if child.RawLeaves != nil {
+		o.RawLeaves = child.RawLeaves
+	}
+	if child.ChunkSize != nil {
+		o.ChunkSize = child.ChunkSize
+	}
+	if child.MaxLinks != nil {
+		o.MaxLinks = child.MaxLinks
+	}
+	if child.Layout != nil {
+		o.Layout = child.Layout
+	}
+	if child.HAMTThreshold != nil {
+		o.HAMTThreshold = child.HAMTThreshold
+	}
+	if child.HAMTFanout != nil {
+		o.HAMTFanout = child.HAMTFanout
+	}
+	return o
+}
+
+func or[T any](v *T, def T) T {
+	if v == nil {
+		return def
+	}
+	return *v
+}
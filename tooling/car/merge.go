@@ -3,16 +3,22 @@ package car
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
 	carv2 "github.com/ipld/go-car/v2"
 	"github.com/ipld/go-car/v2/blockstore"
+	_ "github.com/ipld/go-ipld-prime/codec/dagcbor"
+	_ "github.com/ipld/go-ipld-prime/codec/raw"
+	"github.com/ipld/go-ipld-prime/datamodel"
 	"github.com/ipld/go-ipld-prime/fluent"
 	"github.com/ipld/go-ipld-prime/linking"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
 	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/storage/bsadapter"
 	"github.com/ipld/go-ipld-prime/storage/memstore"
+	"github.com/multiformats/go-multihash"
 )
 
 // https://github.com/ipld/go-ipld-prime/blob/65bfa53512f2328d19273e471ce4fd6d964055a2/storage/bsadapter/bsadapter.go#L111C1-L120C2
@@ -27,7 +33,39 @@ func cidFromBinString(key string) (cid.Cid, error) {
 	return k, nil
 }
 
+// Metadata holds the fixtures that are not blocks, they are stored in a merged
+// CAR file alongside the blocks, so that a gateway can be provisioned from a
+// single file.
+type Metadata struct {
+	// IPNSRecords maps IPNS names to their signed records.
+	IPNSRecords map[string][]byte
+	// DNSLinks maps domains to their DNSLink paths.
+	DNSLinks map[string]string
+}
+
+// Merge writes the blocks of the CAR files at inputPaths to a single CAR file
+// at outputPath, under a root linking to the roots of every input.
 func Merge(inputPaths []string, outputPath string) error {
+	return merge(inputPaths, nil, outputPath)
+}
+
+// MergeWithMetadata merges the CAR files like Merge, and stores meta in the
+// output. The CAR file gets a second root, a dag-cbor node with the IPNS
+// records, as links to raw blocks, and the DNSLinks:
+//
+//	{
+//	  "ipns": {"k51...": <raw block of the record>},
+//	  "dnslink": {"example.org": "/ipfs/bafy..."}
+//	}
+//
+// Until IPNS records in CAR files are specified, see
+// https://github.com/ipfs/specs/issues/369, the file can only be read back
+// with ReadMetadata.
+func MergeWithMetadata(inputPaths []string, meta Metadata, outputPath string) error {
+	return merge(inputPaths, &meta, outputPath)
+}
+
+func merge(inputPaths []string, meta *Metadata, outputPath string) error {
 	// First list all the unique roots in our fixtures
 	uniqRoots := make(map[string]cid.Cid)
 	for _, path := range inputPaths {
@@ -87,11 +125,20 @@ func Merge(inputPaths []string, outputPath string) error {
 	}
 
 	rootCid := lnk.(cidlink.Link).Cid
+	outRoots := []cid.Cid{rootCid}
+
+	if meta != nil {
+		metaCid, err := storeMetadata(lsys, *meta)
+		if err != nil {
+			return err
+		}
+		outRoots = append(outRoots, metaCid)
+	}
 
 	// Now prepare our new CAR file
-	fmt.Printf("Opening the %s file, with root: %v\n", outputPath, rootCid)
+	fmt.Printf("Opening the %s file, with roots: %v\n", outputPath, outRoots)
 	options := []carv2.Option{blockstore.WriteAsCarV1(true)}
-	rout, err := blockstore.OpenReadWrite(outputPath, []cid.Cid{rootCid}, options...)
+	rout, err := blockstore.OpenReadWrite(outputPath, outRoots, options...)
 	if err != nil {
 		return err
 	}
@@ -147,3 +194,143 @@ func Merge(inputPaths []string, outputPath string) error {
 
 	return err
 }
+
+const (
+	metadataIPNS    = "ipns"
+	metadataDNSLink = "dnslink"
+)
+
+// storeMetadata stores the records as raw blocks, and the metadata node
+// linking to them, with lsys.
+func storeMetadata(lsys linking.LinkSystem, meta Metadata) (cid.Cid, error) {
+	rawPrototype := cidlink.LinkPrototype{Prefix: cid.Prefix{
+		Version:  1,
+		Codec:    cid.Raw,
+		MhType:   multihash.SHA2_256,
+		MhLength: -1,
+	}}
+
+	names := sortedKeys(meta.IPNSRecords)
+	records := make(map[string]cid.Cid, len(names))
+	for _, name := range names {
+		lnk, err := lsys.Store(linking.LinkContext{}, rawPrototype, basicnode.NewBytes(meta.IPNSRecords[name]))
+		if err != nil {
+			return cid.Undef, err
+		}
+		records[name] = lnk.(cidlink.Link).Cid
+	}
+
+	domains := sortedKeys(meta.DNSLinks)
+	node := fluent.MustBuildMap(basicnode.Prototype.Map, 2, func(ma fluent.MapAssembler) {
+		ma.AssembleEntry(metadataIPNS).CreateMap(int64(len(names)), func(ma fluent.MapAssembler) {
+			for _, name := range names {
+				ma.AssembleEntry(name).AssignLink(cidlink.Link{Cid: records[name]})
+			}
+		})
+		ma.AssembleEntry(metadataDNSLink).CreateMap(int64(len(domains)), func(ma fluent.MapAssembler) {
+			for _, domain := range domains {
+				ma.AssembleEntry(domain).AssignString(meta.DNSLinks[domain])
+			}
+		})
+	})
+
+	lnk, err := lsys.Store(linking.LinkContext{}, cidlink.LinkPrototype{Prefix: cid.Prefix{
+		Version:  1,
+		Codec:    cid.DagCBOR,
+		MhType:   multihash.SHA2_256,
+		MhLength: -1,
+	}}, node)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return lnk.(cidlink.Link).Cid, nil
+}
+
+// ReadMetadata returns the metadata stored in the merged CAR file at path by
+// MergeWithMetadata.
+func ReadMetadata(path string) (*Metadata, error) {
+	bs, err := blockstore.OpenReadOnly(path, blockstore.UseWholeCIDs(true))
+	if err != nil {
+		return nil, err
+	}
+	defer bs.Close()
+
+	roots, err := bs.Roots()
+	if err != nil {
+		return nil, err
+	}
+	if len(roots) < 2 || roots[1].Prefix().Codec != cid.DagCBOR {
+		return nil, fmt.Errorf("%s has no metadata, merge it with MergeWithMetadata", path)
+	}
+
+	lsys := cidlink.DefaultLinkSystem()
+	lsys.SetReadStorage(&bsadapter.Adapter{Wrapped: bs})
+	lsys.TrustedStorage = false
+
+	node, err := lsys.Load(linking.LinkContext{}, cidlink.Link{Cid: roots[1]}, basicnode.Prototype.Map)
+	if err != nil {
+		return nil, fmt.Errorf("reading the metadata of %s: %w", path, err)
+	}
+
+	meta := &Metadata{IPNSRecords: map[string][]byte{}, DNSLinks: map[string]string{}}
+	err = eachEntry(node, metadataIPNS, func(name string, value datamodel.Node) error {
+		link, err := value.AsLink()
+		if err != nil {
+			return err
+		}
+		record, err := lsys.Load(linking.LinkContext{}, link, basicnode.Prototype.Bytes)
+		if err != nil {
+			return err
+		}
+		meta.IPNSRecords[name], err = record.AsBytes()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading the IPNS records of %s: %w", path, err)
+	}
+
+	err = eachEntry(node, metadataDNSLink, func(domain string, value datamodel.Node) error {
+		var err error
+		meta.DNSLinks[domain], err = value.AsString()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading the DNSLinks of %s: %w", path, err)
+	}
+	return meta, nil
+}
+
+// eachEntry calls f with every entry of the map at key in node.
+func eachEntry(node datamodel.Node, key string, f func(key string, value datamodel.Node) error) error {
+	m, err := node.LookupByString(key)
+	if err != nil {
+		return err
+	}
+	it := m.MapIterator()
+	if it == nil {
+		return fmt.Errorf("%s is not a map", key)
+	}
+	for !it.Done() {
+		k, v, err := it.Next()
+		if err != nil {
+			return err
+		}
+		ks, err := k.AsString()
+		if err != nil {
+			return err
+		}
+		if err := f(ks, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,51 @@
+package car
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipld/go-car/v2/blockstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeWithMetadata(t *testing.T) {
+	inputs := []string{"./_fixtures/dag.car", "./_fixtures/hamt.car"}
+	meta := Metadata{
+		IPNSRecords: map[string][]byte{
+			"k51qzi5uqu5dit2ku9mutlfgwyz8u730on38kd10m97m36bjt66my99hb6103f": []byte("record"),
+			"k51qzi5uqu5dlxdsdu5fpuu7h69wu4ohp32iwm9pdt9nq3y5rpn3ln9j12zfhe": []byte("another record"),
+		},
+		DNSLinks: map[string]string{
+			"example.org": "/ipfs/bafybeidlbwbu73tbjr3atntjz4lq5ego5w2uyof35vvwcnheaftzi3rndu",
+		},
+	}
+
+	output := filepath.Join(t.TempDir(), "fixtures.car")
+	require.NoError(t, MergeWithMetadata(inputs, meta, output))
+
+	got, err := ReadMetadata(output)
+	require.NoError(t, err)
+	assert.Equal(t, meta, *got)
+
+	bs, err := blockstore.OpenReadOnly(output, blockstore.UseWholeCIDs(true))
+	require.NoError(t, err)
+	defer bs.Close()
+	roots, err := bs.Roots()
+	require.NoError(t, err)
+	assert.Len(t, roots, 2)
+
+	root := MustOpenUnixfsCar("./_fixtures/dag.car").MustGetRoot().Cid()
+	has, err := bs.Has(context.Background(), root)
+	require.NoError(t, err)
+	assert.True(t, has)
+}
+
+func TestReadMetadataWithoutMetadata(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "fixtures.car")
+	require.NoError(t, Merge([]string{"./_fixtures/dag.car"}, output))
+
+	_, err := ReadMetadata(output)
+	assert.ErrorContains(t, err, "has no metadata")
+}
@@ -0,0 +1,109 @@
+// Package catalog describes the SugarTests of the suite without running them,
+// to review what the suite covers and how the coverage changes between
+// releases.
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+)
+
+// Catalog lists every SugarTest of the suite, in the order they would run.
+type Catalog struct {
+	Version string  `json:"version"`
+	Tests   []Entry `json:"tests"`
+}
+
+// Entry describes a single SugarTest.
+type Entry struct {
+	// Name is the SugarTest name.
+	Name string `json:"name"`
+	// Path is the full name the test runs as, see results.Test.Path.
+	Path  string `json:"path"`
+	Group string `json:"group,omitempty"`
+	// Specs lists the spec URLs of the test, its top-level test, its
+	// expected response and its header checks.
+	Specs []string `json:"specs,omitempty"`
+	// Gates lists the names of the specs.Leaf the test requires, the test
+	// is skipped unless they are all enabled.
+	Gates    []string  `json:"gates,omitempty"`
+	Requests []Request `json:"requests"`
+	// Status is the expected status, e.g. "200", "400-499" or "200|404"
+	// when any of the responses is valid.
+	Status string `json:"status,omitempty"`
+}
+
+// Request is the template of a request sent by a test.
+type Request struct {
+	Method          string            `json:"method"`
+	Path            string            `json:"path"`
+	Query           url.Values        `json:"query,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Proxy           string            `json:"proxy,omitempty"`
+	ProxyTunnel     bool              `json:"proxyTunnel,omitempty"`
+	FollowRedirects bool              `json:"followRedirects,omitempty"`
+}
+
+// String returns the method and URL of the request, e.g.
+// "GET /ipfs/bafy...?format=car".
+func (r Request) String() string {
+	s := r.Method + " " + r.Path
+	if query := r.Query.Encode(); query != "" {
+		s += "?" + query
+	}
+	if host, ok := r.Headers["Host"]; ok {
+		s += " (Host: " + host + ")"
+	}
+	return s
+}
+
+// Write writes the catalog as indented JSON.
+func Write(w io.Writer, c Catalog) error {
+	if c.Tests == nil {
+		c.Tests = []Entry{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// Read parses a catalog, as written by Write.
+func Read(r io.Reader) (*Catalog, error) {
+	var c Catalog
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// WriteTable writes the catalog as a table with one test per line.
+func WriteTable(w io.Writer, c Catalog) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tGROUP\tGATES\tSTATUS\tREQUEST\tSPECS")
+	for _, e := range c.Tests {
+		var requests []string
+		for _, r := range e.Requests {
+			requests = append(requests, r.String())
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Path,
+			dash(e.Group),
+			dash(strings.Join(e.Gates, ",")),
+			dash(e.Status),
+			dash(strings.Join(requests, "; ")),
+			dash(strings.Join(e.Specs, " ")),
+		)
+	}
+	return tw.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
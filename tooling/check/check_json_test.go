@@ -0,0 +1,19 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckIsJSONEqual(t *testing.T) {
+	check := IsJSONEqual([]byte(`{"hello": "world"}`))
+
+	assert.True(t, check.Check([]byte(`{"hello":"world"}`)).Success)
+	assert.False(t, check.Check([]byte(`{"hello":"there"}`)).Success)
+
+	// A truncated body fails the check rather than the whole run.
+	output := check.Check([]byte(`{"hello":`))
+	assert.False(t, output.Success)
+	assert.Contains(t, output.Reason, "expected valid JSON")
+}
@@ -0,0 +1,235 @@
+// Package config reads the configuration file of the test command, which
+// holds named profiles of settings, e.g. one per deployment of a gateway, so
+// that they do not have to be repeated as flags and environment variables.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the path of the configuration file read when none is given.
+const DefaultPath = "gateway-conformance.yaml"
+
+// DefaultTOMLPath is the path of the configuration file read when none is
+// given and DefaultPath does not exist.
+const DefaultTOMLPath = "gateway-conformance.toml"
+
+// Config lists the profiles of the configuration file.
+//
+//	default: staging
+//	profiles:
+//	  staging:
+//	    gateway-url: https://staging.example.com
+//	    specs: -subdomain-gateway
+//	    header:
+//	      X-Deployment: staging
+//	    auth-token: ${STAGING_TOKEN}
+//	    request-timeout: 30s
+//	    baseline: baselines/staging.yaml
+//	    args: [-timeout, 30m]
+//
+// Files with a .toml extension are read as TOML, with the same structure.
+type Config struct {
+	// Default is the name of the profile used when none is selected.
+	Default  string             `yaml:"default"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Profile holds the settings of a target. Settings are named after the
+// flags of the test command, without their leading dashes.
+type Profile struct {
+	// Settings maps the name of a flag to its values. A list sets a flag
+	// that can be repeated, a map sets a flag with "Name: value" values,
+	// such as --header.
+	Settings map[string][]string
+	// Args are passed to the test runner before the arguments of the
+	// command line.
+	Args []string
+}
+
+// Read parses the configuration file at path, as TOML when its extension is
+// .toml and as YAML otherwise. Environment variables in the values written
+// as ${TOKEN} are expanded, so that secrets can be kept out of the file. Any
+// other $ is kept as is, $$ stands for a literal $.
+func Read(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if filepath.Ext(path) == ".toml" {
+		err = readTOML(data, &c)
+	} else {
+		err = yaml.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration %s: %w", path, err)
+	}
+	if c.Default != "" {
+		if _, ok := c.Profiles[c.Default]; !ok {
+			return nil, fmt.Errorf("invalid configuration %s: the default profile %q does not exist", path, c.Default)
+		}
+	}
+	return &c, nil
+}
+
+// Profile returns the profile with the given name, or the default profile
+// when name is empty. ok is false when name is empty and there is no default
+// profile.
+func (c *Config) Profile(name string) (p Profile, ok bool, err error) {
+	if name == "" {
+		name = c.Default
+		if name == "" {
+			return Profile{}, false, nil
+		}
+	}
+	p, ok = c.Profiles[name]
+	if !ok {
+		return Profile{}, false, fmt.Errorf("unknown profile %q, expected one of %s", name, strings.Join(c.Names(), ", "))
+	}
+	return p, true, nil
+}
+
+// Names returns the names of the profiles, sorted.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnmarshalYAML reads a profile from a mapping of flag names to scalars,
+// lists or maps.
+func (p *Profile) UnmarshalYAML(node *yaml.Node) error {
+	var settings map[string]yaml.Node
+	if err := node.Decode(&settings); err != nil {
+		return err
+	}
+
+	p.Settings = map[string][]string{}
+	for name, value := range settings {
+		if value.Kind == yaml.AliasNode {
+			value = *value.Alias
+		}
+		var values []string
+		switch value.Kind {
+		case yaml.ScalarNode:
+			values = []string{value.Value}
+		case yaml.SequenceNode:
+			if err := value.Decode(&values); err != nil {
+				return err
+			}
+		case yaml.MappingNode:
+			var m map[string]string
+			if err := value.Decode(&m); err != nil {
+				return err
+			}
+			for k, v := range m {
+				values = append(values, k+": "+v)
+			}
+			sort.Strings(values)
+		default:
+			return fmt.Errorf("line %d: unsupported value of %s", value.Line, name)
+		}
+		p.set(name, values)
+	}
+	return nil
+}
+
+// readTOML reads a configuration from TOML, where settings are strings,
+// numbers or booleans, arrays of them, or tables of strings.
+func readTOML(data []byte, c *Config) error {
+	var raw struct {
+		Default  string                    `toml:"default"`
+		Profiles map[string]map[string]any `toml:"profiles"`
+	}
+	if err := toml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	c.Default = raw.Default
+	c.Profiles = map[string]Profile{}
+	for name, settings := range raw.Profiles {
+		p := Profile{Settings: map[string][]string{}}
+		for setting, value := range settings {
+			var values []string
+			switch value := value.(type) {
+			case []any:
+				for _, v := range value {
+					s, ok := tomlScalar(v)
+					if !ok {
+						return fmt.Errorf("profile %s: unsupported value of %s", name, setting)
+					}
+					values = append(values, s)
+				}
+			case map[string]any:
+				for k, v := range value {
+					s, ok := v.(string)
+					if !ok {
+						return fmt.Errorf("profile %s: unsupported value of %s.%s", name, setting, k)
+					}
+					values = append(values, k+": "+s)
+				}
+				sort.Strings(values)
+			default:
+				s, ok := tomlScalar(value)
+				if !ok {
+					return fmt.Errorf("profile %s: unsupported value of %s", name, setting)
+				}
+				values = []string{s}
+			}
+			p.set(setting, values)
+		}
+		c.Profiles[name] = p
+	}
+	return nil
+}
+
+func tomlScalar(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case int64, float64, bool:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
+
+// set sets the values of a setting, once their environment variables are
+// expanded.
+func (p *Profile) set(name string, values []string) {
+	for i := range values {
+		values[i] = expand(values[i])
+	}
+	if name == "args" {
+		p.Args = values
+	} else {
+		p.Settings[name] = values
+	}
+}
+
+var variable = regexp.MustCompile(`\$\$|\$\{[A-Za-z_][A-Za-z0-9_]*\}`)
+
+// expand replaces ${NAME} with the value of the environment variable NAME,
+// and $$ with $. Any other $, such as the end of line anchor of a regular
+// expression, is kept as is.
+func expand(s string) string {
+	return variable.ReplaceAllStringFunc(s, func(m string) string {
+		if m == "$$" {
+			return "$"
+		}
+		return os.Getenv(m[2 : len(m)-1])
+	})
+}
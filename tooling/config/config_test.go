@@ -0,0 +1,147 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func write(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), DefaultPath)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestRead(t *testing.T) {
+	t.Setenv("STAGING_TOKEN", "s3cr3t")
+	c, err := Read(write(t, `
+default: local
+shared: &shared
+  specs: -subdomain-gateway
+  request-timeout: 30s
+profiles:
+  local:
+    gateway-url: http://127.0.0.1:8080
+  staging:
+    <<: *shared
+    gateway-url: https://staging.example.com
+    group: [UnixFS, Tar]
+    header:
+      X-Deployment: staging
+      Accept-Language: en
+    auth-token: ${STAGING_TOKEN}
+    args: [-timeout, 30m]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"local", "staging"}, c.Names())
+
+	p, ok, err := c.Profile("staging")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string][]string{
+		"specs":           {"-subdomain-gateway"},
+		"request-timeout": {"30s"},
+		"gateway-url":     {"https://staging.example.com"},
+		"group":           {"UnixFS", "Tar"},
+		"header":          {"Accept-Language: en", "X-Deployment: staging"},
+		"auth-token":      {"s3cr3t"},
+	}, p.Settings)
+	assert.Equal(t, []string{"-timeout", "30m"}, p.Args)
+
+	p, ok, err = c.Profile("")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"http://127.0.0.1:8080"}, p.Settings["gateway-url"])
+
+	_, _, err = c.Profile("production")
+	assert.ErrorContains(t, err, `unknown profile "production", expected one of local, staging`)
+}
+
+func TestReadWithoutDefault(t *testing.T) {
+	c, err := Read(write(t, "profiles:\n  local:\n    gateway-url: http://127.0.0.1:8080\n"))
+	require.NoError(t, err)
+	_, ok, err := c.Profile("")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestReadInvalid(t *testing.T) {
+	_, err := Read(write(t, "default: missing\nprofiles: {}\n"))
+	assert.ErrorContains(t, err, `the default profile "missing" does not exist`)
+
+	_, err = Read(write(t, "profiles:\n  local:\n    header: [[nested]]\n"))
+	assert.Error(t, err)
+}
+
+func TestReadTOML(t *testing.T) {
+	t.Setenv("STAGING_TOKEN", "s3cr3t")
+	path := filepath.Join(t.TempDir(), DefaultTOMLPath)
+	require.NoError(t, os.WriteFile(path, []byte(`
+default = "local"
+
+[profiles.local]
+gateway-url = "http://127.0.0.1:8080"
+
+[profiles.staging]
+gateway-url = "https://staging.example.com"
+group = ["UnixFS", "Tar"]
+retries = 2
+insecure = true
+auth-token = "${STAGING_TOKEN}"
+args = ["-timeout", "30m"]
+
+[profiles.staging.header]
+X-Deployment = "staging"
+Accept-Language = "en"
+`), 0644))
+
+	c, err := Read(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"local", "staging"}, c.Names())
+
+	p, ok, err := c.Profile("")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"http://127.0.0.1:8080"}, p.Settings["gateway-url"])
+
+	p, _, err = c.Profile("staging")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"gateway-url": {"https://staging.example.com"},
+		"group":       {"UnixFS", "Tar"},
+		"retries":     {"2"},
+		"insecure":    {"true"},
+		"header":      {"Accept-Language: en", "X-Deployment: staging"},
+		"auth-token":  {"s3cr3t"},
+	}, p.Settings)
+	assert.Equal(t, []string{"-timeout", "30m"}, p.Args)
+
+	require.NoError(t, os.WriteFile(path, []byte("[profiles.local]\nheader = { X-Retries = 2 }\n"), 0644))
+	_, err = Read(path)
+	assert.ErrorContains(t, err, "unsupported value of header.X-Retries")
+}
+
+func TestReadExpandsOnlyBracedVariables(t *testing.T) {
+	t.Setenv("TOKEN", "s3cr3t")
+	t.Setenv("ROOTS", "expanded")
+	c, err := Read(write(t, `
+profiles:
+  local:
+    auth-token: ${TOKEN}
+    run: ^TestTar/.*$
+    skip: $ROOTS|$$ROOTS
+    header:
+      X-Literal: $${TOKEN}
+`))
+	require.NoError(t, err)
+
+	p, _, err := c.Profile("local")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s3cr3t"}, p.Settings["auth-token"])
+	assert.Equal(t, []string{"^TestTar/.*$"}, p.Settings["run"])
+	assert.Equal(t, []string{"$ROOTS|$ROOTS"}, p.Settings["skip"])
+	assert.Equal(t, []string{"X-Literal: ${TOKEN}"}, p.Settings["header"])
+}
@@ -0,0 +1,233 @@
+// Package dashboard aggregates the reports of many conformance runs, usually
+// one per implementation, into comparison tables and into the content of the
+// Hugo website in www/.
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ipfs/gateway-conformance/tooling/report"
+	"github.com/ipfs/gateway-conformance/tooling/test2json"
+)
+
+// UnknownVersion is the version of the runs whose report has no version.
+const UnknownVersion = "unknown"
+
+// Run is the report of an implementation tested with a given version of the
+// suite.
+type Run struct {
+	// Implementation is the name of the report file without its extension,
+	// e.g. "kubo" for kubo.json.
+	Implementation string
+	Version        string
+	JobURL         string
+	Time           time.Time
+	Report         *report.Report
+
+	parents map[string]bool
+}
+
+// Leaf reports whether the test has no subtest in the run.
+func (r *Run) Leaf(name string) bool {
+	if r.parents == nil {
+		r.parents = map[string]bool{}
+		for _, t := range r.Report.Tests {
+			r.parents[t.Parent()] = true
+		}
+	}
+	return !r.parents[name]
+}
+
+// Load reads the JSON reports at paths. A directory is searched recursively
+// for .json files. Reports are either the test2json output of the test
+// command, or the per-test output of the legacy munge.js script. Runs are
+// sorted by implementation, then version.
+func Load(paths ...string) ([]Run, error) {
+	var files []string
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && (p == path || filepath.Ext(p) == ".json") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var runs []Run
+	seen := map[string]string{}
+	for _, file := range files {
+		run, err := ReadRun(file)
+		if err != nil {
+			return nil, err
+		}
+		key := run.Implementation + "@" + run.Version
+		if other, ok := seen[key]; ok {
+			return nil, fmt.Errorf("%s and %s are both reports of %s with version %s", other, file, run.Implementation, run.Version)
+		}
+		seen[key] = file
+		runs = append(runs, run)
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		if runs[i].Implementation != runs[j].Implementation {
+			return runs[i].Implementation < runs[j].Implementation
+		}
+		return runs[i].Version < runs[j].Version
+	})
+	return runs, nil
+}
+
+// ReadRun reads a single report. Tests that did not finish are dropped.
+func ReadRun(path string) (Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Run{}, err
+	}
+
+	var first map[string]json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&first); err != nil {
+		return Run{}, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+
+	var events []test2json.Event
+	if _, ok := first["Action"]; ok {
+		events, err = test2json.ReadEvents(bytes.NewReader(data))
+	} else {
+		events, err = mungedEvents(data)
+	}
+	if err != nil {
+		return Run{}, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+
+	r := report.FromEvents(events)
+	finished := r.Tests[:0]
+	for _, t := range r.Tests {
+		if t.Outcome != "" {
+			finished = append(finished, t)
+		}
+	}
+	r.Tests = finished
+
+	run := Run{
+		Implementation: strings.SplitN(filepath.Base(path), ".", 2)[0],
+		Version:        UnknownVersion,
+		Time:           r.Time,
+		Report:         r,
+	}
+	if v, ok := r.Meta["version"].(string); ok && v != "" {
+		run.Version = v
+	}
+	if v, ok := r.Meta["job_url"].(string); ok {
+		run.JobURL = v
+	}
+	return run, nil
+}
+
+// mungedTest is a test in the output of munge.js, which maps the full name of
+// every test to its outcome and merged output.
+type mungedTest struct {
+	Output  string     `json:"output"`
+	Outcome string     `json:"outcome"`
+	Time    *time.Time `json:"time"`
+}
+
+// mungedEvents turns the output of munge.js back into test2json events.
+func mungedEvents(data []byte) ([]test2json.Event, error) {
+	var tests map[string]mungedTest
+	if err := json.Unmarshal(data, &tests); err != nil {
+		return nil, err
+	}
+
+	// Sorting the names puts parents before their children.
+	names := make([]string, 0, len(tests))
+	for name := range tests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var events []test2json.Event
+	for _, name := range names {
+		t := tests[name]
+		events = append(events,
+			test2json.Event{Time: t.Time, Action: "run", Test: name},
+			test2json.Event{Time: t.Time, Action: "output", Test: name, Output: t.Output},
+		)
+		if t.Outcome == report.Pass || t.Outcome == report.Fail || t.Outcome == report.Skip {
+			events = append(events, test2json.Event{Time: t.Time, Action: t.Outcome, Test: name})
+		}
+	}
+	return events, nil
+}
+
+// Counts holds the outcomes of the leaf tests below a test or a spec.
+type Counts struct {
+	Pass, Fail, Skip int
+}
+
+// Total returns the number of leaf tests.
+func (c Counts) Total() int {
+	return c.Pass + c.Fail + c.Skip
+}
+
+func (c *Counts) add(outcome string) {
+	switch outcome {
+	case report.Pass:
+		c.Pass++
+	case report.Fail:
+		c.Fail++
+	case report.Skip:
+		c.Skip++
+	}
+}
+
+// countLeaves counts the outcomes of the leaf tests of the run matching
+// match.
+func countLeaves(run *Run, match func(t *report.Test) bool) (Counts, bool) {
+	var c Counts
+	found := false
+	for _, t := range run.Report.Tests {
+		if match(t) {
+			found = true
+			if run.Leaf(t.Name) {
+				c.add(t.Outcome)
+			}
+		}
+	}
+	return c, found
+}
+
+// specURL adds the scheme missing from the spec URLs logged by older
+// versions of the suite.
+func specURL(spec string) string {
+	if strings.HasPrefix(spec, "http") {
+		return spec
+	}
+	return "https://" + spec
+}
+
+// ownSpecs returns the spec URLs logged by the test itself, not inherited
+// from its parents.
+func ownSpecs(t *report.Test) []string {
+	var specs []string
+	values, _ := t.Meta["specs"].([]interface{})
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			specs = append(specs, specURL(s))
+		}
+	}
+	return specs
+}
@@ -0,0 +1,163 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pathGateway = "https://specs.ipfs.tech/http-gateways/path-gateway/"
+
+func writeReports(t *testing.T) string {
+	dir := t.TempDir()
+
+	// The test2json output of the test command.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "kubo.json"), []byte(
+		`{"Action":"run","Test":"TestMetadata"}`+"\n"+
+			`{"Action":"output","Test":"TestMetadata","Output":"    --- META: {\"version\":\"v1\",\"job_url\":\"https://example.com/job\"}\n"}`+"\n"+
+			`{"Action":"pass","Test":"TestMetadata"}`+"\n"+
+			`{"Action":"run","Test":"TestGatewayCache"}`+"\n"+
+			`{"Action":"output","Test":"TestGatewayCache","Output":"    --- META: {\"group\":\"Path\",\"specs\":[\"`+pathGateway+`#etag\"]}\n"}`+"\n"+
+			`{"Action":"run","Test":"TestGatewayCache/GET_etag"}`+"\n"+
+			`{"Action":"pass","Test":"TestGatewayCache/GET_etag"}`+"\n"+
+			`{"Action":"run","Test":"TestGatewayCache/HEAD_etag"}`+"\n"+
+			`{"Action":"fail","Test":"TestGatewayCache/HEAD_etag"}`+"\n"+
+			`{"Action":"fail","Test":"TestGatewayCache"}`+"\n"+
+			`{"Action":"run","Test":"TestTar"}`+"\n"+
+			`{"Action":"skip","Test":"TestTar"}`+"\n"), 0644))
+
+	// The output of munge.js, in a subdirectory.
+	munged := map[string]interface{}{
+		"TestGatewayCache": map[string]interface{}{
+			"output":  "    --- META: {\"group\":\"Path\",\"specs\":[\"specs.ipfs.tech/http-gateways/path-gateway/#etag\"]}\n",
+			"outcome": "pass",
+		},
+		"TestGatewayCache/GET_etag": map[string]interface{}{"outcome": "pass"},
+	}
+	data, err := json.Marshal(munged)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "legacy"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "legacy", "rainbow.json"), data, 0644))
+
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	runs, err := Load(writeReports(t))
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+
+	assert.Equal(t, "kubo", runs[0].Implementation)
+	assert.Equal(t, "v1", runs[0].Version)
+	assert.Equal(t, "https://example.com/job", runs[0].JobURL)
+	assert.Equal(t, "rainbow", runs[1].Implementation)
+	assert.Equal(t, UnknownVersion, runs[1].Version)
+	assert.Len(t, runs[1].Report.Tests, 2)
+	assert.True(t, runs[1].Leaf("TestGatewayCache/GET_etag"))
+	assert.False(t, runs[1].Leaf("TestGatewayCache"))
+}
+
+func TestTables(t *testing.T) {
+	runs, err := Load(writeReports(t))
+	require.NoError(t, err)
+
+	byTest := ByTest(runs)
+	require.Len(t, byTest.Rows, 2)
+	assert.Equal(t, Row{
+		Group: "Path",
+		Name:  "GatewayCache",
+		Specs: []string{pathGateway + "#etag"},
+		Cells: []*Counts{{Pass: 1, Fail: 1}, {Pass: 1}},
+	}, byTest.Rows[0])
+	assert.Equal(t, NoGroup, byTest.Rows[1].Group)
+	assert.Equal(t, []*Counts{{Skip: 1}, nil}, byTest.Rows[1].Cells)
+
+	var md bytes.Buffer
+	require.NoError(t, WriteMarkdown(&md, byTest))
+	assert.Equal(t, "| gateway | kubo | rainbow |\n"+
+		"| ------: | :-- | :-- |\n"+
+		"| version | [v1](https://example.com/job) | unknown |\n"+
+		"| **Path** | | |\n"+
+		"| [GatewayCache]("+pathGateway+"#etag) | :red_circle: (1 / 2) | :green_circle: (1 / 1) |\n"+
+		"| **Other** | | |\n"+
+		"| Tar | :yellow_circle: (skipped) |  |\n", md.String())
+
+	bySpec := BySpec(runs)
+	require.Len(t, bySpec.Rows, 1)
+	assert.Equal(t, pathGateway, bySpec.Rows[0].Group)
+	assert.Equal(t, "#etag", bySpec.Rows[0].Name)
+	assert.Equal(t, []*Counts{{Pass: 1, Fail: 1}, {Pass: 1}}, bySpec.Rows[0].Cells)
+
+	var html bytes.Buffer
+	require.NoError(t, WriteHTML(&html, bySpec))
+	assert.Contains(t, html.String(), `<td class="fail">❌ 1 / 2</td><td class="pass">✅ 1 / 1</td>`)
+
+	var js bytes.Buffer
+	require.NoError(t, WriteJSON(&js, byTest))
+	assert.JSONEq(t, `{
+		"runs": [
+			{"implementation": "kubo", "version": "v1", "job_url": "https://example.com/job"},
+			{"implementation": "rainbow", "version": "unknown"}
+		],
+		"rows": [
+			{"group": "Path", "name": "GatewayCache", "specs": ["`+pathGateway+`#etag"], "cells": [{"pass": 1, "fail": 1, "skip": 0}, {"pass": 1, "fail": 0, "skip": 0}]},
+			{"group": "Other", "name": "Tar", "specs": [], "cells": [{"pass": 0, "fail": 0, "skip": 1}, null]}
+		]
+	}`, js.String())
+}
+
+func TestSite(t *testing.T) {
+	runs, err := Load(writeReports(t))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	page := filepath.Join(dir, "content", "results", "kubo", "_index.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(page), 0755))
+	require.NoError(t, os.WriteFile(page, []byte("---\ntitle: Old\nweight: 1\n---\nKept content.\n"), 0644))
+
+	require.NoError(t, Site{Dir: dir}.Write(runs))
+
+	content, err := os.ReadFile(page)
+	require.NoError(t, err)
+	assert.Equal(t, "---\nimplementation_id: kubo\ntitle: kubo\nweight: 1\n---\nKept content.\n", string(content))
+
+	content, err = os.ReadFile(filepath.Join(dir, "content", "specs", "http-gateways", "path-gateway", "_index.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "hashes:\n  - etag\n")
+	assert.Contains(t, string(content), "spec_full_name: https://specs.ipfs.tech/http-gateways/path-gateway\n")
+
+	var results map[string]map[string]interface{}
+	data, err := os.ReadFile(filepath.Join(dir, "data", "testresults", "kubo", "v1.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &results))
+	assert.Equal(t, map[string]interface{}{
+		"full_name":             "TestGatewayCache",
+		"name":                  "TestGatewayCache",
+		"parent_test_full_name": nil,
+		"passed_leave":          1.0,
+		"failed_leaves":         1.0,
+		"skipped_leaves":        0.0,
+		"total_leaves":          2.0,
+		"slug":                  "test-gateway-cache",
+	}, results["TestGatewayCache"])
+
+	var groups map[string]map[string]interface{}
+	data, err = os.ReadFile(filepath.Join(dir, "data", "specsgroups.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &groups))
+	assert.Contains(t, groups[pathGateway+"#etag"], "TestGatewayCache")
+	assert.Contains(t, groups["https://specs.ipfs.tech/http-gateways"], "TestGatewayCache")
+
+	_, err = os.Stat(filepath.Join(dir, "content", "tests", "test-gateway-cache", "get-etag", "_index.md"))
+	assert.NoError(t, err)
+}
+
+func TestSlugifyTestName(t *testing.T) {
+	assert.Equal(t, "test-gateway-cache/get-for-ipfs-path", slugifyTestName("TestGatewayCache/GET_for_ipfs_path"))
+	assert.Equal(t, "test-gateway-ipnspath/get-for-ipns-name", slugifyTestName("TestGatewayIPNSPath/GET_for_%2Fipns%2Fname"))
+}
@@ -0,0 +1,66 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+var githubRunRegexp = regexp.MustCompile(`^https://github\.com/([^/]+)/([^/]+)/actions/runs/(\d+)`)
+
+// GitHubDetails returns a Site.Details function fetching the commit, branch
+// and start time of GitHub Actions runs. The token is optional, it raises
+// the rate limit of the GitHub API. Errors are reported with warn and the
+// run is left without details.
+func GitHubDetails(token string, warn func(error)) func(jobURL string) map[string]interface{} {
+	return func(jobURL string) map[string]interface{} {
+		m := githubRunRegexp.FindStringSubmatch(jobURL)
+		if m == nil {
+			return nil
+		}
+
+		details, err := fetchRun(token, fmt.Sprintf("https://api.github.com/repos/%s/%s/actions/runs/%s", m[1], m[2], m[3]))
+		if err != nil {
+			warn(fmt.Errorf("fetching the details of %s: %w", jobURL, err))
+			return nil
+		}
+		return details
+	}
+}
+
+func fetchRun(token, apiURL string) (map[string]interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	var run struct {
+		CreatedAt    string `json:"created_at"`
+		HeadSHA      string `json:"head_sha"`
+		HeadBranch   string `json:"head_branch"`
+		RunStartedAt string `json:"run_started_at"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&run); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"created_at":     run.CreatedAt,
+		"head_sha":       run.HeadSHA,
+		"head_branch":    run.HeadBranch,
+		"run_started_at": run.RunStartedAt,
+	}, nil
+}
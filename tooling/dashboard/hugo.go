@@ -0,0 +1,536 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ipfs/gateway-conformance/tooling/report"
+	"gopkg.in/yaml.v3"
+)
+
+// Site writes the data and content files of the Hugo website in www/. The
+// layouts of the conformance theme read them.
+type Site struct {
+	// Dir is the root of the website, e.g. "www".
+	Dir string
+	// Details, when set, adds details to the job URL of a run, e.g. the
+	// commit and branch of the GitHub Actions run.
+	Details func(jobURL string) map[string]interface{}
+}
+
+// testGroup describes a test in data/testgroups.json.
+type testGroup struct {
+	Versions []string `json:"versions"`
+	Name     string   `json:"name"`
+	FullName string   `json:"full_name"`
+	Slug     string   `json:"slug"`
+}
+
+// spec describes a spec page in data/specs.json.
+type spec struct {
+	Versions     []string `json:"versions"`
+	SpecFullName string   `json:"spec_full_name"`
+	Slug         string   `json:"slug"`
+	Name         string   `json:"name"`
+	IsHashed     bool     `json:"isHashed"`
+}
+
+// testResult describes the outcome of a test in data/testresults.
+type testResult struct {
+	FullName string  `json:"full_name"`
+	Name     string  `json:"name"`
+	Parent   *string `json:"parent_test_full_name"`
+	Passed   int     `json:"passed_leave"`
+	Failed   int     `json:"failed_leaves"`
+	Skipped  int     `json:"skipped_leaves"`
+	Total    int     `json:"total_leaves"`
+	Slug     string  `json:"slug"`
+}
+
+// Write writes the website files for the runs. Existing pages keep their
+// content, their front matter is updated.
+func (s Site) Write(runs []Run) error {
+	if err := s.writeRuns(runs); err != nil {
+		return err
+	}
+
+	// Every test of every run, with the versions it ran with.
+	groups := map[string]map[string]*testGroup{}
+	tests := map[string]*testGroup{}
+	for i := range runs {
+		for _, t := range runs[i].Report.Tests {
+			g, ok := tests[t.Name]
+			if !ok {
+				g = &testGroup{Name: testName(t.Name), FullName: t.Name, Slug: slugifyTestName(t.Name)}
+				tests[t.Name] = g
+				parent := t.Parent()
+				if parent == "" {
+					parent = "null"
+				}
+				if groups[parent] == nil {
+					groups[parent] = map[string]*testGroup{}
+				}
+				groups[parent][t.Name] = g
+			}
+			g.Versions = addUniq(g.Versions, runs[i].Version)
+		}
+	}
+	if err := s.writeJSON("data/testgroups.json", groups); err != nil {
+		return err
+	}
+
+	if err := s.writeSpecs(runs, tests); err != nil {
+		return err
+	}
+
+	logs := map[string]map[string]map[string]string{}
+	for _, run := range runs {
+		if logs[run.Implementation] == nil {
+			logs[run.Implementation] = map[string]map[string]string{}
+		}
+		versionLogs := map[string]string{}
+		for _, t := range run.Report.Tests {
+			versionLogs[t.Name] = t.Output
+		}
+		logs[run.Implementation][run.Version] = versionLogs
+	}
+	if err := s.writeJSON("data/testlogs.json", logs); err != nil {
+		return err
+	}
+
+	for i := range runs {
+		if err := s.writeResults(&runs[i]); err != nil {
+			return err
+		}
+	}
+
+	return s.writeTests(runs)
+}
+
+// writeRuns writes data/testruns.json, the details of every run by
+// implementation and version.
+func (s Site) writeRuns(runs []Run) error {
+	details := map[string]map[string]map[string]interface{}{}
+	for _, run := range runs {
+		d := map[string]interface{}{"job_url": nil, "time": nil}
+		if run.JobURL != "" {
+			d["job_url"] = run.JobURL
+			if s.Details != nil {
+				for k, v := range s.Details(run.JobURL) {
+					d[k] = v
+				}
+			}
+		}
+		if !run.Time.IsZero() {
+			d["time"] = run.Time.UTC().Format(time.RFC3339)
+			if _, ok := d["created_at"]; !ok {
+				d["created_at"] = d["time"]
+			}
+		}
+		if details[run.Implementation] == nil {
+			details[run.Implementation] = map[string]map[string]interface{}{}
+		}
+		details[run.Implementation][run.Version] = d
+	}
+	return s.writeJSON("data/testruns.json", details)
+}
+
+// writeSpecs writes the tree of the specs logged by the tests in
+// data/specs.json, a page per spec under content/specs, and the tests of
+// every spec in data/specsgroups.json.
+func (s Site) writeSpecs(runs []Run, tests map[string]*testGroup) error {
+	versions := map[string][]string{}
+	testSpecs := map[string][]string{}
+	for _, run := range runs {
+		for _, t := range run.Report.Tests {
+			for _, u := range ownSpecs(t) {
+				// The page of a spec document has no trailing slash, the
+				// layouts append "/#hash" to it.
+				if !strings.Contains(u, "#") {
+					u = strings.TrimSuffix(u, "/")
+				}
+				versions[u] = addUniq(versions[u], run.Version)
+				testSpecs[t.Name] = addUniq(testSpecs[t.Name], u)
+			}
+		}
+	}
+
+	specs := map[string]map[string]*spec{}
+	for full, vs := range versions {
+		current := full
+		for current != "null" {
+			parent, err := specParent(current)
+			if err != nil {
+				return err
+			}
+			name, hashed, err := specName(current)
+			if err != nil {
+				return err
+			}
+			if specs[parent] == nil {
+				specs[parent] = map[string]*spec{}
+			}
+			sp, ok := specs[parent][current]
+			if !ok {
+				sp = &spec{SpecFullName: current, Slug: slugify(current), Name: name, IsHashed: hashed}
+				specs[parent][current] = sp
+			}
+			for _, v := range vs {
+				sp.Versions = addUniq(sp.Versions, v)
+			}
+			current = parent
+		}
+	}
+	for _, children := range specs {
+		for _, sp := range children {
+			sort.Strings(sp.Versions)
+		}
+	}
+	if err := s.writeJSON("data/specs.json", specs); err != nil {
+		return err
+	}
+
+	if err := s.writeSpecPages(specs, "null", nil); err != nil {
+		return err
+	}
+
+	specsGroups := map[string]map[string]*testGroup{}
+	for _, children := range specs {
+		for full := range children {
+			group := map[string]*testGroup{}
+			for name, urls := range testSpecs {
+				if slices.ContainsFunc(urls, func(u string) bool { return strings.HasPrefix(u, full) }) {
+					group[name] = tests[name]
+				}
+			}
+			specsGroups[full] = group
+		}
+	}
+	return s.writeJSON("data/specsgroups.json", specsGroups)
+}
+
+// writeSpecPages writes a page per spec below parent. The sections of a spec
+// document, its hashes, are listed by the page of the document.
+func (s Site) writeSpecPages(specs map[string]map[string]*spec, parent string, dirs []string) error {
+	for _, key := range sortedKeys(specs[parent]) {
+		sp := specs[parent][key]
+		if sp.IsHashed {
+			err := s.updateFrontMatter(path.Join("content/specs", path.Join(dirs...), "_index.md"), func(data map[string]interface{}) {
+				hashes, _ := data["hashes"].([]interface{})
+				if !slices.Contains(hashes, interface{}(sp.Name)) {
+					hashes = append(hashes, sp.Name)
+				}
+				data["hashes"] = hashes
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		sub := append(slices.Clone(dirs), sp.Name)
+		err := s.updateFrontMatter(path.Join("content/specs", path.Join(sub...), "_index.md"), func(data map[string]interface{}) {
+			data["versions"] = sp.Versions
+			data["spec_full_name"] = sp.SpecFullName
+			data["slug"] = sp.Slug
+			data["name"] = sp.Name
+			data["isHashed"] = sp.IsHashed
+			data["title"] = sp.Name
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.writeSpecPages(specs, key, sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeResults writes the outcome of the leaf tests below every test of the
+// run in data/testresults, and a page per test under content/results.
+func (s Site) writeResults(run *Run) error {
+	results := map[string]testResult{}
+	for _, t := range run.Report.Tests {
+		r := testResult{FullName: t.Name, Name: testName(t.Name), Slug: slugifyTestName(t.Name)}
+		if parent := t.Parent(); parent != "" {
+			r.Parent = &parent
+		}
+		for _, leaf := range append(run.Report.Children(t.Name), t) {
+			if !run.Leaf(leaf.Name) {
+				continue
+			}
+			r.Total++
+			switch leaf.Outcome {
+			case report.Pass:
+				r.Passed++
+			case report.Fail:
+				r.Failed++
+			case report.Skip:
+				r.Skipped++
+			}
+		}
+		results[t.Name] = r
+	}
+	err := s.writeJSON(path.Join("data/testresults", run.Implementation, run.Version+".json"), results)
+	if err != nil {
+		return err
+	}
+
+	dir := path.Join("content/results", run.Implementation)
+	err = s.updateFrontMatter(path.Join(dir, "_index.md"), func(data map[string]interface{}) {
+		data["implementation_id"] = run.Implementation
+		data["title"] = run.Implementation
+	})
+	if err != nil {
+		return err
+	}
+	dir = path.Join(dir, run.Version)
+	err = s.updateFrontMatter(path.Join(dir, "_index.md"), func(data map[string]interface{}) {
+		data["implementation_id"] = run.Implementation
+		data["version"] = run.Version
+		data["title"] = run.Version
+	})
+	if err != nil {
+		return err
+	}
+	for _, t := range run.Report.Tests {
+		slug := slugifyTestName(t.Name)
+		err := s.updateFrontMatter(path.Join(dir, slug, "_index.md"), func(data map[string]interface{}) {
+			data["slug"] = slug
+			data["name"] = testName(t.Name)
+			data["full_name"] = t.Name
+			data["outcome"] = t.Outcome
+			data["implementation_id"] = run.Implementation
+			data["version"] = run.Version
+			data["title"] = testName(t.Name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTests writes a page per test under content/tests. The metadata
+// logged by the tests become taxonomies: a test logging {"ipip": "0402"}
+// gets ipips: ["0402"].
+func (s Site) writeTests(runs []Run) error {
+	type page struct {
+		versions   []string
+		taxonomies map[string][]interface{}
+	}
+	pages := map[string]*page{}
+	for _, run := range runs {
+		for _, t := range run.Report.Tests {
+			p, ok := pages[t.Name]
+			if !ok {
+				p = &page{taxonomies: map[string][]interface{}{}}
+				pages[t.Name] = p
+			}
+			p.versions = addUniq(p.versions, run.Version)
+			for k, v := range t.Meta {
+				values := p.taxonomies[k+"s"]
+				if !slices.ContainsFunc(values, func(o interface{}) bool { return reflect.DeepEqual(o, v) }) {
+					p.taxonomies[k+"s"] = append(values, v)
+				}
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(pages) {
+		p := pages[name]
+		slug := slugifyTestName(name)
+		err := s.updateFrontMatter(path.Join("content/tests", slug, "_index.md"), func(data map[string]interface{}) {
+			data["slug"] = slug
+			data["name"] = testName(name)
+			data["full_name"] = name
+			data["versions"] = p.versions
+			for k, v := range p.taxonomies {
+				data[k] = v
+			}
+			data["title"] = testName(name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s Site) writeJSON(p string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	full := filepath.Join(s.Dir, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(full, data, 0644)
+}
+
+var frontMatterDelimiter = []byte("---\n")
+
+// updateFrontMatter updates the YAML front matter of the page at p, which is
+// created when missing.
+func (s Site) updateFrontMatter(p string, update func(data map[string]interface{})) error {
+	full := filepath.Join(s.Dir, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{}
+	var content []byte
+	existing, err := os.ReadFile(full)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	case bytes.HasPrefix(existing, frontMatterDelimiter):
+		matter, rest, ok := bytes.Cut(existing[len(frontMatterDelimiter):], frontMatterDelimiter)
+		if !ok {
+			return fmt.Errorf("%s: unterminated front matter", full)
+		}
+		if err := yaml.Unmarshal(matter, &data); err != nil {
+			return fmt.Errorf("%s: %w", full, err)
+		}
+		if data == nil {
+			data = map[string]interface{}{}
+		}
+		content = rest
+	default:
+		content = existing
+	}
+
+	update(data)
+
+	var matter bytes.Buffer
+	enc := yaml.NewEncoder(&matter)
+	enc.SetIndent(2)
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	page := slices.Concat(frontMatterDelimiter, matter.Bytes(), frontMatterDelimiter, content)
+	return os.WriteFile(full, page, 0644)
+}
+
+// specParent returns the URL of the parent of a spec, or "null" for a
+// top-level spec. The hash of a spec URL counts as a path segment:
+// https://specs.ipfs.tech/http-gateways/path-gateway/#etag is a child of
+// https://specs.ipfs.tech/http-gateways/path-gateway.
+func specParent(spec string) (string, error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return "", err
+	}
+	segments := pathSegments(u.Path)
+	if u.Fragment != "" {
+		segments = append(segments, u.Fragment)
+	}
+	if len(segments) <= 1 {
+		return "null", nil
+	}
+	return fmt.Sprintf("%s://%s/%s", u.Scheme, u.Host, strings.Join(segments[:len(segments)-1], "/")), nil
+}
+
+// specName returns the hash of a spec URL, or its last path segment when it
+// has no hash.
+func specName(spec string) (name string, hashed bool, err error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return "", false, err
+	}
+	if u.Fragment != "" {
+		return u.Fragment, true, nil
+	}
+	segments := pathSegments(u.Path)
+	if len(segments) == 0 {
+		return "", false, fmt.Errorf("invalid spec URL: %s", spec)
+	}
+	return segments[len(segments)-1], false, nil
+}
+
+func pathSegments(p string) []string {
+	var segments []string
+	for _, s := range strings.Split(p, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+var (
+	spacesRegexp      = regexp.MustCompile(`\s+`)
+	punctuationRegexp = regexp.MustCompile(`[.,()"/]`)
+	// The range " -/" keeps the ASCII characters from the space to the slash.
+	unsafeRegexp      = regexp.MustCompile(`[^a-z0-9 -/]`)
+	underscoresRegexp = regexp.MustCompile(`_+`)
+	dashesRegexp      = regexp.MustCompile(`-+`)
+	camelCaseRegexp   = regexp.MustCompile(`([a-z])([A-Z])`)
+)
+
+// slugify turns a string into a URL path segment.
+func slugify(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = spacesRegexp.ReplaceAllString(s, "_")
+	s = punctuationRegexp.ReplaceAllString(s, "-")
+	s = unsafeRegexp.ReplaceAllString(s, "-")
+	s = underscoresRegexp.ReplaceAllString(s, "_")
+	return dashesRegexp.ReplaceAllString(s, "-")
+}
+
+// slugifyTestName slugifies each component of a test name, splitting
+// CamelCase words: "TestGatewayCache/GET_for_ipfs_path" becomes
+// "test-gateway-cache/get-for-ipfs-path".
+func slugifyTestName(name string) string {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		if unescaped, err := url.PathUnescape(part); err == nil {
+			part = unescaped
+		}
+		parts[i] = slugify(camelCaseRegexp.ReplaceAllString(part, "$1-$2"))
+	}
+	return strings.Join(parts, "/")
+}
+
+// testName returns the readable name of a test, the last component of its
+// name with spaces.
+func testName(name string) string {
+	n := strings.ReplaceAll(name[strings.LastIndex(name, "/")+1:], "_", " ")
+	if unescaped, err := url.PathUnescape(n); err == nil {
+		return unescaped
+	}
+	return n
+}
+
+func addUniq(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
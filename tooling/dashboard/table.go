@@ -0,0 +1,287 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/ipfs/gateway-conformance/tooling/report"
+)
+
+// NoGroup is the group of the rows without group.
+const NoGroup = "Other"
+
+// Table compares the runs, one column per run. Rows are grouped, the rows
+// of a group follow each other.
+type Table struct {
+	Runs []Run
+	Rows []Row
+}
+
+// Row is a test, or a spec, and the outcome of its leaf tests in each run.
+type Row struct {
+	Group string
+	// Name is the label of the row, e.g. the test name without its "Test"
+	// prefix.
+	Name string
+	// Specs lists the spec URLs the row links to.
+	Specs []string
+	// Cells holds the counts of each run, nil when the row did not run.
+	Cells []*Counts
+}
+
+// Groups returns the rows by group, in order.
+func (t Table) Groups() [][]Row {
+	var groups [][]Row
+	for i, row := range t.Rows {
+		if i == 0 || row.Group != t.Rows[i-1].Group {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], row)
+	}
+	return groups
+}
+
+// sortRows orders the rows by group, then name, with the rows without group
+// last.
+func sortRows(rows []Row) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Group != b.Group {
+			if a.Group == NoGroup || b.Group == NoGroup {
+				return b.Group == NoGroup
+			}
+			return a.Group < b.Group
+		}
+		return a.Name < b.Name
+	})
+}
+
+// ByTest returns a table with a row per top-level test, grouped by test
+// group. The metadata test is left out.
+func ByTest(runs []Run) Table {
+	table := Table{Runs: runs}
+	seen := map[string]bool{}
+	for i := range runs {
+		for _, t := range runs[i].Report.TopLevel() {
+			if seen[t.Name] || t.Name == "TestMetadata" {
+				continue
+			}
+			seen[t.Name] = true
+
+			row := Row{
+				Group: t.Group,
+				Name:  strings.ReplaceAll(strings.TrimPrefix(t.Name, "Test"), "_", " "),
+				Specs: ownSpecs(t),
+				Cells: make([]*Counts, len(runs)),
+			}
+			if row.Group == "" {
+				row.Group = NoGroup
+			}
+			for j := range runs {
+				c, ok := countLeaves(&runs[j], func(test *report.Test) bool {
+					return test.Name == t.Name || strings.HasPrefix(test.Name, t.Name+"/")
+				})
+				if ok {
+					row.Cells[j] = &c
+				}
+			}
+			table.Rows = append(table.Rows, row)
+		}
+	}
+	sortRows(table.Rows)
+	return table
+}
+
+// BySpec returns a table with a row per spec URL logged by the tests,
+// grouped by spec document. A row counts the leaf tests of every test whose
+// specs start with its URL, so that the row of a document sums up its
+// sections.
+func BySpec(runs []Run) Table {
+	table := Table{Runs: runs}
+	seen := map[string]bool{}
+	for i := range runs {
+		for _, t := range runs[i].Report.Tests {
+			for _, spec := range ownSpecs(t) {
+				if seen[spec] {
+					continue
+				}
+				seen[spec] = true
+				document, _, _ := strings.Cut(spec, "#")
+				table.Rows = append(table.Rows, Row{Group: document, Name: spec, Specs: []string{spec}})
+			}
+		}
+	}
+	sortRows(table.Rows)
+
+	for i := range table.Rows {
+		row := &table.Rows[i]
+		row.Cells = make([]*Counts, len(runs))
+		for j := range runs {
+			c, ok := countLeaves(&runs[j], func(t *report.Test) bool {
+				for _, spec := range t.Specs {
+					if strings.HasPrefix(specURL(spec), row.Name) {
+						return true
+					}
+				}
+				return false
+			})
+			if ok {
+				row.Cells[j] = &c
+			}
+		}
+		if _, fragment, ok := strings.Cut(row.Name, "#"); ok {
+			row.Name = "#" + fragment
+		} else {
+			row.Name = "(document)"
+		}
+	}
+	return table
+}
+
+const markdownTemplate = `| gateway |{{ range .Runs }} {{ .Implementation }} |{{ end }}
+| ------: |{{ range .Runs }} :-- |{{ end }}
+| version |{{ range .Runs }} {{ if .JobURL }}[{{ .Version }}]({{ .JobURL }}){{ else }}{{ .Version }}{{ end }} |{{ end }}
+{{- range .Groups }}
+| **{{ (index . 0).Group }}** |{{ range $.Runs }} |{{ end }}
+{{- range . }}
+| {{ label . }} |{{ range .Cells }} {{ cell . }} |{{ end }}
+{{- end }}
+{{- end }}
+`
+
+var markdown = template.Must(template.New("markdown").Funcs(template.FuncMap{
+	"label": func(row Row) string {
+		name := escapeCell(row.Name)
+		switch len(row.Specs) {
+		case 0:
+			return name
+		case 1:
+			return fmt.Sprintf("[%s](%s)", name, row.Specs[0])
+		}
+		var links []string
+		for i, spec := range row.Specs {
+			links = append(links, fmt.Sprintf("[%d](%s)", i, spec))
+		}
+		return fmt.Sprintf("%s (%s)", name, strings.Join(links, ", "))
+	},
+	"cell": func(c *Counts) string {
+		switch {
+		case c == nil || c.Total() == 0:
+			return ""
+		case c.Fail > 0:
+			return fmt.Sprintf(":red_circle: (%d / %d)", c.Pass, c.Total())
+		case c.Skip > 0:
+			return ":yellow_circle: (skipped)"
+		}
+		return fmt.Sprintf(":green_circle: (%d / %d)", c.Pass, c.Total())
+	},
+}).Parse(markdownTemplate))
+
+// WriteMarkdown writes the table as Markdown, with the runs as columns.
+func WriteMarkdown(w io.Writer, t Table) error {
+	return markdown.Execute(w, t)
+}
+
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Gateway Conformance Dashboard</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.6em; }
+th { background: #f4f4f4; }
+th.row { text-align: right; }
+td { text-align: center; white-space: nowrap; }
+td.fail { color: #cf222e; }
+td.skip { color: #9a6700; }
+td.pass { color: #1a7f37; }
+</style>
+</head>
+<body>
+<h1>Gateway Conformance Dashboard</h1>
+<table>
+<tr><th class="row">gateway</th>{{ range .Runs }}<th>{{ .Implementation }}</th>{{ end }}</tr>
+<tr><th class="row">version</th>{{ range .Runs }}<th>{{ if .JobURL }}<a href="{{ .JobURL }}">{{ .Version }}</a>{{ else }}{{ .Version }}{{ end }}</th>{{ end }}</tr>
+{{- range .Groups }}
+<tr><th class="row" colspan="{{ len $.Runs | inc }}">{{ (index . 0).Group }}</th></tr>
+{{- range . }}
+<tr><th class="row">{{ if eq (len .Specs) 1 }}<a href="{{ index .Specs 0 }}">{{ .Name }}</a>{{ else }}{{ .Name }}{{ range $i, $spec := .Specs }} <a href="{{ $spec }}">[{{ $i }}]</a>{{ end }}{{ end }}</th>
+{{- range .Cells }}{{ with . }}{{ if .Fail }}<td class="fail">❌ {{ .Pass }} / {{ .Total }}</td>{{ else if .Skip }}<td class="skip">⏭️ skipped</td>{{ else if .Pass }}<td class="pass">✅ {{ .Pass }} / {{ .Total }}</td>{{ else }}<td></td>{{ end }}{{ else }}<td></td>{{ end }}{{ end }}</tr>
+{{- end }}
+{{- end }}
+</table>
+</body>
+</html>
+`
+
+var html = htmltemplate.Must(htmltemplate.New("html").Funcs(htmltemplate.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(htmlTemplate))
+
+// WriteHTML writes the table as a self-contained HTML page.
+func WriteHTML(w io.Writer, t Table) error {
+	return html.Execute(w, t)
+}
+
+type jsonTable struct {
+	Runs []jsonRun `json:"runs"`
+	Rows []jsonRow `json:"rows"`
+}
+
+type jsonRun struct {
+	Implementation string `json:"implementation"`
+	Version        string `json:"version"`
+	JobURL         string `json:"job_url,omitempty"`
+}
+
+type jsonRow struct {
+	Group string   `json:"group"`
+	Name  string   `json:"name"`
+	Specs []string `json:"specs"`
+	// Cells holds the counts of each run, null when the row did not run.
+	Cells []*jsonCounts `json:"cells"`
+}
+
+type jsonCounts struct {
+	Pass int `json:"pass"`
+	Fail int `json:"fail"`
+	Skip int `json:"skip"`
+}
+
+// WriteJSON writes the table as indented JSON, with the runs, and the rows
+// with the counts of each run.
+func WriteJSON(w io.Writer, t Table) error {
+	out := jsonTable{Runs: []jsonRun{}, Rows: []jsonRow{}}
+	for _, run := range t.Runs {
+		out.Runs = append(out.Runs, jsonRun{Implementation: run.Implementation, Version: run.Version, JobURL: run.JobURL})
+	}
+	for _, row := range t.Rows {
+		r := jsonRow{Group: row.Group, Name: row.Name, Specs: row.Specs, Cells: []*jsonCounts{}}
+		if r.Specs == nil {
+			r.Specs = []string{}
+		}
+		for _, c := range row.Cells {
+			if c == nil {
+				r.Cells = append(r.Cells, nil)
+				continue
+			}
+			r.Cells = append(r.Cells, &jsonCounts{Pass: c.Pass, Fail: c.Fail, Skip: c.Skip})
+		}
+		out.Rows = append(out.Rows, r)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
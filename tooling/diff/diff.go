@@ -0,0 +1,254 @@
+// Package diff compares the outcomes of two conformance runs, e.g. of the
+// main branch and of a pull request, and reports the regressions.
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/ipfs/gateway-conformance/tooling/report"
+	"github.com/ipfs/gateway-conformance/tooling/results"
+	"github.com/ipfs/gateway-conformance/tooling/test2json"
+)
+
+// Test is the outcome of a test in a run.
+type Test struct {
+	// Path is the full name of the test, e.g. "TestGatewayBlock/GET_raw".
+	Path    string
+	Outcome string
+	Specs   []string
+	Group   string
+}
+
+// Run lists the tests of a run, in the order they started.
+type Run []Test
+
+// FromReport returns the tests of a test2json report.
+func FromReport(r *report.Report) Run {
+	var run Run
+	for _, t := range r.Tests {
+		run = append(run, Test{Path: t.Name, Outcome: t.Outcome, Specs: t.Specs, Group: t.Group})
+	}
+	return run
+}
+
+// FromResults returns the tests of a structured report.
+func FromResults(r *results.Report) Run {
+	var run Run
+	for _, t := range r.Tests {
+		run = append(run, Test{Path: t.Path, Outcome: string(t.Outcome), Specs: t.Specs, Group: t.Group})
+	}
+	return run
+}
+
+// Read reads a run from the JSON report of the test command, either the
+// test2json events written with --json-output, or the structured results
+// written with --results-output.
+func Read(path string) (Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var first map[string]json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&first); err != nil {
+		return nil, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+
+	if _, ok := first["schemaVersion"]; ok {
+		r, err := results.Read(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid report %s: %w", path, err)
+		}
+		return FromResults(r), nil
+	}
+
+	events, err := test2json.ReadEvents(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+	return FromReport(report.FromEvents(events)), nil
+}
+
+// Change is a test whose outcome differs between two runs. The outcome of a
+// test missing from a run, or skipped, is empty.
+type Change struct {
+	Test
+	Old string
+}
+
+// Diff lists the tests whose outcome changed between two runs.
+type Diff struct {
+	// NewlyFailing lists the tests that passed and now fail, they are the
+	// regressions.
+	NewlyFailing []Change
+	// NewlyPassing lists the tests that failed and now pass.
+	NewlyPassing []Change
+	// Added lists the tests that did not run and now run.
+	Added []Change
+	// Removed lists the tests that ran and no longer run.
+	Removed []Change
+}
+
+// HasRegressions reports whether a test that passed now fails.
+func (d Diff) HasRegressions() bool {
+	return len(d.NewlyFailing) > 0
+}
+
+// Compare returns the tests whose outcome changed between the old and new
+// runs. Skipped tests are considered as not run. Failing and passing tests
+// are listed at the deepest level, so that a failing check is reported
+// rather than every test above it. Added and removed tests are listed at the
+// highest level, so that a new test is reported rather than all its checks.
+func Compare(old, new Run) Diff {
+	outcomes := map[string]string{}
+	for _, t := range old {
+		outcomes[t.Path] = ran(t.Outcome)
+	}
+
+	var d Diff
+	seen := map[string]bool{}
+	for _, t := range new {
+		seen[t.Path] = true
+		c := Change{Test: t, Old: outcomes[t.Path]}
+		c.Outcome = ran(t.Outcome)
+
+		switch {
+		case c.Old == c.Outcome:
+		case c.Old == "" && c.Outcome != "":
+			d.Added = append(d.Added, c)
+		case c.Old != "" && c.Outcome == "":
+			d.Removed = append(d.Removed, c)
+		case c.Outcome == report.Fail:
+			d.NewlyFailing = append(d.NewlyFailing, c)
+		default:
+			d.NewlyPassing = append(d.NewlyPassing, c)
+		}
+	}
+	for _, t := range old {
+		if !seen[t.Path] && ran(t.Outcome) != "" {
+			d.Removed = append(d.Removed, Change{Test: Test{Path: t.Path, Specs: t.Specs, Group: t.Group}, Old: t.Outcome})
+		}
+	}
+
+	d.NewlyFailing = deepest(d.NewlyFailing)
+	d.NewlyPassing = deepest(d.NewlyPassing)
+	d.Added = highest(d.Added)
+	d.Removed = highest(d.Removed)
+	return d
+}
+
+func ran(outcome string) string {
+	if outcome == report.Skip {
+		return ""
+	}
+	return outcome
+}
+
+// deepest drops the changes with a subtest in changes.
+func deepest(changes []Change) []Change {
+	var kept []Change
+	for _, c := range changes {
+		if !slices.ContainsFunc(changes, func(o Change) bool { return strings.HasPrefix(o.Path, c.Path+"/") }) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// highest drops the changes with a parent test in changes.
+func highest(changes []Change) []Change {
+	var kept []Change
+	for _, c := range changes {
+		if !slices.ContainsFunc(changes, func(o Change) bool { return strings.HasPrefix(c.Path, o.Path+"/") }) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// NoSpec groups the changes of tests without spec nor group.
+const NoSpec = "Other"
+
+// Section groups the changes of a spec.
+type Section struct {
+	Spec    string
+	Changes []Change
+}
+
+// BySpec groups the changes by spec document, the URL of their first spec
+// without its fragment, in the order the specs first appear. Tests without
+// spec are grouped by test group.
+func BySpec(changes []Change) []Section {
+	var sections []Section
+	index := map[string]int{}
+	for _, c := range changes {
+		spec := NoSpec
+		switch {
+		case len(c.Specs) > 0:
+			spec, _, _ = strings.Cut(c.Specs[0], "#")
+		case c.Group != "":
+			spec = c.Group
+		}
+		i, ok := index[spec]
+		if !ok {
+			i = len(sections)
+			index[spec] = i
+			sections = append(sections, Section{Spec: spec})
+		}
+		sections[i].Changes = append(sections[i].Changes, c)
+	}
+	return sections
+}
+
+const markdownTemplate = `# Conformance Diff
+
+| Change | Tests |
+|---|---|
+| {{ if .NewlyFailing }}❌{{ else }}✅{{ end }} Newly failing | {{ len .NewlyFailing }} |
+| Newly passing | {{ len .NewlyPassing }} |
+| Added | {{ len .Added }} |
+| Removed | {{ len .Removed }} |
+{{ template "changes" (section "Newly failing" .NewlyFailing) }}
+{{- template "changes" (section "Newly passing" .NewlyPassing) }}
+{{- template "changes" (section "Added" .Added) }}
+{{- template "changes" (section "Removed" .Removed) }}
+{{- define "changes" }}{{ with .Changes }}
+## {{ $.Title }}
+{{ range bySpec . }}
+### {{ .Spec }}
+{{ range .Changes }}
+- ` + "`{{ .Path }}`" + `{{ .Note }}
+{{- end }}
+{{ end }}
+{{- end }}{{ end }}`
+
+var markdown = template.Must(template.New("markdown").Funcs(template.FuncMap{
+	"bySpec": BySpec,
+	"section": func(title string, changes []Change) map[string]interface{} {
+		return map[string]interface{}{"Title": title, "Changes": changes}
+	},
+}).Parse(markdownTemplate))
+
+// Note describes the outcome of an added or removed test.
+func (c Change) Note() string {
+	switch {
+	case c.Old == "":
+		return ": " + c.Outcome
+	case c.Outcome == "":
+		return " (was " + c.Old + ")"
+	}
+	return ""
+}
+
+// WriteMarkdown writes the diff as Markdown: a summary table, followed by the
+// changed tests grouped by spec.
+func WriteMarkdown(w io.Writer, d Diff) error {
+	return markdown.Execute(w, d)
+}
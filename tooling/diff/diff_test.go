@@ -0,0 +1,84 @@
+package diff
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling/results"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pathGateway = "https://specs.ipfs.tech/http-gateways/path-gateway/"
+
+func TestCompare(t *testing.T) {
+	old := Run{
+		{Path: "TestA", Outcome: "pass"},
+		{Path: "TestA/regressed", Outcome: "pass", Specs: []string{pathGateway + "#etag"}},
+		{Path: "TestA/regressed/Header_Etag", Outcome: "pass", Specs: []string{pathGateway + "#etag"}},
+		{Path: "TestA/fixed", Outcome: "fail", Group: "UnixFS"},
+		{Path: "TestA/skipped", Outcome: "pass"},
+		{Path: "TestB", Outcome: "pass"},
+		{Path: "TestB/removed", Outcome: "pass"},
+	}
+	new := Run{
+		{Path: "TestA", Outcome: "fail"},
+		{Path: "TestA/regressed", Outcome: "fail", Specs: []string{pathGateway + "#etag"}},
+		{Path: "TestA/regressed/Header_Etag", Outcome: "fail", Specs: []string{pathGateway + "#etag"}},
+		{Path: "TestA/fixed", Outcome: "pass", Group: "UnixFS"},
+		{Path: "TestA/skipped", Outcome: "skip"},
+		{Path: "TestC", Outcome: "fail"},
+		{Path: "TestC/added", Outcome: "fail"},
+	}
+
+	d := Compare(old, new)
+	assert.True(t, d.HasRegressions())
+	assert.Equal(t, []Change{{Test: new[2], Old: "pass"}}, d.NewlyFailing)
+	assert.Equal(t, []Change{{Test: new[3], Old: "fail"}}, d.NewlyPassing)
+	assert.Equal(t, []Change{{Test: new[5]}}, d.Added)
+	assert.Equal(t, []string{"TestA/skipped", "TestB"}, paths(d.Removed))
+
+	var md bytes.Buffer
+	require.NoError(t, WriteMarkdown(&md, d))
+	assert.Contains(t, md.String(), "| ❌ Newly failing | 1 |")
+	assert.Contains(t, md.String(), "### "+pathGateway+"\n\n- `TestA/regressed/Header_Etag`\n")
+	assert.Contains(t, md.String(), "### UnixFS\n\n- `TestA/fixed`\n")
+	assert.Contains(t, md.String(), "- `TestC`: fail\n")
+	assert.Contains(t, md.String(), "- `TestB` (was pass)\n")
+
+	assert.False(t, Compare(old, old).HasRegressions())
+}
+
+func paths(changes []Change) []string {
+	var paths []string
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	return paths
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+
+	events := filepath.Join(dir, "events.json")
+	require.NoError(t, os.WriteFile(events, []byte(
+		`{"Action":"run","Test":"TestA"}`+"\n"+
+			`{"Action":"output","Test":"TestA","Output":"    --- META: {\"group\":\"UnixFS\"}\n"}`+"\n"+
+			`{"Action":"fail","Test":"TestA"}`+"\n"), 0644))
+	run, err := Read(events)
+	require.NoError(t, err)
+	assert.Equal(t, Run{{Path: "TestA", Outcome: "fail", Group: "UnixFS"}}, run)
+
+	structured := filepath.Join(dir, "results.json")
+	f, err := os.Create(structured)
+	require.NoError(t, err)
+	require.NoError(t, results.Write(f, results.Report{Tests: []results.Test{
+		{Name: "a", Path: "TestA/a", Outcome: results.Pass, Specs: []string{pathGateway}},
+	}}))
+	require.NoError(t, f.Close())
+	run, err = Read(structured)
+	require.NoError(t, err)
+	assert.Equal(t, Run{{Path: "TestA/a", Outcome: "pass", Specs: []string{pathGateway}}}, run)
+}
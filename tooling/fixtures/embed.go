@@ -0,0 +1,126 @@
+package fixtures
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	embedded "github.com/ipfs/gateway-conformance/fixtures"
+)
+
+var (
+	extractOnce sync.Once
+	extractDir  string
+)
+
+// extracted returns a directory holding a copy of the fixtures embedded in the
+// binary. The copy lives in the user cache directory, keyed by the digest of
+// the fixtures, and is only written on first use.
+func extracted() string {
+	extractOnce.Do(func() {
+		var err error
+		extractDir, err = extract(embedded.FS)
+		if err != nil {
+			panic(err)
+		}
+	})
+	return extractDir
+}
+
+func extract(fsys fs.FS) (string, error) {
+	digest := sha256.New()
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		digest.Write([]byte(path))
+		digest.Write(data)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	name := "fixtures-" + hex.EncodeToString(digest.Sum(nil))[:16]
+
+	// Prefer the user cache directory, which survives across runs, and fall
+	// back to the temporary directory, e.g. in containers running as a user
+	// without a writable home.
+	var bases []string
+	if cache, err := os.UserCacheDir(); err == nil {
+		bases = append(bases, filepath.Join(cache, "gateway-conformance"))
+	}
+	bases = append(bases, filepath.Join(os.TempDir(), "gateway-conformance"))
+
+	for _, base := range bases {
+		var dir string
+		dir, err = extractTo(fsys, base, name)
+		if err == nil {
+			return dir, nil
+		}
+	}
+	return "", err
+}
+
+func extractTo(fsys fs.FS, base, name string) (string, error) {
+	dir := filepath.Join(base, name)
+	if _, err := os.Stat(dir); err == nil {
+		return dir, nil
+	}
+
+	err := os.MkdirAll(base, 0755)
+	if err != nil {
+		return "", err
+	}
+	// Write into a temporary directory first, so concurrent runs never observe
+	// a partially extracted copy.
+	tmp, err := os.MkdirTemp(base, ".fixtures-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(tmp, filepath.FromSlash(path))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		src, err := fsys.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		dst, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		defer dst.Close()
+		_, err = io.Copy(dst, src)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Rename(tmp, dir)
+	if err != nil {
+		// Another process may have won the race.
+		if _, statErr := os.Stat(dir); statErr == nil {
+			return dir, nil
+		}
+		return "", err
+	}
+
+	return dir, nil
+}
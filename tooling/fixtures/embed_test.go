@@ -0,0 +1,38 @@
+package fixtures
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	embedded "github.com/ipfs/gateway-conformance/fixtures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractMatchesSourceTree(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	dir, err := extract(embedded.FS)
+	require.NoError(t, err)
+
+	fxs, err := List()
+	require.NoError(t, err)
+
+	all := append(append(fxs.CarFiles, fxs.ConfigFiles...), fxs.IPNSRecords...)
+	require.NotEmpty(t, all)
+	for _, path := range all {
+		rel, err := filepath.Rel(Dir(), path)
+		require.NoError(t, err)
+
+		expected, err := os.ReadFile(path)
+		require.NoError(t, err)
+		actual, err := os.ReadFile(filepath.Join(dir, rel))
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual, rel)
+	}
+
+	again, err := extract(embedded.FS)
+	require.NoError(t, err)
+	assert.Equal(t, dir, again)
+}
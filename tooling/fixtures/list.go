@@ -4,13 +4,21 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/ipfs/gateway-conformance/tooling"
 )
 
+// Dir returns the directory holding the fixtures. When the source tree is not
+// available, e.g. when running a released binary, the fixtures embedded in the
+// binary are used instead.
 func Dir() string {
 	home := tooling.Home()
-	return path.Join(home, "fixtures")
+	dir := path.Join(home, "fixtures")
+	if _, err := os.Stat(dir); err == nil {
+		return dir
+	}
+	return extracted()
 }
 
 type Fixtures struct {
@@ -19,6 +27,14 @@ type Fixtures struct {
 	IPNSRecords []string
 }
 
+// IPNSName returns the IPNS name of the record fixture at path. Record files
+// are named after their IPNS name, followed by an optional "_" and a
+// description, e.g. k51...6103f_v2.ipns-record.
+func IPNSName(path string) string {
+	name, _, _ := strings.Cut(strings.TrimSuffix(filepath.Base(path), ".ipns-record"), "_")
+	return name
+}
+
 func List() (*Fixtures, error) {
 	var carFiles []string
 	var yamlFiles []string
@@ -0,0 +1,23 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling/car"
+	"github.com/ipfs/gateway-conformance/tooling/dnslink"
+	. "github.com/ipfs/gateway-conformance/tooling/tmpl"
+)
+
+var shared = car.MustOpenUnixfsCar("a/shared.car")
+
+func TestShared(t *testing.T) {
+	_ = shared
+	_ = car.MustOpenUnixfsCar(Fmt("a/{{name}}.car", "templated"))
+}
+
+func TestLocal(t *testing.T) {
+	// Shadows the package-level declaration.
+	shared := "k51qzi5uqu5dit2ku9mutlfgwyz8u730on38kd10m97m36bjt66my99hb6103f"
+	_ = shared
+	_ = dnslink.MustOpenDNSLink("a/missing.yml")
+}
@@ -0,0 +1,318 @@
+// Package manifest lists the fixtures with their integrity hashes and the
+// tests using them, and verifies the fixtures against this list, so that a
+// fixture changed by mistake, or no longer used, does not go unnoticed.
+package manifest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	carblockstore "github.com/ipld/go-car/v2/blockstore"
+
+	"github.com/ipfs/gateway-conformance/tooling/builder"
+	"github.com/ipfs/gateway-conformance/tooling/fixtures"
+	"github.com/ipfs/gateway-conformance/tooling/ipns"
+)
+
+// FileName is the name of the manifest, in the fixtures directory.
+const FileName = "manifest.json"
+
+// Kinds of fixtures.
+const (
+	CAR        = "car"
+	IPNSRecord = "ipns-record"
+	DNSLink    = "dnslink"
+)
+
+// Manifest lists every fixture, sorted by path.
+type Manifest struct {
+	Fixtures []Fixture `json:"fixtures"`
+}
+
+// Fixture describes a fixture file.
+type Fixture struct {
+	// Path is the path of the file in the fixtures directory, with forward
+	// slashes.
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	SHA256 string `json:"sha256"`
+	// Roots and Blocks list the CIDs of a CAR file. Roots are listed as
+	// found in the header, Blocks are sorted.
+	Roots  []string `json:"roots,omitempty"`
+	Blocks []string `json:"blocks,omitempty"`
+	// Description is the path of the description a CAR file is built from,
+	// see the generate-fixtures command. The CAR files without one are
+	// the exceptions, crafted by other means.
+	Description string `json:"description,omitempty"`
+	// Valid tells whether the signature of an IPNS record is valid, some
+	// records are invalid on purpose.
+	Valid *bool `json:"valid,omitempty"`
+	// Tests lists the top-level tests using the fixture, see References.
+	Tests []string `json:"tests"`
+}
+
+// Read parses the manifest at path.
+func Read(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Write writes the manifest as indented JSON.
+func Write(w io.Writer, m *Manifest) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// Generate describes the fixtures of fxs, found in dir, and the tests using
+// them according to refs.
+func Generate(dir string, fxs *fixtures.Fixtures, refs *References) (*Manifest, error) {
+	m := &Manifest{Fixtures: []Fixture{}}
+	for _, file := range all(fxs) {
+		f, err := describe(dir, file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Path, err)
+		}
+		f.Tests = refs.Tests(f.Path)
+		m.Fixtures = append(m.Fixtures, f)
+	}
+	sort.Slice(m.Fixtures, func(i, j int) bool { return m.Fixtures[i].Path < m.Fixtures[j].Path })
+	return m, nil
+}
+
+// Get returns the fixture at path.
+func (m *Manifest) Get(path string) (Fixture, bool) {
+	for _, f := range m.Fixtures {
+		if f.Path == path {
+			return f, true
+		}
+	}
+	return Fixture{}, false
+}
+
+// Paths returns the paths of the fixtures of fxs in dir, as listed in the
+// manifest.
+func Paths(dir string, fxs *fixtures.Fixtures) ([]string, error) {
+	var paths []string
+	for _, file := range all(fxs) {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	return paths, nil
+}
+
+func all(fxs *fixtures.Fixtures) []string {
+	return slices.Concat(fxs.CarFiles, fxs.IPNSRecords, fxs.ConfigFiles)
+}
+
+// describe reads the fixture at file, in dir. The blocks of CAR files are
+// hashed again, a block not matching its CID is an error.
+func describe(dir, file string) (Fixture, error) {
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return Fixture{}, err
+	}
+	f := Fixture{Path: filepath.ToSlash(rel)}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return f, err
+	}
+	sum := sha256.Sum256(data)
+	f.SHA256 = hex.EncodeToString(sum[:])
+
+	switch filepath.Ext(file) {
+	case ".car":
+		f.Kind = CAR
+		f.Roots, f.Blocks, err = readCAR(file)
+		if err != nil {
+			return f, err
+		}
+		description := strings.TrimSuffix(file, ".car") + builder.Extension
+		if _, err := os.Stat(description); err == nil {
+			f.Description = strings.TrimSuffix(f.Path, ".car") + builder.Extension
+		}
+	case ".ipns-record":
+		f.Kind = IPNSRecord
+		valid := validIPNSRecord(file) == nil
+		f.Valid = &valid
+	default:
+		f.Kind = DNSLink
+	}
+	return f, nil
+}
+
+// readCAR returns the roots and the sorted blocks of the CAR file at path,
+// and checks that every block matches its CID.
+func readCAR(path string) (roots, blocks []string, err error) {
+	bs, err := carblockstore.OpenReadOnly(path, carblockstore.UseWholeCIDs(true))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer bs.Close()
+
+	rootCids, err := bs.Roots()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, root := range rootCids {
+		roots = append(roots, root.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cids, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for c := range cids {
+		block, err := bs.Get(ctx, c)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := checkBlock(c, block.RawData()); err != nil {
+			return nil, nil, err
+		}
+		blocks = append(blocks, c.String())
+	}
+	sort.Strings(blocks)
+	return roots, blocks, nil
+}
+
+func checkBlock(c cid.Cid, data []byte) error {
+	sum, err := c.Prefix().Sum(data)
+	if err != nil {
+		return err
+	}
+	if !sum.Equals(c) {
+		return fmt.Errorf("block %s does not match its CID", c)
+	}
+	return nil
+}
+
+// validIPNSRecord checks the signature of the IPNS record at path, against
+// the name found in its file name.
+func validIPNSRecord(path string) error {
+	record, err := ipns.OpenIPNSRecordWithKey(path)
+	if err != nil {
+		return err
+	}
+	return record.Valid()
+}
+
+// Problem is a fixture that does not match the manifest.
+type Problem struct {
+	Path    string
+	Message string
+	// Warning is set for the fixtures that are fine, but no test uses.
+	Warning bool
+}
+
+func (p Problem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// Verify checks the fixtures of fxs, found in dir, against the manifest: their
+// hashes, the CIDs of the blocks of the CAR files and their descriptions, the
+// signatures of the IPNS records, and the tests using them. refs are the references found in the
+// source of the tests, they are only checked when not nil, e.g. when the
+// source is available.
+func Verify(dir string, fxs *fixtures.Fixtures, m *Manifest, refs *References) []Problem {
+	var problems []Problem
+	report := func(path, format string, args ...any) {
+		problems = append(problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	found := map[string]bool{}
+	for _, file := range all(fxs) {
+		got, err := describe(dir, file)
+		found[got.Path] = true
+		if err != nil {
+			report(got.Path, "%v", err)
+			continue
+		}
+
+		want, ok := m.Get(got.Path)
+		if !ok {
+			report(got.Path, "not in the manifest")
+			continue
+		}
+		if got.SHA256 != want.SHA256 {
+			report(got.Path, "sha256 is %s, the manifest has %s", got.SHA256, want.SHA256)
+		}
+		if !slices.Equal(got.Roots, want.Roots) {
+			report(got.Path, "roots are %v, the manifest has %v", got.Roots, want.Roots)
+		}
+		if !slices.Equal(got.Blocks, want.Blocks) {
+			report(got.Path, "has %d blocks, %d of them in the manifest", len(got.Blocks), countIn(got.Blocks, want.Blocks))
+		}
+		if got.Description != want.Description {
+			if got.Description == "" {
+				report(got.Path, "has no description, the manifest has %s", want.Description)
+			} else {
+				report(got.Path, "is described by %s, the manifest lists it without a description", got.Description)
+			}
+		}
+		if got.Valid != nil && (want.Valid == nil || *got.Valid != *want.Valid) {
+			if err := validIPNSRecord(file); err != nil {
+				report(got.Path, "the record is invalid, the manifest expects a valid one: %v", err)
+			} else {
+				report(got.Path, "the record is valid, the manifest expects an invalid one")
+			}
+		}
+
+		if len(want.Tests) == 0 {
+			problems = append(problems, Problem{Path: got.Path, Message: "unused, no test refers to it", Warning: true})
+		}
+		if refs != nil && !slices.Equal(refs.Tests(got.Path), want.Tests) {
+			report(got.Path, "used by %v, the manifest has %v", refs.Tests(got.Path), want.Tests)
+		}
+	}
+
+	for _, f := range m.Fixtures {
+		if !found[f.Path] {
+			report(f.Path, "missing, the manifest lists it")
+		}
+	}
+	if refs != nil {
+		paths := make([]string, 0, len(refs.Missing))
+		for path := range refs.Missing {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			report(path, "missing, %v opens it", refs.Missing[path])
+		}
+	}
+	return problems
+}
+
+func countIn(cids, set []string) int {
+	n := 0
+	for _, c := range cids {
+		if _, ok := slices.BinarySearch(set, c); ok {
+			n++
+		}
+	}
+	return n
+}
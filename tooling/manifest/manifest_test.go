@@ -0,0 +1,167 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	carblockstore "github.com/ipld/go-car/v2/blockstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ipfs/gateway-conformance/tooling/fixtures"
+)
+
+const ipnsName = "k51qzi5uqu5dit2ku9mutlfgwyz8u730on38kd10m97m36bjt66my99hb6103f"
+
+func TestFindReferences(t *testing.T) {
+	paths := []string{"a/shared.car", "a/templated.car", "b/unused.car", "b/" + ipnsName + "_v2.ipns-record"}
+	refs, err := FindReferences("./_fixtures/tests", paths)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]string{
+		"a/shared.car":                      {"TestShared"},
+		"a/templated.car":                   {"TestShared"},
+		"b/" + ipnsName + "_v2.ipns-record": {"TestLocal"},
+	}, refs.ByFixture)
+	assert.Equal(t, map[string][]string{"a/missing.yml": {"TestLocal"}}, refs.Missing)
+	assert.Equal(t, []string{}, refs.Tests("b/unused.car"))
+}
+
+// testFixtures copies a CAR file, an IPNS record and a DNSLink config to a
+// temporary directory.
+func testFixtures(t *testing.T) (string, *fixtures.Fixtures) {
+	dir := t.TempDir()
+	all, err := fixtures.List()
+	require.NoError(t, err)
+
+	fxs := &fixtures.Fixtures{}
+	cp := func(src string) string {
+		data, err := os.ReadFile(src)
+		require.NoError(t, err)
+		dst := filepath.Join(dir, filepath.Base(src))
+		require.NoError(t, os.WriteFile(dst, data, 0644))
+		return dst
+	}
+	fxs.CarFiles = []string{cp("../car/_fixtures/dag.car")}
+	for _, file := range all.IPNSRecords {
+		if fixtures.IPNSName(file) == ipnsName {
+			fxs.IPNSRecords = []string{cp(file)}
+		}
+	}
+	fxs.ConfigFiles = []string{cp(all.ConfigFiles[0])}
+	require.Len(t, fxs.IPNSRecords, 1)
+	return dir, fxs
+}
+
+func TestVerify(t *testing.T) {
+	dir, fxs := testFixtures(t)
+	paths, err := Paths(dir, fxs)
+	require.NoError(t, err)
+	refs := &References{ByFixture: map[string][]string{}}
+	for _, path := range paths {
+		refs.ByFixture[path] = []string{"TestSomething"}
+	}
+
+	m, err := Generate(dir, fxs, refs)
+	require.NoError(t, err)
+	require.Len(t, m.Fixtures, 3)
+	car, ok := m.Get("dag.car")
+	require.True(t, ok)
+	assert.Equal(t, []string{"bafybeidlbwbu73tbjr3atntjz4lq5ego5w2uyof35vvwcnheaftzi3rndu"}, car.Roots)
+	assert.Len(t, car.Blocks, 5)
+	assert.Empty(t, car.Description)
+	record, ok := m.Get(filepath.Base(fxs.IPNSRecords[0]))
+	require.True(t, ok)
+	assert.True(t, *record.Valid)
+
+	assert.Empty(t, Verify(dir, fxs, m, refs))
+
+	t.Run("unused and stale references", func(t *testing.T) {
+		refs := &References{
+			ByFixture: map[string][]string{"dag.car": {"TestOther"}},
+			Missing:   map[string][]string{"gone.car": {"TestOther"}},
+		}
+		m, err := Generate(dir, fxs, refs)
+		require.NoError(t, err)
+		refs.ByFixture["dag.car"] = []string{"TestSomething"}
+
+		var warnings, errors []string
+		for _, p := range Verify(dir, fxs, m, refs) {
+			if p.Warning {
+				warnings = append(warnings, p.String())
+			} else {
+				errors = append(errors, p.String())
+			}
+		}
+		assert.Len(t, warnings, 2)
+		assert.Equal(t, []string{
+			"dag.car: used by [TestSomething], the manifest has [TestOther]",
+			"gone.car: missing, [TestOther] opens it",
+		}, errors)
+	})
+
+	t.Run("descriptions", func(t *testing.T) {
+		dir, fxs := testFixtures(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "dag.car.yaml"), nil, 0644))
+
+		described, err := Generate(dir, fxs, refs)
+		require.NoError(t, err)
+		car, ok := described.Get("dag.car")
+		require.True(t, ok)
+		assert.Equal(t, "dag.car.yaml", car.Description)
+		assert.Empty(t, Verify(dir, fxs, described, refs))
+
+		var messages []string
+		for _, p := range Verify(dir, fxs, m, refs) {
+			messages = append(messages, p.String())
+		}
+		assert.Equal(t, []string{"dag.car: is described by dag.car.yaml, the manifest lists it without a description"}, messages)
+	})
+
+	t.Run("changed fixtures", func(t *testing.T) {
+		dir, fxs := testFixtures(t)
+
+		// Corrupts a block of the CAR file.
+		bs, err := carblockstore.OpenReadOnly(fxs.CarFiles[0])
+		require.NoError(t, err)
+		leaf, err := bs.Get(context.Background(), cid.MustParse("bafkreidw23elffhagxz3oi6ctoibqouzfowfn3bwcvq2yzgd5n5h4gjyou"))
+		require.NoError(t, err)
+		bs.Close()
+		data, err := os.ReadFile(fxs.CarFiles[0])
+		require.NoError(t, err)
+		i := bytes.Index(data, leaf.RawData())
+		require.Positive(t, i)
+		data[i] ^= 0xff
+		require.NoError(t, os.WriteFile(fxs.CarFiles[0], data, 0644))
+
+		// Replaces the IPNS record with the record of another name, its
+		// signature no longer matches its name.
+		all, err := fixtures.List()
+		require.NoError(t, err)
+		for _, file := range all.IPNSRecords {
+			if fixtures.IPNSName(file) == "k51qzi5uqu5dlkw8pxuw9qmqayfdeh4kfebhmreauqdc6a7c3y7d5i9fi8mk9w" {
+				other, err := os.ReadFile(file)
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(fxs.IPNSRecords[0], other, 0644))
+			}
+		}
+
+		config := filepath.Base(fxs.ConfigFiles[0])
+		require.NoError(t, os.Remove(fxs.ConfigFiles[0]))
+		fxs.ConfigFiles = nil
+
+		var messages []string
+		for _, p := range Verify(dir, fxs, m, refs) {
+			messages = append(messages, p.String())
+		}
+		require.Len(t, messages, 4, messages)
+		assert.Equal(t, "dag.car: block bafkreidw23elffhagxz3oi6ctoibqouzfowfn3bwcvq2yzgd5n5h4gjyou does not match its CID", messages[0])
+		assert.Contains(t, messages[1], record.Path+": sha256 is ")
+		assert.Contains(t, messages[2], record.Path+": the record is invalid, the manifest expects a valid one")
+		assert.Equal(t, config+": missing, the manifest lists it", messages[3])
+	})
+}
@@ -0,0 +1,313 @@
+package manifest
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/gateway-conformance/tooling/fixtures"
+)
+
+// References maps the fixtures to the tests using them, found in the source
+// of the tests.
+type References struct {
+	// ByFixture maps the path of every fixture to the sorted names of the
+	// top-level tests using it.
+	ByFixture map[string][]string
+	// Missing maps the fixture paths opened by tests, which match no
+	// fixture, to the sorted names of these tests.
+	Missing map[string][]string
+}
+
+// Tests returns the tests using the fixture at path, never nil.
+func (r *References) Tests(path string) []string {
+	if r == nil || r.ByFixture[path] == nil {
+		return []string{}
+	}
+	return r.ByFixture[path]
+}
+
+// openers are the functions opening a fixture from its path, given as their
+// first argument.
+var openers = []string{"MustOpenUnixfsCar", "MustOpenDNSLink", "MustOpenIPNSRecordWithKey"}
+
+// FindReferences parses the Go files of the tests in testsDir, and finds the
+// fixtures, with the given paths in the fixtures directory, that every
+// top-level test uses. A test uses a fixture when it, or a package-level
+// declaration it refers to, opens the fixture or holds its path or, for IPNS
+// records, its IPNS name in a string literal. Paths opened with
+// tmpl.Fmt are matched as patterns, e.g. "dag-{{format}}-traversal.car".
+func FindReferences(testsDir string, paths []string) (*References, error) {
+	fset := token.NewFileSet()
+	files, err := filepath.Glob(filepath.Join(testsDir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The fixtures and the declarations used by every package-level
+	// declaration.
+	uses := map[string]*declUses{}
+	var tests []string
+	missing := map[string]map[string]bool{}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		// Identifiers are resolved within the file, to tell the local
+		// variables from the package-level declarations.
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		topLevel := map[any]bool{}
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				topLevel[decl] = true
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					topLevel[spec] = true
+				}
+			}
+		}
+		for _, decl := range f.Decls {
+			for _, name := range declNames(decl) {
+				u := &declUses{fixtures: map[string]bool{}, decls: map[string]bool{}}
+				u.scan(decl, paths, topLevel)
+				uses[name] = u
+				for _, m := range u.missing {
+					if missing[m] == nil {
+						missing[m] = map[string]bool{}
+					}
+					missing[m][name] = true
+				}
+			}
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && strings.HasPrefix(fn.Name.Name, "Test") {
+				tests = append(tests, fn.Name.Name)
+			}
+		}
+	}
+
+	refs := &References{ByFixture: map[string][]string{}, Missing: map[string][]string{}}
+	for _, test := range tests {
+		for path := range closure(uses, test) {
+			refs.ByFixture[path] = append(refs.ByFixture[path], test)
+		}
+	}
+	for path := range refs.ByFixture {
+		sort.Strings(refs.ByFixture[path])
+	}
+
+	for path, decls := range missing {
+		seen := map[string]bool{}
+		for _, test := range tests {
+			for decl := range decls {
+				if reaches(uses, test, decl) && !seen[test] {
+					seen[test] = true
+					refs.Missing[path] = append(refs.Missing[path], test)
+				}
+			}
+		}
+		sort.Strings(refs.Missing[path])
+	}
+	return refs, nil
+}
+
+type declUses struct {
+	fixtures map[string]bool
+	// decls are the identifiers the declaration refers to, that are not
+	// local declarations. Some are not package-level declarations either,
+	// e.g. the imported packages.
+	decls   map[string]bool
+	missing []string
+}
+
+// scan finds the fixtures and the declarations used by decl. topLevel holds
+// the package-level declarations of its file.
+func (u *declUses) scan(decl ast.Decl, paths []string, topLevel map[any]bool) {
+	var inspect func(n ast.Node) bool
+	inspect = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			// The selected name is a field or a method, never a
+			// package-level declaration.
+			ast.Inspect(n.X, inspect)
+			return false
+		case *ast.Ident:
+			// Identifiers declared in another file are not
+			// resolved.
+			if n.Obj == nil || topLevel[n.Obj.Decl] {
+				u.decls[n.Name] = true
+			}
+		case *ast.BasicLit:
+			if s, ok := stringLit(n); ok {
+				for _, path := range literalFixtures(s, paths) {
+					u.fixtures[path] = true
+				}
+			}
+		case *ast.CallExpr:
+			if pattern, ok := openedPath(n); ok {
+				matched := matchPattern(pattern, paths)
+				for _, path := range matched {
+					u.fixtures[path] = true
+				}
+				if len(matched) == 0 {
+					u.missing = append(u.missing, pattern)
+				}
+			}
+		}
+		return true
+	}
+	ast.Inspect(decl, inspect)
+}
+
+// closure returns the fixtures used by the declaration name, and the
+// declarations it refers to.
+func closure(uses map[string]*declUses, name string) map[string]bool {
+	found := map[string]bool{}
+	visited := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		u, ok := uses[name]
+		if !ok || visited[name] {
+			return
+		}
+		visited[name] = true
+		for path := range u.fixtures {
+			found[path] = true
+		}
+		for decl := range u.decls {
+			visit(decl)
+		}
+	}
+	visit(name)
+	return found
+}
+
+// reaches reports whether the declaration from refers to the declaration to,
+// directly or not.
+func reaches(uses map[string]*declUses, from, to string) bool {
+	visited := map[string]bool{}
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		if name == to {
+			return true
+		}
+		u, ok := uses[name]
+		if !ok || visited[name] {
+			return false
+		}
+		visited[name] = true
+		for decl := range u.decls {
+			if visit(decl) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(from)
+}
+
+func declNames(decl ast.Decl) []string {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Recv == nil {
+			return []string{decl.Name.Name}
+		}
+	case *ast.GenDecl:
+		var names []string
+		for _, spec := range decl.Specs {
+			if vs, ok := spec.(*ast.ValueSpec); ok {
+				for _, name := range vs.Names {
+					names = append(names, name.Name)
+				}
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+func stringLit(n ast.Expr) (string, bool) {
+	lit, ok := n.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// openedPath returns the path, or the tmpl.Fmt pattern, of the fixture opened
+// by call, when it is a call to one of the openers with a literal path.
+func openedPath(call *ast.CallExpr) (string, bool) {
+	if !slices.Contains(openers, funcName(call)) || len(call.Args) == 0 {
+		return "", false
+	}
+	if s, ok := stringLit(call.Args[0]); ok {
+		return s, true
+	}
+	if fmtCall, ok := call.Args[0].(*ast.CallExpr); ok && funcName(fmtCall) == "Fmt" && len(fmtCall.Args) > 0 {
+		return stringLit(fmtCall.Args[0])
+	}
+	return "", false
+}
+
+func funcName(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		return fun.Sel.Name
+	}
+	return ""
+}
+
+var placeholder = regexp.MustCompile(`{{[^}]*}}`)
+
+// matchPattern returns the paths matching pattern, where every tmpl.Fmt
+// placeholder matches any part of a file name.
+func matchPattern(pattern string, paths []string) []string {
+	var expr strings.Builder
+	expr.WriteString("^")
+	last := 0
+	for _, loc := range placeholder.FindAllStringIndex(pattern, -1) {
+		expr.WriteString(regexp.QuoteMeta(pattern[last:loc[0]]))
+		expr.WriteString("[^/]+")
+		last = loc[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(pattern[last:]))
+	expr.WriteString("$")
+	re := regexp.MustCompile(expr.String())
+
+	var matched []string
+	for _, path := range paths {
+		if re.MatchString(path) {
+			matched = append(matched, path)
+		}
+	}
+	return matched
+}
+
+// literalFixtures returns the fixtures a string literal refers to, by path,
+// or by IPNS name for IPNS records.
+func literalFixtures(s string, paths []string) []string {
+	var found []string
+	for _, path := range paths {
+		if s == path {
+			found = append(found, path)
+			continue
+		}
+		if filepath.Ext(path) == ".ipns-record" {
+			if name := fixtures.IPNSName(path); len(name) > 0 && strings.Contains(s, name) {
+				found = append(found, path)
+			}
+		}
+	}
+	return found
+}
@@ -2,9 +2,17 @@ package tooling
 
 import (
 	"encoding/json"
+	"strings"
+	"sync"
 	"testing"
 )
 
+// groups maps top-level test names to the group they logged.
+var groups sync.Map
+
+// specs maps top-level test names to the specs they logged.
+var specs sync.Map
+
 func LogMetadata(t *testing.T, value interface{}) {
 	t.Helper()
 
@@ -19,6 +27,8 @@ func LogMetadata(t *testing.T, value interface{}) {
 func LogTestGroup(t *testing.T, name string) {
 	t.Helper()
 
+	groups.Store(t.Name(), name)
+
 	LogMetadata(t, struct {
 		Group string `json:"group"`
 	}{
@@ -26,6 +36,15 @@ func LogTestGroup(t *testing.T, name string) {
 	})
 }
 
+// TestGroup returns the group logged by the top-level test of the test with
+// the given full name.
+func TestGroup(name string) string {
+	top, _, _ := strings.Cut(name, "/")
+	group, _ := groups.Load(top)
+	g, _ := group.(string)
+	return g
+}
+
 func LogVersion(t *testing.T) {
 	LogMetadata(t, struct {
 		Version string `json:"version"`
@@ -42,14 +61,39 @@ func LogJobURL(t *testing.T) {
 	})
 }
 
-func LogSpecs(t *testing.T, specs ...string) {
-	if len(specs) == 0 {
+func LogSpecs(t *testing.T, urls ...string) {
+	if len(urls) == 0 {
 		return
 	}
 
+	if !strings.Contains(t.Name(), "/") {
+		specs.Store(t.Name(), urls)
+	}
+
 	LogMetadata(t, struct {
 		Specs []string `json:"specs"`
 	}{
-		Specs: specs,
+		Specs: urls,
+	})
+}
+
+// TestSpecs returns the specs logged by the top-level test of the test with
+// the given full name.
+func TestSpecs(name string) []string {
+	top, _, _ := strings.Cut(name, "/")
+	urls, _ := specs.Load(top)
+	s, _ := urls.([]string)
+	return s
+}
+
+func LogHint(t *testing.T, hint string) {
+	if hint == "" {
+		return
+	}
+
+	LogMetadata(t, struct {
+		Hint string `json:"hint"`
+	}{
+		Hint: hint,
 	})
 }
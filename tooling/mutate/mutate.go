@@ -0,0 +1,183 @@
+// Package mutate injects faults in the responses of a gateway, to check that
+// the conformance tests catch them. A mutation that does not make any test
+// fail points to checks that are too weak.
+package mutate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Header is set on every response changed by a mutation, to the name of the
+// mutation, so that tests that received a mutated response can be found in
+// the results.
+const Header = "X-Conformance-Mutation"
+
+// Mutation changes a response of the upstream gateway.
+type Mutation struct {
+	Name        string
+	Description string
+	// Apply changes res in place and reports whether it did change it.
+	Apply func(res *http.Response) (bool, error)
+}
+
+// All lists the mutations applied by default.
+var All = []Mutation{
+	DropHeader("Cache-Control"),
+	DropHeader("X-Ipfs-Path"),
+	DropHeader("X-Ipfs-Roots"),
+	{
+		Name:        "strip-etag",
+		Description: "Removes the Etag header.",
+		Apply:       dropHeader("Etag"),
+	},
+	{
+		Name:        "wrong-status",
+		Description: "Replaces the status code: 200 becomes 203, any other status becomes 200.",
+		Apply:       wrongStatus,
+	},
+	{
+		Name:        "flip-byte",
+		Description: "Inverts the bits of the byte in the middle of the body.",
+		Apply:       body(flipByte),
+	},
+	{
+		Name:        "truncate-body",
+		Description: "Drops the second half of the body.",
+		Apply:       body(truncate),
+	},
+	{
+		Name:        "reorder-car",
+		Description: "Reverses the order of the blocks of CAR responses.",
+		Apply:       body(reorderCAR),
+	},
+}
+
+// Get returns the mutation with the given name. Besides the mutations in All,
+// "drop-header:NAME" drops any header.
+func Get(name string) (Mutation, error) {
+	for _, m := range All {
+		if m.Name == name {
+			return m, nil
+		}
+	}
+	if header, ok := strings.CutPrefix(name, "drop-header:"); ok && header != "" {
+		return DropHeader(header), nil
+	}
+	return Mutation{}, fmt.Errorf("unknown mutation %q", name)
+}
+
+// Names returns the names of the mutations in All.
+func Names() []string {
+	var names []string
+	for _, m := range All {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+// DropHeader returns a mutation removing the given header.
+func DropHeader(header string) Mutation {
+	header = http.CanonicalHeaderKey(header)
+	return Mutation{
+		Name:        "drop-header:" + header,
+		Description: fmt.Sprintf("Removes the %s header.", header),
+		Apply:       dropHeader(header),
+	}
+}
+
+func dropHeader(header string) func(res *http.Response) (bool, error) {
+	return func(res *http.Response) (bool, error) {
+		if _, ok := res.Header[http.CanonicalHeaderKey(header)]; !ok {
+			return false, nil
+		}
+		res.Header.Del(header)
+		return true, nil
+	}
+}
+
+func wrongStatus(res *http.Response) (bool, error) {
+	if res.StatusCode == http.StatusOK {
+		res.StatusCode = http.StatusNonAuthoritativeInfo
+	} else {
+		res.StatusCode = http.StatusOK
+	}
+	res.Status = fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode))
+	return true, nil
+}
+
+// body turns a function changing a body into a mutation. The function
+// returns nil when it does not apply to the body.
+func body(mutate func(res *http.Response, body []byte) []byte) func(res *http.Response) (bool, error) {
+	return func(res *http.Response) (bool, error) {
+		if res.Body == nil || res.Body == http.NoBody || res.Request != nil && res.Request.Method == http.MethodHead {
+			return false, nil
+		}
+
+		data, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return false, err
+		}
+
+		mutated := mutate(res, data)
+		if mutated == nil {
+			mutated = data
+		}
+		res.Body = io.NopCloser(bytes.NewReader(mutated))
+		res.ContentLength = int64(len(mutated))
+		res.Header.Set("Content-Length", strconv.Itoa(len(mutated)))
+		// The body is sent in one piece, with its new length.
+		res.TransferEncoding = nil
+		return !bytes.Equal(data, mutated), nil
+	}
+}
+
+func flipByte(_ *http.Response, body []byte) []byte {
+	if len(body) == 0 {
+		return nil
+	}
+	mutated := bytes.Clone(body)
+	mutated[len(mutated)/2] ^= 0xff
+	return mutated
+}
+
+func truncate(_ *http.Response, body []byte) []byte {
+	if len(body) == 0 {
+		return nil
+	}
+	return body[:len(body)/2]
+}
+
+func reorderCAR(res *http.Response, body []byte) []byte {
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "application/vnd.ipld.car") {
+		return nil
+	}
+
+	// A CARv1 stream is a header followed by blocks, each prefixed with
+	// its length as a varint.
+	var sections [][]byte
+	for rest := body; len(rest) > 0; {
+		size, n := binary.Uvarint(rest)
+		if n <= 0 || uint64(len(rest)-n) < size {
+			return nil
+		}
+		sections = append(sections, rest[:n+int(size)])
+		rest = rest[n+int(size):]
+	}
+	if len(sections) < 3 {
+		return nil
+	}
+
+	mutated := bytes.NewBuffer(make([]byte, 0, len(body)))
+	mutated.Write(sections[0])
+	for i := len(sections) - 1; i > 0; i-- {
+		mutated.Write(sections[i])
+	}
+	return mutated.Bytes()
+}
@@ -0,0 +1,246 @@
+package mutate
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling"
+	"github.com/ipfs/gateway-conformance/tooling/results"
+	"github.com/ipfs/go-cid"
+	carv2 "github.com/ipld/go-car/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func response(contentType string, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": {contentType}, "Etag": {`"etag"`}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
+
+func apply(t *testing.T, name string, res *http.Response) (bool, []byte) {
+	m, err := Get(name)
+	require.NoError(t, err)
+	applied, err := m.Apply(res)
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return applied, body
+}
+
+func blockCids(t *testing.T, car []byte) []cid.Cid {
+	r, err := carv2.NewBlockReader(bytes.NewReader(car))
+	require.NoError(t, err)
+	var cids []cid.Cid
+	for {
+		block, err := r.Next()
+		if err == io.EOF {
+			return cids
+		}
+		require.NoError(t, err)
+		cids = append(cids, block.Cid())
+	}
+}
+
+func TestMutations(t *testing.T) {
+	car, err := os.ReadFile(filepath.Join(tooling.Home(), "fixtures", "trustless_gateway_car", "subdir-with-two-single-block-files.car"))
+	require.NoError(t, err)
+
+	t.Run("strip-etag", func(t *testing.T) {
+		res := response("text/plain", []byte("hello"))
+		applied, body := apply(t, "strip-etag", res)
+		assert.True(t, applied)
+		assert.Empty(t, res.Header.Get("Etag"))
+		assert.Equal(t, "hello", string(body))
+	})
+
+	t.Run("drop-header", func(t *testing.T) {
+		res := response("text/plain", []byte("hello"))
+		applied, _ := apply(t, "drop-header:content-type", res)
+		assert.True(t, applied)
+		assert.Empty(t, res.Header.Get("Content-Type"))
+
+		applied, _ = apply(t, "drop-header:X-Missing", response("text/plain", nil))
+		assert.False(t, applied)
+	})
+
+	t.Run("wrong-status", func(t *testing.T) {
+		res := response("text/plain", []byte("hello"))
+		applied, _ := apply(t, "wrong-status", res)
+		assert.True(t, applied)
+		assert.Equal(t, http.StatusNonAuthoritativeInfo, res.StatusCode)
+	})
+
+	t.Run("flip-byte", func(t *testing.T) {
+		res := response("text/plain", []byte("hello"))
+		applied, body := apply(t, "flip-byte", res)
+		assert.True(t, applied)
+		assert.Equal(t, []byte{'h', 'e', 'l' ^ 0xff, 'l', 'o'}, body)
+		assert.Equal(t, "5", res.Header.Get("Content-Length"))
+
+		applied, _ = apply(t, "flip-byte", response("text/plain", nil))
+		assert.False(t, applied)
+	})
+
+	t.Run("truncate-body", func(t *testing.T) {
+		res := response("text/plain", []byte("hello"))
+		applied, body := apply(t, "truncate-body", res)
+		assert.True(t, applied)
+		assert.Equal(t, "he", string(body))
+		assert.Equal(t, int64(2), res.ContentLength)
+	})
+
+	t.Run("reorder-car", func(t *testing.T) {
+		res := response("application/vnd.ipld.car; version=1", car)
+		applied, body := apply(t, "reorder-car", res)
+		assert.True(t, applied)
+
+		expected := blockCids(t, car)
+		slices.Reverse(expected)
+		assert.Equal(t, expected, blockCids(t, body))
+
+		applied, _ = apply(t, "reorder-car", response("application/octet-stream", car))
+		assert.False(t, applied)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := Get("unknown")
+		assert.Error(t, err)
+	})
+}
+
+func TestProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Etag", `"etag"`)
+		w.Header().Set("X-Host", r.Host)
+		// The proxy must not add a Content-Type upstream left out.
+		w.Header()["Content-Type"] = nil
+		w.Write([]byte("hello " + r.URL.Path))
+	}))
+	defer upstream.Close()
+	u, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	m, err := Get("flip-byte")
+	require.NoError(t, err)
+	proxy := httptest.NewServer(NewProxy(u, m))
+	defer proxy.Close()
+
+	req, err := http.NewRequest(http.MethodGet, proxy.URL+"/ipfs/cid", nil)
+	require.NoError(t, err)
+	req.Host = "cid.ipfs.example.com"
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, "cid.ipfs.example.com", res.Header.Get("X-Host"))
+	assert.Equal(t, "flip-byte", res.Header.Get(Header))
+	assert.Equal(t, `"etag"`, res.Header.Get("Etag"))
+	assert.Empty(t, res.Header.Get("Content-Type"))
+	assert.Len(t, body, len("hello /ipfs/cid"))
+	assert.NotEqual(t, "hello /ipfs/cid", string(body))
+}
+
+func TestProxyTunnel(t *testing.T) {
+	hello := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Host", r.Host)
+		w.Write([]byte("hello " + r.URL.Path))
+	})
+	// upstream serves the requests sent through a tunnel itself, as
+	// gateways acting as HTTP proxies do.
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			hello.ServeHTTP(w, r)
+			return
+		}
+		if r.Host == "refused.example.com:80" {
+			http.Error(w, "no tunnel", http.StatusForbidden)
+			return
+		}
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		_ = (&http.Server{Handler: hello}).Serve(&connListener{conn: conn})
+	}))
+	defer upstream.Close()
+	u, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	m, err := Get("flip-byte")
+	require.NoError(t, err)
+	proxy := httptest.NewServer(NewProxy(u, m))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	conn, res, err := connect(context.Background(), proxyURL, "example.com:80")
+	require.NoError(t, err)
+	require.Nil(t, res)
+	defer conn.Close()
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(context.Context, string, string) (net.Conn, error) { return conn, nil },
+	}}
+
+	for _, path := range []string{"/ipfs/a", "/ipfs/b"} {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com"+path, nil)
+		require.NoError(t, err)
+		req.Host = "cid.ipfs.example.com"
+		res, err := client.Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		require.NoError(t, err)
+
+		assert.Equal(t, "cid.ipfs.example.com", res.Header.Get("X-Host"))
+		assert.Equal(t, "flip-byte", res.Header.Get(Header))
+		assert.Len(t, body, len("hello "+path))
+		assert.NotEqual(t, "hello "+path, string(body))
+	}
+
+	_, res, err = connect(context.Background(), proxyURL, "refused.example.com:80")
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+}
+
+func TestCompare(t *testing.T) {
+	mutated := results.Response{Headers: http.Header{Header: {"strip-etag"}}}
+	baseline := &results.Report{Tests: []results.Test{
+		{Path: "TestA/caught", Outcome: results.Pass},
+		{Path: "TestA/missed", Outcome: results.Pass},
+		{Path: "TestA/untouched", Outcome: results.Pass},
+		{Path: "TestA/failing", Outcome: results.Fail},
+	}}
+	run := &results.Report{Tests: []results.Test{
+		{Path: "TestA/caught", Outcome: results.Fail},
+		{Path: "TestA/missed", Outcome: results.Pass, Exchanges: []results.Exchange{{Response: &mutated}}},
+		{Path: "TestA/untouched", Outcome: results.Pass, Exchanges: []results.Exchange{{Response: &results.Response{}}}},
+		{Path: "TestA/failing", Outcome: results.Fail},
+	}}
+
+	o := Compare(Mutation{Name: "strip-etag"}, baseline, run)
+	assert.Equal(t, []string{"TestA/caught"}, o.Caught)
+	assert.Equal(t, []string{"TestA/missed"}, o.Missed)
+
+	var md bytes.Buffer
+	require.NoError(t, WriteMarkdown(&md, []Outcome{o}))
+	assert.Contains(t, md.String(), "| ✅ strip-etag | 1 | 1 |")
+	assert.Contains(t, md.String(), "- TestA/missed")
+}
@@ -0,0 +1,229 @@
+package mutate
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+)
+
+// NewProxy returns a reverse proxy to upstream applying the mutation to every
+// response. The Host header of the requests is preserved, so that subdomain
+// and DNSLink requests reach upstream unchanged.
+//
+// CONNECT requests open the tunnel on upstream, the requests sent through
+// the tunnel are then proxied over it and their responses mutated as well.
+func NewProxy(upstream *url.URL, m Mutation) http.Handler {
+	proxy := newReverseProxy(m, func(r *httputil.ProxyRequest) {
+		r.SetURL(upstream)
+		r.Out.Host = r.In.Host
+	}, nil)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodConnect {
+			tunnel(w, r, upstream, m)
+			return
+		}
+		proxy.ServeHTTP(noSniffWriter{w}, r)
+	})
+}
+
+func newReverseProxy(m Mutation, rewrite func(*httputil.ProxyRequest), transport http.RoundTripper) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite:   rewrite,
+		Transport: transport,
+		ModifyResponse: func(res *http.Response) error {
+			if m.Apply == nil {
+				return nil
+			}
+			applied, err := m.Apply(res)
+			if err != nil {
+				return err
+			}
+			if applied {
+				res.Header.Set(Header, m.Name)
+			}
+			return nil
+		},
+	}
+}
+
+// tunnel serves a CONNECT request. The tunnel is opened on upstream first,
+// its refusal is passed on to the client as is.
+func tunnel(w http.ResponseWriter, r *http.Request, upstream *url.URL, m Mutation) {
+	target := r.Host
+	first, res, err := connect(r.Context(), upstream, target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if res != nil {
+		defer res.Body.Close()
+		for k, v := range res.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(res.StatusCode)
+		io.Copy(w, res.Body)
+		return
+	}
+
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		first.Close()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		first.Close()
+		conn.Close()
+		return
+	}
+
+	// The requests are sent over the tunnel opened above, and over new
+	// tunnels to the same target when the transport needs more
+	// connections.
+	var used sync.Once
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var reused net.Conn
+			used.Do(func() { reused = first })
+			if reused != nil {
+				return reused, nil
+			}
+			conn, res, err := connect(ctx, upstream, target)
+			if err != nil {
+				return nil, err
+			}
+			if res != nil {
+				res.Body.Close()
+				return nil, fmt.Errorf("upstream refused the tunnel to %s: %s", target, res.Status)
+			}
+			return conn, nil
+		},
+	}
+	defer transport.CloseIdleConnections()
+	defer used.Do(func() { first.Close() })
+
+	proxy := newReverseProxy(m, func(r *httputil.ProxyRequest) {
+		// The requests are sent in clear through the tunnel, as the
+		// client sent them.
+		r.Out.URL.Scheme = "http"
+		r.Out.URL.Host = target
+		r.Out.Host = r.In.Host
+	}, transport)
+
+	closed := make(chan struct{})
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxy.ServeHTTP(noSniffWriter{w}, r)
+		}),
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			if state == http.StateClosed {
+				close(closed)
+			}
+		},
+	}
+	// Serve returns once the listener is exhausted, the connection itself
+	// is served until the client closes it.
+	_ = srv.Serve(&connListener{conn: conn})
+	<-closed
+}
+
+// connect opens a tunnel to target on upstream. The response of upstream is
+// returned, with a nil connection, when it refuses the tunnel.
+func connect(ctx context.Context, upstream *url.URL, target string) (net.Conn, *http.Response, error) {
+	var dialer interface {
+		DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+	} = &net.Dialer{}
+	addr := upstream.Host
+	if upstream.Scheme == "https" {
+		dialer = &tls.Dialer{Config: &tls.Config{ServerName: upstream.Hostname()}}
+		if upstream.Port() == "" {
+			addr = net.JoinHostPort(upstream.Hostname(), "443")
+		}
+	} else if upstream.Port() == "" {
+		addr = net.JoinHostPort(upstream.Hostname(), "80")
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: target},
+		Host:   target,
+		Header: make(http.Header),
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{res.Body, conn}
+		return nil, res, nil
+	}
+	return &bufferedConn{Conn: conn, r: br}, nil, nil
+}
+
+// bufferedConn reads what the reader of the CONNECT response buffered before
+// reading from the connection.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// connListener is a net.Listener accepting a single connection.
+type connListener struct {
+	conn net.Conn
+	once sync.Once
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	var conn net.Conn
+	l.once.Do(func() { conn = l.conn })
+	if conn == nil {
+		return nil, net.ErrClosed
+	}
+	return conn, nil
+}
+
+func (l *connListener) Close() error   { return nil }
+func (l *connListener) Addr() net.Addr { return l.conn.LocalAddr() }
+
+// noSniffWriter prevents net/http from setting a Content-Type the upstream
+// response, or the mutation, left out.
+type noSniffWriter struct {
+	http.ResponseWriter
+}
+
+func (w noSniffWriter) WriteHeader(code int) {
+	if _, ok := w.Header()["Content-Type"]; !ok {
+		w.Header()["Content-Type"] = nil
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w noSniffWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,92 @@
+package mutate
+
+import (
+	"io"
+	"text/template"
+
+	"github.com/ipfs/gateway-conformance/tooling/results"
+)
+
+// Outcome is the effect of a mutation on the tests passing without it.
+type Outcome struct {
+	Mutation Mutation
+	// Caught lists the tests that fail with the mutation.
+	Caught []string
+	// Missed lists the tests that still pass even though they received
+	// a mutated response, their checks are likely too weak.
+	Missed []string
+	// Err is set when the suite could not be run with the mutation.
+	Err error
+}
+
+// Compare returns the outcome of the mutation, given the results of a run
+// without it and of a run with it. Tests are identified by their path.
+func Compare(m Mutation, baseline, mutated *results.Report) Outcome {
+	passed := map[string]bool{}
+	for _, t := range baseline.Tests {
+		passed[t.Path] = t.Outcome == results.Pass
+	}
+
+	o := Outcome{Mutation: m}
+	for _, t := range mutated.Tests {
+		if !passed[t.Path] {
+			continue
+		}
+		switch {
+		case t.Outcome == results.Fail:
+			o.Caught = append(o.Caught, t.Path)
+		case t.Outcome == results.Pass && isMutated(t):
+			o.Missed = append(o.Missed, t.Path)
+		}
+	}
+	return o
+}
+
+func isMutated(t results.Test) bool {
+	for _, e := range t.Exchanges {
+		if e.Response != nil && len(e.Response.Headers.Values(Header)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+const markdownTemplate = `# Mutation Report
+
+| Mutation | Caught | Missed |
+|---|---|---|
+{{- range . }}
+| {{ if .Err }}⚠️{{ else if not .Caught }}❌{{ else }}✅{{ end }} {{ .Mutation.Name }} | {{ len .Caught }} | {{ len .Missed }} |
+{{- end }}
+{{ range . }}
+## {{ .Mutation.Name }}
+
+{{ .Mutation.Description }}
+{{ with .Err }}
+` + "```" + `
+{{ . }}
+` + "```" + `
+{{ end }}
+{{- if .Missed }}
+Tests passing with a mutated response:
+{{ range .Missed }}
+- {{ . }}
+{{- end }}
+{{ end }}
+{{- if .Caught }}
+<details><summary>Tests failing with the mutation</summary>
+{{ range .Caught }}
+- {{ . }}
+{{- end }}
+
+</details>
+{{ end }}
+{{- end }}`
+
+var markdown = template.Must(template.New("markdown").Parse(markdownTemplate))
+
+// WriteMarkdown writes the outcomes as Markdown: a summary table, followed by
+// the tests each mutation went through.
+func WriteMarkdown(w io.Writer, outcomes []Outcome) error {
+	return markdown.Execute(w, outcomes)
+}
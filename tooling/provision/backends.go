@@ -0,0 +1,157 @@
+package provision
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/go-cid"
+)
+
+// Kubo provisions a Kubo node through its RPC API, e.g.
+// http://127.0.0.1:5001. CAR files are imported without pinning their roots,
+// IPNS records are put in the routing even when the node is offline.
+type Kubo struct {
+	API    string
+	Client *http.Client
+}
+
+func (k Kubo) ImportCAR(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return k.post(ctx, "dag/import", url.Values{"pin-roots": {"false"}}, f)
+}
+
+func (k Kubo) PutIPNSRecord(ctx context.Context, name ipns.Name, record []byte) error {
+	return k.post(ctx, "routing/put", url.Values{"arg": {name.AsPath().String()}, "allow-offline": {"true"}}, bytes.NewReader(record))
+}
+
+// post sends file to the RPC API command.
+func (k Kubo) post(ctx context.Context, command string, params url.Values, file io.Reader) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "file")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	u := strings.TrimSuffix(k.API, "/") + "/api/v0/" + command + "?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	res, err := k.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+	// Errors happening while the response streams are reported in a
+	// trailer.
+	if _, err := io.Copy(io.Discard, res.Body); err != nil {
+		return err
+	}
+	if msg := res.Trailer.Get("X-Stream-Error"); msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
+
+// HTTP provisions a blockstore with an HTTP API. Every block is sent to
+// BlockURL, and every IPNS record to IPNSURL, where "{cid}" is replaced with
+// the CID of the block and "{name}" with the IPNS name of the record, e.g.
+// https://blocks.example.com/{cid}.
+type HTTP struct {
+	BlockURL string
+	// IPNSURL is optional, IPNS records are not provisioned without it.
+	IPNSURL string
+	// Method is the method of the requests, PUT or POST.
+	Method string
+	Header http.Header
+	Client *http.Client
+}
+
+func (h HTTP) ImportCAR(ctx context.Context, path string) error {
+	return blocks(ctx, path, func(c cid.Cid, data []byte) error {
+		return h.send(ctx, strings.ReplaceAll(h.BlockURL, "{cid}", c.String()), "application/vnd.ipld.raw", data)
+	})
+}
+
+func (h HTTP) PutIPNSRecord(ctx context.Context, name ipns.Name, record []byte) error {
+	if h.IPNSURL == "" {
+		return nil
+	}
+	return h.send(ctx, strings.ReplaceAll(h.IPNSURL, "{name}", name.String()), "application/vnd.ipfs.ipns-record", record)
+}
+
+func (h HTTP) send(ctx context.Context, u, contentType string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, h.Method, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for name, values := range h.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	res, err := h.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := checkResponse(res); err != nil {
+		return fmt.Errorf("%s %s: %w", h.Method, u, err)
+	}
+	return nil
+}
+
+// Dir provisions a local directory, for gateways reading their blocks and
+// records from files:
+//
+//	blocks/<cid>        the raw data of every block
+//	ipns/<name>         the signed IPNS record of every name
+//
+// CIDs are written in their default string form, base58 for CIDv0 and base32
+// for CIDv1, and IPNS names in base36.
+type Dir struct {
+	Path string
+}
+
+func (d Dir) ImportCAR(ctx context.Context, path string) error {
+	dir := filepath.Join(d.Path, "blocks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return blocks(ctx, path, func(c cid.Cid, data []byte) error {
+		return os.WriteFile(filepath.Join(dir, c.String()), data, 0644)
+	})
+}
+
+func (d Dir) PutIPNSRecord(ctx context.Context, name ipns.Name, record []byte) error {
+	dir := filepath.Join(d.Path, "ipns")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name.String()), record, 0644)
+}
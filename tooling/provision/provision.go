@@ -0,0 +1,253 @@
+// Package provision loads the conformance fixtures into the gateway under
+// test, through one of several backends, and verifies that the gateway
+// serves them.
+package provision
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ipfs/boxo/ipns"
+	"github.com/ipfs/go-cid"
+	carblockstore "github.com/ipld/go-car/v2/blockstore"
+
+	"github.com/ipfs/gateway-conformance/tooling/car"
+	"github.com/ipfs/gateway-conformance/tooling/fixtures"
+)
+
+// Backend stores fixtures where the gateway under test reads them from.
+type Backend interface {
+	// ImportCAR stores every block of the CAR file at path.
+	ImportCAR(ctx context.Context, path string) error
+	// PutIPNSRecord stores the signed IPNS record of name.
+	PutIPNSRecord(ctx context.Context, name ipns.Name, record []byte) error
+}
+
+// Stats counts the fixtures provisioned.
+type Stats struct {
+	CARs        int
+	IPNSRecords int
+	// Invalid lists the IPNS records that were not provisioned, by file or
+	// by name, some fixtures are invalid on purpose, a gateway must not
+	// accept them.
+	Invalid []string
+}
+
+// Fixtures stores the CAR files and the valid IPNS records of fxs with the
+// backend.
+func Fixtures(ctx context.Context, b Backend, fxs *fixtures.Fixtures) (Stats, error) {
+	var stats Stats
+	for _, file := range fxs.CarFiles {
+		if err := b.ImportCAR(ctx, file); err != nil {
+			return stats, fmt.Errorf("importing %s: %w", file, err)
+		}
+		stats.CARs++
+	}
+
+	for _, file := range fxs.IPNSRecords {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return stats, err
+		}
+		name, err := validIPNSRecord(fixtures.IPNSName(file), data)
+		if err != nil {
+			stats.Invalid = append(stats.Invalid, file)
+			continue
+		}
+		if err := b.PutIPNSRecord(ctx, name, data); err != nil {
+			return stats, fmt.Errorf("putting %s: %w", file, err)
+		}
+		stats.IPNSRecords++
+	}
+	return stats, nil
+}
+
+// Merged stores the blocks and the valid IPNS records of the merged CAR file
+// at path, written by extract-fixtures --merged --metadata, with the backend.
+func Merged(ctx context.Context, b Backend, path string) (Stats, error) {
+	var stats Stats
+	meta, err := car.ReadMetadata(path)
+	if err != nil {
+		return stats, err
+	}
+
+	if err := b.ImportCAR(ctx, path); err != nil {
+		return stats, fmt.Errorf("importing %s: %w", path, err)
+	}
+	stats.CARs++
+
+	keys := make([]string, 0, len(meta.IPNSRecords))
+	for key := range meta.IPNSRecords {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name, err := validIPNSRecord(key, meta.IPNSRecords[key])
+		if err != nil {
+			stats.Invalid = append(stats.Invalid, key)
+			continue
+		}
+		if err := b.PutIPNSRecord(ctx, name, meta.IPNSRecords[key]); err != nil {
+			return stats, fmt.Errorf("putting %s: %w", key, err)
+		}
+		stats.IPNSRecords++
+	}
+	return stats, nil
+}
+
+// validIPNSRecord validates the record data of the IPNS name key.
+func validIPNSRecord(key string, data []byte) (ipns.Name, error) {
+	name, err := ipns.NameFromString(key)
+	if err != nil {
+		return ipns.Name{}, err
+	}
+	record, err := ipns.UnmarshalRecord(data)
+	if err != nil {
+		return ipns.Name{}, err
+	}
+	if err := ipns.ValidateWithName(record, name); err != nil {
+		return ipns.Name{}, err
+	}
+	return name, nil
+}
+
+// Roots returns the roots of the CAR files, the gateway must serve them once
+// provisioned. Roots missing from their CAR file are left out.
+func Roots(files []string) ([]cid.Cid, error) {
+	var roots []cid.Cid
+	seen := map[cid.Cid]bool{}
+	for _, file := range files {
+		bs, err := carblockstore.OpenReadOnly(file, carblockstore.UseWholeCIDs(true))
+		if err != nil {
+			return nil, err
+		}
+		fileRoots, err := bs.Roots()
+		if err != nil {
+			bs.Close()
+			return nil, fmt.Errorf("reading %s: %w", file, err)
+		}
+		for _, root := range fileRoots {
+			// Some fixtures only hold a part of a DAG, without its
+			// root.
+			has, err := bs.Has(context.Background(), root)
+			if err != nil {
+				bs.Close()
+				return nil, err
+			}
+			if has && !seen[root] {
+				seen[root] = true
+				roots = append(roots, root)
+			}
+		}
+		bs.Close()
+	}
+	return roots, nil
+}
+
+// Verify requests every root as a raw block from the gateway at gatewayURL,
+// until the gateway serves it or timeout elapses. It returns the roots the
+// gateway did not serve.
+func Verify(ctx context.Context, client *http.Client, gatewayURL string, roots []cid.Cid, timeout time.Duration) []cid.Cid {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var missing []cid.Cid
+	for _, root := range roots {
+		for fetchBlock(ctx, client, gatewayURL, root) != nil {
+			if !wait(ctx, 100*time.Millisecond) {
+				missing = append(missing, root)
+				break
+			}
+		}
+	}
+	return missing
+}
+
+// wait waits for d and reports whether ctx is still running.
+func wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// fetchBlock checks that the gateway serves the block of c.
+func fetchBlock(ctx context.Context, client *http.Client, gatewayURL string, c cid.Cid) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(gatewayURL, "/")+"/ipfs/"+c.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.ipld.raw")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	sum, err := c.Prefix().Sum(data)
+	if err != nil {
+		return err
+	}
+	if !sum.Equals(c) {
+		return fmt.Errorf("the block does not match its CID")
+	}
+	return nil
+}
+
+// blocks calls put with every block of the CAR file at path.
+func blocks(ctx context.Context, path string, put func(c cid.Cid, data []byte) error) error {
+	bs, err := carblockstore.OpenReadOnly(path, carblockstore.UseWholeCIDs(true))
+	if err != nil {
+		return err
+	}
+	defer bs.Close()
+
+	// Stops listing the blocks when put fails.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cids, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return err
+	}
+	for c := range cids {
+		block, err := bs.Get(ctx, c)
+		if err != nil {
+			return err
+		}
+		if err := put(c, block.RawData()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkResponse returns an error holding the body of res when its status is
+// not a success.
+func checkResponse(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	return fmt.Errorf("%s: %s", res.Status, bytes.TrimSpace(body))
+}
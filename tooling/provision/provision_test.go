@@ -0,0 +1,164 @@
+package provision
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ipfs/gateway-conformance/tooling/car"
+	"github.com/ipfs/gateway-conformance/tooling/fixtures"
+)
+
+func testFixtures(t *testing.T) *fixtures.Fixtures {
+	all, err := fixtures.List()
+	require.NoError(t, err)
+
+	fxs := &fixtures.Fixtures{IPNSRecords: all.IPNSRecords}
+	for _, file := range all.CarFiles {
+		if filepath.Base(file) == "gateway-raw-block.car" {
+			fxs.CarFiles = append(fxs.CarFiles, file)
+		}
+	}
+	require.Len(t, fxs.CarFiles, 1)
+	return fxs
+}
+
+func TestHTTP(t *testing.T) {
+	var mu sync.Mutex
+	var blocks, records int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/blocks/baf"):
+			assert.Equal(t, "application/vnd.ipld.raw", r.Header.Get("Content-Type"))
+			blocks++
+		case strings.HasPrefix(r.URL.Path, "/ipns/k"):
+			assert.Equal(t, "application/vnd.ipfs.ipns-record", r.Header.Get("Content-Type"))
+			records++
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	fxs := testFixtures(t)
+	stats, err := Fixtures(context.Background(), HTTP{
+		BlockURL: srv.URL + "/blocks/{cid}",
+		IPNSURL:  srv.URL + "/ipns/{name}",
+		Method:   http.MethodPost,
+		Header:   http.Header{"X-Api-Key": {"secret"}},
+		Client:   srv.Client(),
+	}, fxs)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, stats.CARs)
+	assert.Equal(t, len(fxs.IPNSRecords), stats.IPNSRecords+len(stats.Invalid))
+	assert.NotEmpty(t, stats.Invalid, "some fixtures are invalid on purpose")
+	assert.Positive(t, blocks)
+	assert.Equal(t, stats.IPNSRecords, records)
+}
+
+func TestKubo(t *testing.T) {
+	var mu sync.Mutex
+	var commands []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, http.MethodPost, r.Method)
+		f, _, err := r.FormFile("file")
+		require.NoError(t, err)
+		data, err := io.ReadAll(f)
+		require.NoError(t, err)
+		assert.NotEmpty(t, data)
+		commands = append(commands, strings.TrimPrefix(r.URL.Path, "/api/v0/")+"?"+r.URL.RawQuery)
+	}))
+	defer srv.Close()
+
+	fxs := testFixtures(t)
+	fxs.IPNSRecords = fxs.IPNSRecords[:1]
+	name := strings.SplitN(filepath.Base(fxs.IPNSRecords[0]), "_", 2)[0]
+	name = strings.TrimSuffix(name, ".ipns-record")
+
+	stats, err := Fixtures(context.Background(), Kubo{API: srv.URL, Client: srv.Client()}, fxs)
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.IPNSRecords, "the record is valid")
+	assert.Equal(t, []string{
+		"dag/import?pin-roots=false",
+		"routing/put?allow-offline=true&arg=%2Fipns%2F" + name,
+	}, commands)
+}
+
+func TestKuboError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"Message":"blockstore is read-only"}`, http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	_, err := Fixtures(context.Background(), Kubo{API: srv.URL, Client: srv.Client()}, testFixtures(t))
+	assert.ErrorContains(t, err, "blockstore is read-only")
+}
+
+func TestDirAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	fxs := testFixtures(t)
+	_, err := Fixtures(context.Background(), Dir{Path: dir}, fxs)
+	require.NoError(t, err)
+
+	// A gateway serving the blocks of the directory.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join(dir, "blocks", strings.TrimPrefix(r.URL.Path, "/ipfs/")))
+	}))
+	defer srv.Close()
+
+	roots, err := Roots(fxs.CarFiles)
+	require.NoError(t, err)
+	require.Len(t, roots, 1)
+	assert.Empty(t, Verify(context.Background(), srv.Client(), srv.URL, roots, time.Second))
+
+	missing := cid.MustParse("bafkqaaa")
+	assert.Equal(t, []cid.Cid{missing}, Verify(context.Background(), srv.Client(), srv.URL, []cid.Cid{missing}, 200*time.Millisecond))
+
+	entries, err := os.ReadDir(filepath.Join(dir, "ipns"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, entries)
+}
+
+func TestMerged(t *testing.T) {
+	fxs := testFixtures(t)
+	meta := car.Metadata{IPNSRecords: map[string][]byte{}}
+	for _, file := range fxs.IPNSRecords {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		meta.IPNSRecords[fixtures.IPNSName(file)] = data
+	}
+	merged := filepath.Join(t.TempDir(), "fixtures.car")
+	require.NoError(t, car.MergeWithMetadata(fxs.CarFiles, meta, merged))
+
+	want, err := Fixtures(context.Background(), Dir{Path: t.TempDir()}, fxs)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	stats, err := Merged(context.Background(), Dir{Path: dir}, merged)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.CARs)
+	assert.Equal(t, want.IPNSRecords, stats.IPNSRecords)
+	assert.Len(t, stats.Invalid, len(want.Invalid))
+
+	entries, err := os.ReadDir(filepath.Join(dir, "ipns"))
+	require.NoError(t, err)
+	assert.Len(t, entries, stats.IPNSRecords)
+}
@@ -0,0 +1,73 @@
+package report
+
+import (
+	"html/template"
+	"io"
+)
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Gateway Conformance Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+tr.pass td.outcome { color: #1a7f37; }
+tr.fail td.outcome { color: #cf222e; font-weight: bold; }
+tr.skip td.outcome { color: #9a6700; }
+pre { white-space: pre-wrap; margin: 0.5em 0; font-size: 0.85em; }
+td.name { word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>Gateway Conformance Report</h1>
+{{- with .Report.Meta.version }}<p>Version: {{ . }}</p>{{ end }}
+{{- with .Report.Meta.gateway_url }}<p>Gateway: {{ . }}</p>{{ end }}
+{{- with .Report.Meta.job_url }}<p>Job: <a href="{{ . }}">{{ . }}</a></p>{{ end }}
+<table>
+<tr><th>Tests</th><th>Passed</th><th>Failed</th><th>Skipped</th><th>Time (s)</th></tr>
+<tr><td>{{ len .Report.Tests }}</td><td>{{ .Pass }}</td><td>{{ .Fail }}</td><td>{{ .Skip }}</td><td>{{ seconds .Report.Elapsed }}</td></tr>
+</table>
+{{- range .Report.TopLevel }}
+<h2 id="{{ .Name }}">{{ .Name }}{{ with .Group }} <small>({{ . }})</small>{{ end }}</h2>
+<table>
+<tr><th>Test</th><th>Outcome</th><th>Time (s)</th><th>Details</th></tr>
+{{- range (children .) }}
+<tr class="{{ .Outcome }}">
+<td class="name">{{ .Name }}</td>
+<td class="outcome">{{ .Outcome }}</td>
+<td>{{ seconds .Elapsed }}</td>
+<td>
+{{- with .Hint }}<div>Hint: {{ . }}</div>{{ end }}
+{{- range .Specs }}<div>Spec: <a href="{{ . }}">{{ . }}</a></div>{{ end }}
+{{- if eq .Outcome "fail" }}<details><summary>Failure</summary><pre>{{ .Failure }}</pre></details>{{ end }}
+</td>
+</tr>
+{{- end }}
+</table>
+{{- end }}
+</body>
+</html>
+`
+
+// WriteHTML writes the report as a self-contained HTML page.
+func WriteHTML(w io.Writer, r *Report) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"seconds": seconds,
+		"children": func(t *Test) []*Test {
+			return append([]*Test{t}, r.Children(t.Name)...)
+		},
+	}).Parse(htmlTemplate)
+	if err != nil {
+		return err
+	}
+
+	pass, fail, skip := r.Counts()
+	return tmpl.Execute(w, struct {
+		Report           *Report
+		Pass, Fail, Skip int
+	}{r, pass, fail, skip})
+}
@@ -0,0 +1,157 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr,omitempty"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       string           `xml:"time,attr"`
+	Timestamp  string           `xml:"timestamp,attr,omitempty"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Cases      []junitTestCase  `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string           `xml:"name,attr"`
+	Classname  string           `xml:"classname,attr"`
+	Time       string           `xml:"time,attr"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Failure    *junitResult     `xml:"failure,omitempty"`
+	Skipped    *junitResult     `xml:"skipped,omitempty"`
+	SystemOut  *junitOutput     `xml:"system-out,omitempty"`
+}
+
+type junitProperties struct {
+	Property []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitResult struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",cdata"`
+}
+
+type junitOutput struct {
+	Body string `xml:",cdata"`
+}
+
+// xmlSafe replaces the characters that are not allowed in XML documents,
+// e.g. control characters from binary response bodies.
+func xmlSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' ||
+			(r >= 0x20 && r <= 0xD7FF) ||
+			(r >= 0xE000 && r <= 0xFFFD) ||
+			(r >= 0x10000 && r <= 0x10FFFF) {
+			return r
+		}
+		return '\uFFFD'
+	}, s)
+}
+
+func seconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
+
+func testProperties(t *Test) *junitProperties {
+	var properties []junitProperty
+	if t.Group != "" {
+		properties = append(properties, junitProperty{Name: "group", Value: t.Group})
+	}
+	for _, spec := range t.Specs {
+		properties = append(properties, junitProperty{Name: "spec", Value: spec})
+	}
+	if t.Hint != "" {
+		properties = append(properties, junitProperty{Name: "hint", Value: t.Hint})
+	}
+	if len(properties) == 0 {
+		return nil
+	}
+	return &junitProperties{Property: properties}
+}
+
+// WriteJUnit writes the report as JUnit XML. Every top-level test becomes a
+// test suite, which holds a test case for the test itself and for each of its
+// subtests. Groups, spec URLs and hints are attached as test case properties.
+func WriteJUnit(w io.Writer, r *Report) error {
+	suites := junitTestSuites{
+		Name: "Gateway Tests",
+		Time: seconds(r.Elapsed),
+	}
+
+	for _, top := range r.TopLevel() {
+		suite := junitTestSuite{
+			Name: top.Name,
+			Time: seconds(top.Elapsed),
+		}
+		if !r.Time.IsZero() {
+			suite.Timestamp = r.Time.UTC().Format("2006-01-02T15:04:05")
+		}
+		var properties []junitProperty
+		for _, key := range []string{"version", "job_url", "gateway_url", "subdomain_gateway_url"} {
+			if v, ok := r.Meta[key].(string); ok && v != "" {
+				properties = append(properties, junitProperty{Name: key, Value: v})
+			}
+		}
+		if len(properties) > 0 {
+			suite.Properties = &junitProperties{Property: properties}
+		}
+
+		for _, t := range append([]*Test{top}, r.Children(top.Name)...) {
+			c := junitTestCase{
+				Name:       t.Name,
+				Classname:  top.Name,
+				Time:       seconds(t.Elapsed),
+				Properties: testProperties(t),
+				SystemOut:  &junitOutput{Body: xmlSafe(t.Output)},
+			}
+			switch t.Outcome {
+			case Fail:
+				c.Failure = &junitResult{Message: "Failed", Body: xmlSafe(t.Failure())}
+				suite.Failures++
+			case Skip:
+				c.Skipped = &junitResult{Message: "Skipped", Body: xmlSafe(t.Failure())}
+				suite.Skipped++
+			}
+			suite.Tests++
+			suite.Cases = append(suite.Cases, c)
+		}
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,116 @@
+package report
+
+import (
+	"io"
+	"strings"
+	"text/template"
+)
+
+const markdownTemplate = `# Gateway Conformance Report
+{{ with .Report.Meta.version }}
+- Version: {{ . }}
+{{- end }}
+{{- with .Report.Meta.gateway_url }}
+- Gateway: {{ . }}
+{{- end }}
+{{- with .Report.Meta.job_url }}
+- Job: {{ . }}
+{{- end }}
+
+| Tests | Passed | Failed | Skipped | Time (s) |
+|---|---|---|---|---|
+| {{ len .Report.Tests }} | {{ .Pass }} | {{ .Fail }} | {{ .Skip }} | {{ seconds .Report.Elapsed }} |
+
+## Summary
+
+| Test | Group | Passed | Failed | Skipped |
+|---|---|---|---|---|
+{{- range .Suites }}
+| {{ if .Fail }}❌{{ else }}✅{{ end }} {{ cell .Test.Name }} | {{ cell .Test.Group }} | {{ .Pass }} | {{ .Fail }} | {{ .Skip }} |
+{{- end }}
+{{ if .Failures }}
+## Failures
+{{ range .Failures }}
+### {{ .Name }}
+{{ with .Hint }}
+Hint: {{ . }}
+{{ end }}
+{{- range .Specs }}
+- {{ . }}
+{{- end }}
+{{ with .Failure }}
+<details><summary>Details</summary>
+
+` + "```" + `
+{{ . }}
+` + "```" + `
+
+</details>
+{{ end }}
+{{- end }}
+{{- end }}`
+
+type markdownSuite struct {
+	Test             *Test
+	Pass, Fail, Skip int
+}
+
+// WriteMarkdown writes a summary of the report as Markdown: counts per
+// top-level test, followed by the details of every failing leaf test.
+func WriteMarkdown(w io.Writer, r *Report) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"seconds": seconds,
+		"cell":    escapeCell,
+	}).Parse(markdownTemplate)
+	if err != nil {
+		return err
+	}
+
+	var suites []markdownSuite
+	for _, top := range r.TopLevel() {
+		suite := markdownSuite{Test: top}
+		for _, t := range append([]*Test{top}, r.Children(top.Name)...) {
+			switch t.Outcome {
+			case Pass:
+				suite.Pass++
+			case Fail:
+				suite.Fail++
+			case Skip:
+				suite.Skip++
+			}
+		}
+		suites = append(suites, suite)
+	}
+
+	// Only list the failing tests without failing children, their parents
+	// fail as a consequence.
+	var failures []*Test
+	for _, t := range r.Tests {
+		if t.Outcome != Fail {
+			continue
+		}
+		leaf := true
+		for _, c := range r.Children(t.Name) {
+			if c.Outcome == Fail {
+				leaf = false
+				break
+			}
+		}
+		if leaf {
+			failures = append(failures, t)
+		}
+	}
+
+	pass, fail, skip := r.Counts()
+	return tmpl.Execute(w, struct {
+		Report           *Report
+		Pass, Fail, Skip int
+		Suites           []markdownSuite
+		Failures         []*Test
+	}{r, pass, fail, skip, suites, failures})
+}
+
+// escapeCell makes a value safe to use in a Markdown table cell.
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
@@ -0,0 +1,204 @@
+// Package report turns the test2json events of a conformance run into
+// human and machine readable reports (JUnit XML, HTML and Markdown).
+package report
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ipfs/gateway-conformance/tooling/test2json"
+)
+
+const (
+	Pass = "pass"
+	Fail = "fail"
+	Skip = "skip"
+)
+
+// Test is the outcome of a single test or subtest.
+type Test struct {
+	// Name is the full name of the test, as reported by `go test`,
+	// e.g. "TestGatewayBlock/GET_with_format=raw_param_returns_a_raw_block".
+	Name    string
+	Outcome string
+	Elapsed float64
+	Output  string
+	// Meta merges every `--- META:` entry logged by the test.
+	Meta map[string]interface{}
+	// Specs lists the spec URLs logged by the test and its parents.
+	Specs []string
+	// Hint is the hint logged by the test or its closest parent.
+	Hint string
+	// Group is the test group logged by the top-level test.
+	Group string
+}
+
+// Path splits the test name into its components.
+func (t *Test) Path() []string {
+	return strings.Split(t.Name, "/")
+}
+
+// Parent returns the name of the parent test, or "" for top-level tests.
+func (t *Test) Parent() string {
+	i := strings.LastIndex(t.Name, "/")
+	if i < 0 {
+		return ""
+	}
+	return t.Name[:i]
+}
+
+// Report holds every test of a run, in the order they started.
+type Report struct {
+	Time    time.Time
+	Elapsed float64
+	Tests   []*Test
+	// Meta merges the metadata logged by the tests, e.g. the version.
+	Meta map[string]interface{}
+}
+
+// Counts returns the number of passed, failed and skipped tests.
+func (r *Report) Counts() (pass, fail, skip int) {
+	for _, t := range r.Tests {
+		switch t.Outcome {
+		case Pass:
+			pass++
+		case Fail:
+			fail++
+		case Skip:
+			skip++
+		}
+	}
+	return
+}
+
+// TopLevel returns the top-level tests.
+func (r *Report) TopLevel() []*Test {
+	var tests []*Test
+	for _, t := range r.Tests {
+		if t.Parent() == "" {
+			tests = append(tests, t)
+		}
+	}
+	return tests
+}
+
+// Children returns every descendant of the given top-level test, in order.
+func (r *Report) Children(name string) []*Test {
+	var tests []*Test
+	for _, t := range r.Tests {
+		if strings.HasPrefix(t.Name, name+"/") {
+			tests = append(tests, t)
+		}
+	}
+	return tests
+}
+
+var metaRegexp = regexp.MustCompile(`--- META: (.*)`)
+
+// FromEvents builds a report from a test2json event stream.
+func FromEvents(events []test2json.Event) *Report {
+	r := &Report{Meta: map[string]interface{}{}}
+	tests := map[string]*Test{}
+
+	for _, e := range events {
+		if e.Time != nil && r.Time.IsZero() {
+			r.Time = *e.Time
+		}
+
+		if e.Test == "" {
+			if e.Elapsed != nil && (e.Action == Pass || e.Action == Fail) {
+				r.Elapsed = *e.Elapsed
+			}
+			continue
+		}
+
+		t, ok := tests[e.Test]
+		if !ok {
+			t = &Test{Name: e.Test, Meta: map[string]interface{}{}}
+			tests[e.Test] = t
+			r.Tests = append(r.Tests, t)
+		}
+
+		switch e.Action {
+		case "output":
+			t.Output += e.Output
+			if m := metaRegexp.FindStringSubmatch(e.Output); m != nil {
+				meta := map[string]interface{}{}
+				if err := json.Unmarshal([]byte(m[1]), &meta); err == nil {
+					for k, v := range meta {
+						t.Meta[k] = v
+						r.Meta[k] = v
+					}
+					if specs, ok := meta["specs"].([]interface{}); ok {
+						for _, s := range specs {
+							if s, ok := s.(string); ok {
+								t.Specs = appendUniq(t.Specs, s)
+							}
+						}
+					}
+				}
+			}
+		case Pass, Fail, Skip:
+			t.Outcome = e.Action
+			if e.Elapsed != nil {
+				t.Elapsed = *e.Elapsed
+			}
+		}
+	}
+
+	// Inherit specs, hints and groups from parents. Tests are ordered so that
+	// parents always come before their children.
+	for _, t := range r.Tests {
+		if hint, ok := t.Meta["hint"].(string); ok {
+			t.Hint = hint
+		}
+		if group, ok := t.Meta["group"].(string); ok {
+			t.Group = group
+		}
+
+		parent, ok := tests[t.Parent()]
+		if !ok {
+			continue
+		}
+		for _, s := range parent.Specs {
+			t.Specs = appendUniq(t.Specs, s)
+		}
+		if t.Hint == "" {
+			t.Hint = parent.Hint
+		}
+		if t.Group == "" {
+			t.Group = parent.Group
+		}
+	}
+
+	return r
+}
+
+func appendUniq(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// Failure extracts the failure details from the test output, without the
+// framing and metadata lines.
+func (t *Test) Failure() string {
+	var lines []string
+	for _, line := range strings.SplitAfter(t.Output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "=== ") ||
+			strings.HasPrefix(trimmed, "--- PASS") ||
+			strings.HasPrefix(trimmed, "--- FAIL") ||
+			strings.HasPrefix(trimmed, "--- SKIP") ||
+			metaRegexp.MatchString(trimmed) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Trim(strings.Join(lines, ""), "\n")
+}
@@ -0,0 +1,97 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling/test2json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const output = "\x16=== RUN   TestMetadata\n" +
+	"    metadata.go:30: --- META: {\"version\":\"dev\"}\n" +
+	"\x16--- PASS: TestMetadata (0.00s)\n" +
+	"\x16=== RUN   TestGatewayBlock\n" +
+	"    raw.go:15: --- META: {\"group\":\"Block-CAR\"}\n" +
+	"\x16=== RUN   TestGatewayBlock/GET_raw\n" +
+	"    test.go:82: --- META: {\"specs\":[\"https://specs.ipfs.tech/http-gateways/path-gateway/\"]}\n" +
+	"    test.go:83: --- META: {\"hint\":\"raw blocks are returned as-is\"}\n" +
+	"\x16=== RUN   TestGatewayBlock/GET_raw/Status_code\n" +
+	"\x0f    run.go:58: Status code is not 200. It is 404\x0e\n" +
+	"\x16--- FAIL: TestGatewayBlock/GET_raw/Status_code (0.00s)\n" +
+	"\x16=== NAME  TestGatewayBlock/GET_raw\n" +
+	"\x16--- FAIL: TestGatewayBlock/GET_raw (0.01s)\n" +
+	"\x16=== NAME  TestGatewayBlock\n" +
+	"\x16--- FAIL: TestGatewayBlock (0.01s)\n" +
+	"\x16=== NAME  \n" +
+	"\x16FAIL\n"
+
+func fromOutput(t *testing.T) *Report {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, test2json.Convert(&buf, strings.NewReader(output), "Gateway Tests"))
+	events, err := test2json.ReadEvents(&buf)
+	require.NoError(t, err)
+
+	return FromEvents(events)
+}
+
+func TestFromEvents(t *testing.T) {
+	r := fromOutput(t)
+
+	require.Len(t, r.Tests, 4)
+	assert.Equal(t, "dev", r.Meta["version"])
+
+	pass, fail, skip := r.Counts()
+	assert.Equal(t, []int{1, 3, 0}, []int{pass, fail, skip})
+
+	status := r.Tests[3]
+	assert.Equal(t, "TestGatewayBlock/GET_raw/Status_code", status.Name)
+	assert.Equal(t, Fail, status.Outcome)
+	assert.Equal(t, "Block-CAR", status.Group)
+	assert.Equal(t, "raw blocks are returned as-is", status.Hint)
+	assert.Equal(t, []string{"https://specs.ipfs.tech/http-gateways/path-gateway/"}, status.Specs)
+	assert.Equal(t, "    run.go:58: Status code is not 200. It is 404", status.Failure())
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteJUnit(&buf, fromOutput(t)))
+
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &suites))
+
+	assert.Equal(t, 4, suites.Tests)
+	assert.Equal(t, 3, suites.Failures)
+	require.Len(t, suites.Suites, 2)
+
+	block := suites.Suites[1]
+	require.Len(t, block.Cases, 3)
+	status := block.Cases[2]
+	require.NotNil(t, status.Failure)
+	assert.Contains(t, status.Failure.Body, "Status code is not 200")
+	assert.Contains(t, status.Properties.Property, junitProperty{Name: "spec", Value: "https://specs.ipfs.tech/http-gateways/path-gateway/"})
+	assert.Contains(t, status.Properties.Property, junitProperty{Name: "hint", Value: "raw blocks are returned as-is"})
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteMarkdown(&buf, fromOutput(t)))
+
+	md := buf.String()
+	assert.Contains(t, md, "| ❌ TestGatewayBlock | Block-CAR | 0 | 3 | 0 |")
+	assert.Contains(t, md, "### TestGatewayBlock/GET_raw/Status_code")
+	assert.NotContains(t, md, "### TestGatewayBlock/GET_raw\n")
+	assert.Contains(t, md, "Hint: raw blocks are returned as-is")
+}
+
+func TestWriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteHTML(&buf, fromOutput(t)))
+
+	assert.Contains(t, buf.String(), `<a href="https://specs.ipfs.tech/http-gateways/path-gateway/">`)
+}
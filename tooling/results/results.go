@@ -0,0 +1,149 @@
+// Package results defines the structured, versioned model of the outcome of
+// a conformance run. Unlike the test2json stream, it does not depend on the
+// formatting of the test logs.
+//
+// The model is described by the JSON schema in schema.json. Fields are only
+// ever added within a SchemaVersion, a breaking change bumps it.
+package results
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SchemaVersion is the version of the results JSON schema.
+const SchemaVersion = 1
+
+// Schema is the JSON schema describing a Report.
+//
+//go:embed schema.json
+var Schema []byte
+
+type Outcome string
+
+const (
+	Pass Outcome = "pass"
+	Fail Outcome = "fail"
+	Skip Outcome = "skip"
+)
+
+// Report is the outcome of a whole conformance run.
+type Report struct {
+	SchemaVersion       int    `json:"schemaVersion"`
+	Version             string `json:"version"`
+	JobURL              string `json:"jobURL,omitempty"`
+	GatewayURL          string `json:"gatewayURL,omitempty"`
+	SubdomainGatewayURL string `json:"subdomainGatewayURL,omitempty"`
+	// HTTPVersion is the HTTP version the run was forced to use, the
+	// protocol negotiated for every request is in Response.Proto.
+	HTTPVersion string `json:"httpVersion,omitempty"`
+	Tests       []Test `json:"tests"`
+	// Summary sums up the outcome of the tests per spec.
+	Summary *Summary `json:"summary,omitempty"`
+}
+
+// Test is the outcome of a single SugarTest.
+type Test struct {
+	// Name is the SugarTest name.
+	Name string `json:"name"`
+	// Path is the full name of the test as reported by `go test`, which can
+	// be used to find the test in the test2json stream.
+	Path  string   `json:"path"`
+	Group string   `json:"group,omitempty"`
+	Specs []string `json:"specs,omitempty"`
+	// Gates lists the names of the specs required to run the test, e.g.
+	// "path-unixfs-gateway".
+	Gates     []string   `json:"gates,omitempty"`
+	Hint      string     `json:"hint,omitempty"`
+	Exchanges []Exchange `json:"exchanges,omitempty"`
+	Outcome   Outcome    `json:"outcome"`
+	Reason    string     `json:"reason,omitempty"`
+	Checks    []Check    `json:"checks,omitempty"`
+}
+
+// Exchange is a request sent by a test and a summary of the response.
+type Exchange struct {
+	Request  Request   `json:"request"`
+	Response *Response `json:"response,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	// Attempts is the number of times the request was sent, more than one
+	// when it was retried after network errors.
+	Attempts int `json:"attempts,omitempty"`
+}
+
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Host    string      `json:"host,omitempty"`
+	Headers http.Header `json:"headers,omitempty"`
+}
+
+type Response struct {
+	StatusCode    int         `json:"statusCode"`
+	Proto         string      `json:"proto,omitempty"`
+	Headers       http.Header `json:"headers,omitempty"`
+	ContentLength int64       `json:"contentLength"`
+}
+
+// Check is the outcome of a single assertion on a response, such as a
+// status code, a header or a body check.
+type Check struct {
+	Name    string   `json:"name"`
+	Path    string   `json:"path"`
+	Specs   []string `json:"specs,omitempty"`
+	Hint    string   `json:"hint,omitempty"`
+	Outcome Outcome  `json:"outcome"`
+	Reason  string   `json:"reason,omitempty"`
+}
+
+// NewRequest summarizes req.
+func NewRequest(req *http.Request) Request {
+	r := Request{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Headers: req.Header.Clone(),
+	}
+	if req.Host != req.URL.Host {
+		r.Host = req.Host
+	}
+	return r
+}
+
+// NewResponse summarizes res, without reading its body.
+func NewResponse(res *http.Response) *Response {
+	if res == nil {
+		return nil
+	}
+	return &Response{
+		StatusCode:    res.StatusCode,
+		Proto:         res.Proto,
+		Headers:       res.Header.Clone(),
+		ContentLength: res.ContentLength,
+	}
+}
+
+// Write writes the report as indented JSON.
+func Write(w io.Writer, r Report) error {
+	r.SchemaVersion = SchemaVersion
+	if r.Tests == nil {
+		r.Tests = []Test{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// Read parses a report, as written by Write.
+func Read(r io.Reader) (*Report, error) {
+	var report Report
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, err
+	}
+	if report.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("unsupported results schema version %d, expected %d", report.SchemaVersion, SchemaVersion)
+	}
+	return &report, nil
+}
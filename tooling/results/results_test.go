@@ -0,0 +1,71 @@
+package results
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema(t *testing.T) {
+	var schema struct {
+		Properties map[string]interface{} `json:"properties"`
+		Required   []string               `json:"required"`
+	}
+	require.NoError(t, json.Unmarshal(Schema, &schema))
+
+	for _, key := range schema.Required {
+		assert.Contains(t, schema.Properties, key)
+	}
+	assert.Contains(t, schema.Required, "schemaVersion")
+	assert.Contains(t, schema.Required, "tests")
+}
+
+func TestWriteRead(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://127.0.0.1:8080/ipfs/bafy", nil)
+	require.NoError(t, err)
+	req.Host = "example.com"
+	req.Header.Set("Accept", "application/vnd.ipld.raw")
+
+	report := Report{
+		Version:    "v1.2.3",
+		GatewayURL: "http://127.0.0.1:8080",
+		Tests: []Test{{
+			Name:  "GET a raw block",
+			Path:  "TestGatewayBlock/GET_a_raw_block",
+			Group: "Block-CAR",
+			Specs: []string{"https://specs.ipfs.tech/http-gateways/path-gateway/"},
+			Exchanges: []Exchange{{
+				Request:  NewRequest(req),
+				Response: &Response{StatusCode: 404, ContentLength: -1},
+			}},
+			Outcome: Fail,
+			Reason:  "Status code is not 200. It is 404",
+			Checks: []Check{{
+				Name:    "Status code",
+				Path:    "TestGatewayBlock/GET_a_raw_block/Status_code",
+				Outcome: Fail,
+				Reason:  "Status code is not 200. It is 404",
+			}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, report))
+
+	read, err := Read(&buf)
+	require.NoError(t, err)
+
+	report.SchemaVersion = SchemaVersion
+	assert.Equal(t, report, *read)
+	assert.Equal(t, "example.com", read.Tests[0].Exchanges[0].Request.Host)
+}
+
+func TestReadUnsupportedVersion(t *testing.T) {
+	_, err := Read(strings.NewReader(`{"schemaVersion": 999, "tests": []}`))
+	assert.ErrorContains(t, err, "unsupported results schema version 999")
+}
@@ -0,0 +1,165 @@
+package results
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ipfs/gateway-conformance/tooling/specs"
+)
+
+// Counts holds the outcomes of a set of tests.
+type Counts struct {
+	Pass int `json:"pass"`
+	Fail int `json:"fail"`
+	Skip int `json:"skip"`
+	// Score is the percentage of the tests that ran which passed, skipped
+	// tests are left out. It is 0 when no test ran.
+	Score float64 `json:"score"`
+}
+
+// Ran returns the number of tests that passed or failed.
+func (c Counts) Ran() int {
+	return c.Pass + c.Fail
+}
+
+func (c *Counts) add(outcome Outcome) {
+	switch outcome {
+	case Pass:
+		c.Pass++
+	case Fail:
+		c.Fail++
+	case Skip:
+		c.Skip++
+	}
+	if c.Ran() > 0 {
+		c.Score = 100 * float64(c.Pass) / float64(c.Ran())
+	}
+}
+
+// SpecSummary holds the outcomes of the tests requiring a spec. The tests of
+// a collection are the tests requiring any of its children.
+type SpecSummary struct {
+	Name string `json:"name"`
+	// Children lists the names of the specs of a collection.
+	Children []string `json:"children,omitempty"`
+	Enabled  bool     `json:"enabled"`
+	Counts
+}
+
+// Summary sums up the outcome of a run, overall and per spec.
+type Summary struct {
+	Counts
+	// Specs lists every collection followed by its children, then the specs
+	// outside of a collection.
+	Specs []SpecSummary `json:"specs"`
+	// FailingSpecs lists the spec URLs of the failing tests and checks.
+	FailingSpecs []string `json:"failingSpecs,omitempty"`
+}
+
+// Summarize counts the outcomes of the tests, overall and for each of the
+// specs.
+func Summarize(tests []Test, all []specs.Spec) Summary {
+	var s Summary
+	var failing []string
+	for _, t := range tests {
+		s.add(t.Outcome)
+		if t.Outcome != Fail {
+			continue
+		}
+		failing = append(failing, t.Specs...)
+		for _, c := range t.Checks {
+			if c.Outcome == Fail {
+				failing = append(failing, c.Specs...)
+			}
+		}
+	}
+	sort.Strings(failing)
+	s.FailingSpecs = slices.Compact(failing)
+
+	inCollection := map[string]bool{}
+	for _, spec := range all {
+		if c, ok := spec.(specs.Collection); ok {
+			for _, child := range c.Children() {
+				inCollection[child.Name()] = true
+			}
+		}
+	}
+
+	for _, spec := range all {
+		switch spec := spec.(type) {
+		case specs.Collection:
+			s.Specs = append(s.Specs, summarizeSpec(tests, spec, spec.Children()...))
+			for _, child := range spec.Children() {
+				s.Specs = append(s.Specs, summarizeSpec(tests, child, child))
+			}
+		default:
+			if !inCollection[spec.Name()] {
+				s.Specs = append(s.Specs, summarizeSpec(tests, spec, spec))
+			}
+		}
+	}
+	return s
+}
+
+// summarizeSpec counts the outcomes of the tests requiring any of leaves.
+func summarizeSpec(tests []Test, spec specs.Spec, leaves ...specs.Spec) SpecSummary {
+	summary := SpecSummary{Name: spec.Name(), Enabled: spec.IsEnabled()}
+	if c, ok := spec.(specs.Collection); ok {
+		for _, child := range c.Children() {
+			summary.Children = append(summary.Children, child.Name())
+		}
+	}
+
+	for _, t := range tests {
+		if slices.ContainsFunc(leaves, func(leaf specs.Spec) bool { return slices.Contains(t.Gates, leaf.Name()) }) {
+			summary.add(t.Outcome)
+		}
+	}
+	return summary
+}
+
+// WriteSummary writes the summary as an aligned table, followed by the spec
+// URLs of the failing tests.
+func WriteSummary(w io.Writer, s Summary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SPEC\tPASS\tFAIL\tSKIP\tSCORE")
+
+	children := map[string]bool{}
+	for _, spec := range s.Specs {
+		for _, child := range spec.Children {
+			children[child] = true
+		}
+	}
+	for _, spec := range s.Specs {
+		name := spec.Name
+		if children[name] {
+			name = "  " + name
+		}
+		if !spec.Enabled {
+			name += " (disabled)"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n", name, spec.Pass, spec.Fail, spec.Skip, score(spec.Counts))
+	}
+	fmt.Fprintf(tw, "TOTAL\t%d\t%d\t%d\t%s\n", s.Pass, s.Fail, s.Skip, score(s.Counts))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(s.FailingSpecs) > 0 {
+		fmt.Fprintln(w, "\nFailing specs:")
+		for _, spec := range s.FailingSpecs {
+			fmt.Fprintf(w, "- %s\n", spec)
+		}
+	}
+	return nil
+}
+
+func score(c Counts) string {
+	if c.Ran() == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", c.Score)
+}
@@ -0,0 +1,50 @@
+package results
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling/specs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pathGateway = "https://specs.ipfs.tech/http-gateways/path-gateway/"
+
+func TestSummarize(t *testing.T) {
+	tests := []Test{
+		{Path: "TestA/one", Gates: []string{"path-raw-gateway"}, Outcome: Pass},
+		{Path: "TestA/two", Gates: []string{"path-raw-gateway"}, Outcome: Pass},
+		{Path: "TestA/three", Gates: []string{"path-raw-gateway", "path-tar-gateway"}, Outcome: Fail,
+			Specs: []string{pathGateway + "#etag"},
+			Checks: []Check{
+				{Outcome: Pass, Specs: []string{pathGateway + "#cache-control"}},
+				{Outcome: Fail, Specs: []string{pathGateway + "#etag", pathGateway + "#last-modified"}},
+			}},
+		{Path: "TestB/one", Gates: []string{"proxy-gateway"}, Outcome: Skip},
+		{Path: "TestC/one", Outcome: Pass},
+	}
+	all := []specs.Spec{specs.PathGatewayRaw, specs.PathGatewayTAR, specs.PathGateway, specs.ProxyGateway}
+
+	s := Summarize(tests, all)
+	assert.Equal(t, Counts{Pass: 3, Fail: 1, Skip: 1, Score: 75}, s.Counts)
+	assert.Equal(t, []string{pathGateway + "#etag", pathGateway + "#last-modified"}, s.FailingSpecs)
+
+	var names []string
+	for _, spec := range s.Specs {
+		names = append(names, spec.Name)
+	}
+	assert.Equal(t, []string{"path-gateway", "path-unixfs-gateway", "path-ipns-gateway", "path-tar-gateway", "path-dag-gateway", "path-raw-gateway", "proxy-gateway"}, names)
+
+	assert.Equal(t, Counts{Pass: 2, Fail: 1, Score: 200.0 / 3}, s.Specs[0].Counts)
+	assert.Len(t, s.Specs[0].Children, 5)
+	assert.Equal(t, Counts{Fail: 1}, s.Specs[3].Counts)
+	assert.Equal(t, Counts{Skip: 1}, s.Specs[6].Counts)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSummary(&buf, s))
+	assert.Contains(t, buf.String(), "path-gateway ")
+	assert.Contains(t, buf.String(), "  path-raw-gateway ")
+	assert.Contains(t, buf.String(), "66.7%")
+	assert.Contains(t, buf.String(), "\nFailing specs:\n- "+pathGateway+"#etag\n")
+}
@@ -0,0 +1,109 @@
+//go:build go1.22 && !go1.28
+
+// The hooks below mirror the unexported testDeps interface of the testing
+// package, which may change with every Go release. They are checked against
+// Go 1.22 to 1.27, deps_unchecked.go stops the build with newer versions
+// until TestDepsMatchTestingPackage passes with them and the constraint above
+// is raised.
+
+package runner
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"regexp"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
+
+// corpusEntry mirrors the type alias of the same name in package testing.
+type corpusEntry = struct {
+	Parent     string
+	Path       string
+	Data       []byte
+	Values     []any
+	Generation int
+	IsSeed     bool
+}
+
+var errFuzzing = errors.New("fuzzing is not supported by the conformance runner")
+
+// deps implements the hooks the testing package expects from the main
+// function generated by `go test`. It mirrors testing/internal/testdeps,
+// minus fuzzing and coverage support.
+type deps struct{}
+
+var (
+	matchMu  sync.Mutex
+	matchPat string
+	matchRe  *regexp.Regexp
+)
+
+func (deps) MatchString(pat, str string) (bool, error) {
+	matchMu.Lock()
+	defer matchMu.Unlock()
+
+	if matchRe == nil || matchPat != pat {
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			return false, err
+		}
+		matchPat = pat
+		matchRe = re
+	}
+	return matchRe.MatchString(str), nil
+}
+
+func (deps) StartCPUProfile(w io.Writer) error {
+	return pprof.StartCPUProfile(w)
+}
+
+func (deps) StopCPUProfile() {
+	pprof.StopCPUProfile()
+}
+
+func (deps) WriteProfileTo(name string, w io.Writer, debug int) error {
+	return pprof.Lookup(name).WriteTo(w, debug)
+}
+
+func (deps) ImportPath() string {
+	return "github.com/ipfs/gateway-conformance/tests"
+}
+
+func (deps) ModulePath() string {
+	return "github.com/ipfs/gateway-conformance"
+}
+
+func (deps) StartTestLog(io.Writer) {}
+
+func (deps) StopTestLog() error {
+	return nil
+}
+
+func (deps) SetPanicOnExit0(bool) {}
+
+func (deps) CoordinateFuzzing(time.Duration, int64, time.Duration, int64, int, []corpusEntry, []reflect.Type, string, string) error {
+	return errFuzzing
+}
+
+func (deps) RunFuzzWorker(func(corpusEntry) error) error {
+	return errFuzzing
+}
+
+func (deps) ReadCorpus(string, []reflect.Type) ([]corpusEntry, error) {
+	return nil, errFuzzing
+}
+
+func (deps) CheckCorpus([]any, []reflect.Type) error {
+	return nil
+}
+
+func (deps) ResetCoverage() {}
+
+func (deps) SnapshotCoverage() {}
+
+func (deps) InitRuntimeCoverage() (string, func(string, string) (string, error), func() float64) {
+	return "", nil, nil
+}
@@ -0,0 +1,33 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDepsMatchTestingPackage(t *testing.T) {
+	// The unexported testDeps interface is the first parameter of MainStart.
+	testDeps := reflect.TypeOf(testing.MainStart).In(0)
+	impl := reflect.TypeOf(deps{})
+
+	for i := 0; i < testDeps.NumMethod(); i++ {
+		want := testDeps.Method(i)
+		got, ok := impl.MethodByName(want.Name)
+		if !assert.True(t, ok, "deps does not implement %s", want.Name) {
+			continue
+		}
+		// The receiver is the first parameter of the method of the type.
+		var in []reflect.Type
+		for j := 1; j < got.Type.NumIn(); j++ {
+			in = append(in, got.Type.In(j))
+		}
+		var out []reflect.Type
+		for j := 0; j < got.Type.NumOut(); j++ {
+			out = append(out, got.Type.Out(j))
+		}
+		assert.Equal(t, want.Type, reflect.FuncOf(in, out, got.Type.IsVariadic()), "signature of %s", want.Name)
+	}
+	assert.True(t, impl.Implements(testDeps))
+}
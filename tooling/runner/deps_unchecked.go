@@ -0,0 +1,7 @@
+//go:build go1.28
+
+package runner
+
+// The hooks of deps.go were not checked against the testing package of this
+// Go version, see deps.go.
+var _ = runner_hooks_not_checked_against_this_go_version
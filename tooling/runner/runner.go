@@ -0,0 +1,165 @@
+// Package runner runs the conformance test suite in-process, the same way the
+// main function generated by `go test` would, so that running the suite does
+// not require a Go toolchain.
+package runner
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+	"testing"
+	"time"
+)
+
+// DefaultTimeout is the timeout of a run whose arguments do not set one, the
+// default of `go test`. The run panics once it is reached, so that a gateway
+// that hangs does not hang the CLI forever.
+const DefaultTimeout = 10 * time.Minute
+
+// testFlags are the flags `go test` accepts without the "test." prefix.
+var testFlags = map[string]bool{
+	"bench":                true,
+	"benchmem":             true,
+	"benchtime":            true,
+	"blockprofile":         true,
+	"blockprofilerate":     true,
+	"count":                true,
+	"cpu":                  true,
+	"cpuprofile":           true,
+	"failfast":             true,
+	"fullpath":             true,
+	"list":                 true,
+	"memprofile":           true,
+	"memprofilerate":       true,
+	"mutexprofile":         true,
+	"mutexprofilefraction": true,
+	"outputdir":            true,
+	"parallel":             true,
+	"run":                  true,
+	"short":                true,
+	"shuffle":              true,
+	"skip":                 true,
+	"timeout":              true,
+	"trace":                true,
+	"v":                    true,
+}
+
+// Args rewrites `go test` style arguments (e.g. "-run", "-timeout 5m") into
+// the "-test." prefixed form understood by the testing package. Arguments
+// that are not test flags, such as "-specs", are returned unchanged.
+func Args(args []string) []string {
+	rewritten := make([]string, 0, len(args))
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			name := strings.TrimLeft(arg, "-")
+			name, _, _ = strings.Cut(name, "=")
+			if testFlags[name] {
+				arg = "-test." + strings.TrimLeft(arg, "-")
+			}
+		}
+		rewritten = append(rewritten, arg)
+	}
+	return rewritten
+}
+
+// Run runs tests with the given `go test` style arguments, writes their output
+// to w and returns the exit code `go test` would have exited with.
+//
+// The testing package writes directly to os.Stdout, which is redirected to w
+// for the duration of the run. Its flags are global: they are reset to their
+// defaults before the run and restored after it, so that the arguments of a
+// run do not leak into the next one. Run must not be called concurrently.
+func Run(w io.Writer, tests []testing.InternalTest, args []string) (int, error) {
+	m := testing.MainStart(deps{}, recovered(tests), nil, nil, nil)
+
+	restore, err := resetFlags()
+	defer restore()
+	if err != nil {
+		return 2, err
+	}
+
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	err = flag.CommandLine.Parse(Args(args))
+	if err != nil {
+		return 2, err
+	}
+
+	r, pw, err := os.Pipe()
+	if err != nil {
+		return 2, err
+	}
+	defer r.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(w, r)
+		done <- err
+	}()
+
+	stdout := os.Stdout
+	os.Stdout = pw
+	code := m.Run()
+	os.Stdout = stdout
+
+	pw.Close()
+	return code, <-done
+}
+
+// resetFlags sets the flags of the testing package to their defaults, and
+// the timeout to DefaultTimeout. The returned function restores their
+// previous values.
+func resetFlags() (func(), error) {
+	previous := map[string]string{}
+	restore := func() {
+		for name, value := range previous {
+			_ = flag.CommandLine.Set(name, value)
+		}
+	}
+
+	var err error
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		if err != nil || !strings.HasPrefix(f.Name, "test.") {
+			return
+		}
+		previous[f.Name] = f.Value.String()
+
+		value := f.DefValue
+		if f.Name == "test.timeout" {
+			value = DefaultTimeout.String()
+		}
+		// Some defaults, e.g. of -test.fuzztime, cannot be set back.
+		if f.Value.String() == value {
+			return
+		}
+		if e := f.Value.Set(value); e != nil {
+			err = fmt.Errorf("resetting -%s: %w", f.Name, e)
+		}
+	})
+	return restore, err
+}
+
+// recovered wraps the tests so that a panic in the body of a top-level test
+// fails that test instead of crashing the CLI before the reports are
+// written. The subtests of the SugarTests recover their own panics, see
+// tooling/test.
+func recovered(tests []testing.InternalTest) []testing.InternalTest {
+	wrapped := make([]testing.InternalTest, len(tests))
+	for i, test := range tests {
+		f := test.F
+		wrapped[i] = testing.InternalTest{
+			Name: test.Name,
+			F: func(t *testing.T) {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("panic: %v\n%s", r, debug.Stack())
+					}
+				}()
+				f(t)
+			},
+		}
+	}
+	return wrapped
+}
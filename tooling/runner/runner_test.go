@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArgs(t *testing.T) {
+	assert.Equal(t,
+		[]string{"-test.timeout", "30m", "-test.skip=TestGatewayCar/.*", "-specs=+subdomain-gateway", "-test.v", "extra"},
+		Args([]string{"-timeout", "30m", "--skip=TestGatewayCar/.*", "-specs=+subdomain-gateway", "-test.v", "extra"}),
+	)
+}
+
+func TestRunRecoversPanics(t *testing.T) {
+	var out bytes.Buffer
+	code, err := Run(&out, []testing.InternalTest{
+		{Name: "TestPanics", F: func(t *testing.T) { panic("boom") }},
+		{Name: "TestPasses", F: func(t *testing.T) {}},
+	}, []string{"-v"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "--- FAIL: TestPanics")
+	assert.Contains(t, out.String(), "panic: boom")
+	assert.Contains(t, out.String(), "--- PASS: TestPasses")
+}
+
+func TestRunResetsFlags(t *testing.T) {
+	timeout := flag.Lookup("test.timeout").Value.String()
+
+	var ran, timeouts []string
+	record := func(t *testing.T) {
+		ran = append(ran, t.Name())
+		timeouts = append(timeouts, flag.Lookup("test.timeout").Value.String())
+	}
+	tests := []testing.InternalTest{{Name: "TestA", F: record}, {Name: "TestB", F: record}}
+
+	_, err := Run(io.Discard, tests, []string{"-run", "TestA", "-timeout", "1m"})
+	require.NoError(t, err)
+	_, err = Run(io.Discard, tests, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"TestA", "TestA", "TestB"}, ran)
+	assert.Equal(t, []string{"1m0s", DefaultTimeout.String(), DefaultTimeout.String()}, timeouts)
+	// The flags of the process are restored after a run.
+	assert.Equal(t, timeout, flag.Lookup("test.timeout").Value.String())
+}
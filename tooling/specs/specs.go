@@ -72,6 +72,11 @@ func (c Collection) Name() string {
 	return c.name
 }
 
+// Children returns the specs of the collection.
+func (c Collection) Children() []Spec {
+	return c.children
+}
+
 func (c Collection) IsEnabled() bool {
 	for _, s := range c.children {
 		if !s.IsEnabled() {
@@ -0,0 +1,153 @@
+package test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator provides the credentials attached to every request sent to
+// the gateway.
+type Authenticator interface {
+	// Header returns the name and the value of the header carrying the
+	// credentials.
+	Header() (string, string, error)
+}
+
+type headerAuth struct {
+	name, value string
+}
+
+func (a headerAuth) Header() (string, string, error) {
+	return a.name, a.value, nil
+}
+
+// BearerToken authenticates with a static bearer token.
+func BearerToken(token string) Authenticator {
+	return headerAuth{"Authorization", "Bearer " + token}
+}
+
+// BasicAuth authenticates with a username and a password.
+func BasicAuth(username, password string) Authenticator {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return headerAuth{"Authorization", "Basic " + credentials}
+}
+
+// HeaderAuth authenticates with a custom header, e.g. an API key.
+func HeaderAuth(name, value string) Authenticator {
+	return headerAuth{http.CanonicalHeaderKey(name), value}
+}
+
+// TokenCommand authenticates with a bearer token printed by a shell command.
+// The command runs again once the token expires: after ttl, or before the
+// expiry of the token when it is a JWT with an "exp" claim.
+func TokenCommand(command string, ttl time.Duration) Authenticator {
+	return &tokenCommand{command: command, ttl: ttl}
+}
+
+type tokenCommand struct {
+	command string
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// tokenExpiryMargin is how long before its expiry a token is refreshed, so
+// that it does not expire while a request is in flight.
+const tokenExpiryMargin = 30 * time.Second
+
+func (c *tokenCommand) Header() (string, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == "" || !time.Now().Before(c.expires) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command("sh", "-c", c.command)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", "", fmt.Errorf("running the auth command: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+
+		token := strings.TrimSpace(stdout.String())
+		if token == "" {
+			return "", "", fmt.Errorf("the auth command did not print a token")
+		}
+
+		c.token = token
+		c.expires = time.Now().Add(c.ttl)
+		if exp, ok := jwtExpiry(token); ok && exp.Add(-tokenExpiryMargin).Before(c.expires) {
+			c.expires = exp.Add(-tokenExpiryMargin)
+		}
+	}
+
+	return "Authorization", "Bearer " + c.token, nil
+}
+
+// jwtExpiry returns the "exp" claim of token, if it is a JWT.
+func jwtExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+// authorize attaches the credentials of the configured Authenticator to req,
+// unless the test set the header itself.
+func authorize(req *http.Request, builder RequestBuilder) error {
+	if clientConfig.Auth == nil {
+		return nil
+	}
+
+	name, value, err := clientConfig.Auth.Header()
+	if err != nil {
+		return err
+	}
+	if !hasHeader(builder, name) {
+		req.Header.Set(name, value)
+	}
+	return nil
+}
+
+// hasHeader reports whether the test sets the header with the given name.
+func hasHeader(builder RequestBuilder, name string) bool {
+	for key := range builder.Headers_ {
+		if http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact returns a copy of the headers without the credentials of the
+// configured Authenticator, so that they do not end up in the reports.
+func redact(header http.Header) http.Header {
+	header = header.Clone()
+	if clientConfig.Auth == nil {
+		return header
+	}
+	name, _, err := clientConfig.Auth.Header()
+	if err == nil && header.Get(name) != "" {
+		header.Set(name, "REDACTED")
+	}
+	return header
+}
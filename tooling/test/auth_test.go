@@ -0,0 +1,88 @@
+package test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticAuth(t *testing.T) {
+	for _, tc := range []struct {
+		auth        Authenticator
+		name, value string
+	}{
+		{BearerToken("t0k3n"), "Authorization", "Bearer t0k3n"},
+		{BasicAuth("user", "pa:ss"), "Authorization", "Basic dXNlcjpwYTpzcw=="},
+		{HeaderAuth("x-api-key", "secret"), "X-Api-Key", "secret"},
+	} {
+		name, value, err := tc.auth.Header()
+		require.NoError(t, err)
+		assert.Equal(t, tc.name, name)
+		assert.Equal(t, tc.value, value)
+	}
+}
+
+func TestTokenCommand(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "counter")
+	command := fmt.Sprintf(`echo x >> %s; echo "token-$(wc -l < %s | tr -d ' ')"`, counter, counter)
+
+	auth := TokenCommand(command, 50*time.Millisecond)
+
+	_, value, err := auth.Header()
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token-1", value)
+
+	_, value, err = auth.Header()
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token-1", value, "the token is cached until it expires")
+
+	time.Sleep(60 * time.Millisecond)
+	_, value, err = auth.Header()
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token-2", value)
+}
+
+func TestTokenCommandJWTExpiry(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp)))
+	jwt := "eyJhbGciOiJub25lIn0." + payload + ".c2ln"
+
+	auth := TokenCommand("echo "+jwt, 24*time.Hour).(*tokenCommand)
+	_, value, err := auth.Header()
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer "+jwt, value)
+	assert.Equal(t, time.Unix(exp, 0).Add(-tokenExpiryMargin), auth.expires)
+}
+
+func TestTokenCommandFailure(t *testing.T) {
+	_, _, err := TokenCommand("echo nope >&2; exit 3", time.Minute).Header()
+	assert.ErrorContains(t, err, "nope")
+
+	_, _, err = TokenCommand("true", time.Minute).Header()
+	assert.ErrorContains(t, err, "did not print a token")
+}
+
+func TestAuthorize(t *testing.T) {
+	config := DefaultClientConfig
+	config.Auth = BearerToken("t0k3n")
+	configure(t, config)
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+	require.NoError(t, authorize(req, Request()))
+	assert.Equal(t, "Bearer t0k3n", req.Header.Get("Authorization"))
+	assert.Equal(t, "REDACTED", redact(req.Header).Get("Authorization"))
+	assert.Equal(t, "Bearer t0k3n", req.Header.Get("Authorization"), "redact does not modify the request")
+
+	// Tests setting their own Authorization header keep it.
+	req, err = http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+	require.NoError(t, authorize(req, Request().Header("authorization", "Basic Zm9vOmJhcg==")))
+	assert.Empty(t, req.Header.Get("Authorization"))
+}
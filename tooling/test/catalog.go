@@ -0,0 +1,170 @@
+package test
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling"
+	"github.com/ipfs/gateway-conformance/tooling/catalog"
+	"github.com/ipfs/gateway-conformance/tooling/specs"
+)
+
+// ListOnly makes RunWithSpecs add the SugarTests to the catalog instead of
+// running them, no request is sent. Every test is listed, whether the specs
+// it requires are enabled or not.
+var ListOnly = false
+
+// lister collects the catalog entries of the SugarTests in ListOnly mode.
+type lister struct {
+	mu      sync.Mutex
+	entries []catalog.Entry
+}
+
+var listed = &lister{}
+
+// Catalog returns the catalog entries of every SugarTest listed so far.
+func Catalog() []catalog.Entry {
+	listed.mu.Lock()
+	defer listed.mu.Unlock()
+
+	return slices.Clone(listed.entries)
+}
+
+// list adds the tests to the catalog. Each test still runs as a subtest of t,
+// so that it gets its final path and the -run and -skip flags apply.
+func (l *lister) list(t *testing.T, tests SugarTests, required []specs.Leaf) {
+	t.Helper()
+
+	var gates []string
+	for _, spec := range required {
+		gates = append(gates, spec.Name())
+	}
+
+	parent := t
+	for _, test := range tests {
+		t.Run(safeName(test.Name), func(t *testing.T) {
+			if !isSelected(parent, t, test) {
+				t.Skip(notSelected)
+			}
+			defer recoverPanic(t)
+			entry := catalogEntry(t, test, gates)
+
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			l.entries = append(l.entries, entry)
+		})
+	}
+}
+
+func catalogEntry(t *testing.T, test SugarTest, gates []string) catalog.Entry {
+	requests := test.Requests
+	if len(requests) == 0 {
+		requests = []RequestBuilder{test.Request}
+	}
+
+	entry := catalog.Entry{
+		Name:  test.Name,
+		Path:  t.Name(),
+		Group: tooling.TestGroup(t.Name()),
+		Specs: testSpecs(t.Name(), test),
+		Gates: gates,
+	}
+	for _, r := range requests {
+		method := r.Method_
+		if method == "" {
+			method = "GET"
+		}
+		entry.Requests = append(entry.Requests, catalog.Request{
+			Method:          method,
+			Path:            r.Path_,
+			Query:           r.Query_,
+			Headers:         r.Headers_,
+			Proxy:           r.Proxy_,
+			ProxyTunnel:     r.UseProxyTunnel_,
+			FollowRedirects: r.FollowRedirects_,
+		})
+	}
+	if test.Response != nil {
+		entry.Status = expectStatus(test.Response)
+	}
+
+	return entry
+}
+
+// testSpecs returns the specs of the test running as name: the specs logged
+// by its top-level test, its own specs, and the specs of its expected
+// response and of its header checks.
+func testSpecs(name string, test SugarTest) []string {
+	all := slices.Concat(tooling.TestSpecs(name), test.AllSpecs())
+	if test.Response != nil {
+		all = append(all, expectSpecs(test.Response)...)
+	}
+
+	// Keep the first occurrence of each spec.
+	var specs []string
+	for _, spec := range all {
+		if !slices.Contains(specs, spec) {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// expectSpecs returns the specs of an expected response and of its headers.
+func expectSpecs(e ExpectValidator) []string {
+	var specs []string
+	switch e := e.(type) {
+	case ExpectBuilder:
+		specs = append(specs, e.Specs_...)
+		for _, h := range e.Headers_ {
+			specs = append(specs, h.Specs_...)
+		}
+	case AllOfExpectBuilder:
+		for _, expect := range e.Expect_ {
+			specs = append(specs, expectSpecs(expect)...)
+		}
+	case AnyOfExpectBuilder:
+		for _, expect := range e.Expect_ {
+			specs = append(specs, expectSpecs(expect)...)
+		}
+	}
+	return specs
+}
+
+// expectStatus returns the status expected by e: a single code, a range
+// such as "400-499", or the alternatives of AnyOf separated by "|".
+func expectStatus(e ExpectValidator) string {
+	switch e := e.(type) {
+	case ExpectBuilder:
+		switch {
+		case e.StatusCode_ != 0:
+			return fmt.Sprint(e.StatusCode_)
+		case e.StatusCodeFrom_ != 0 || e.StatusCodeTo_ != 0:
+			return fmt.Sprintf("%d-%d", e.StatusCodeFrom_, e.StatusCodeTo_)
+		}
+	case AllOfExpectBuilder:
+		for _, expect := range e.Expect_ {
+			if status := expectStatus(expect); status != "" {
+				return status
+			}
+		}
+	case AnyOfExpectBuilder:
+		var statuses []string
+		for _, expect := range e.Expect_ {
+			status := expectStatus(expect)
+			if status == "" {
+				// Any status is valid.
+				return ""
+			}
+			if !slices.Contains(statuses, status) {
+				statuses = append(statuses, status)
+			}
+		}
+		return strings.Join(statuses, "|")
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling/catalog"
+	"github.com/ipfs/gateway-conformance/tooling/specs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListOnly(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL)
+	}))
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+
+	defer func(listOnly bool) { ListOnly = listOnly }(ListOnly)
+	ListOnly = true
+
+	tests := SugarTests{
+		{
+			Name: "GET with range",
+			Spec: "https://specs.ipfs.tech/http-gateways/path-gateway/#range-request-header",
+			Request: Request().
+				Path("/ipfs/cid").
+				Query("format", "raw").
+				Header("Range", "bytes=0-1"),
+			Response: Expect().
+				Status(206).
+				Headers(
+					Header("Content-Range").
+						Spec("https://specs.ipfs.tech/http-gateways/path-gateway/#content-range-response-header"),
+				),
+		},
+		{
+			Name: "HEAD with any of",
+			Requests: Requests(
+				Request().Method("HEAD").Path("/ipfs/a"),
+				Request().Method("HEAD").Path("/ipfs/b"),
+			),
+			Response: AnyOf(
+				Expect().Status(200),
+				Expect().StatusBetween(400, 499),
+			),
+		},
+	}
+	RunWithSpecs(t, tests, specs.PathGatewayRaw, specs.ProxyGateway)
+
+	var entries []catalog.Entry
+	for _, e := range Catalog() {
+		if strings.HasPrefix(e.Path, t.Name()+"/") {
+			entries = append(entries, e)
+		}
+	}
+	assert.Equal(t, []catalog.Entry{
+		{
+			Name: "GET with range",
+			Path: t.Name() + "/GET_with_range",
+			Specs: []string{
+				"https://specs.ipfs.tech/http-gateways/path-gateway/#range-request-header",
+				"https://specs.ipfs.tech/http-gateways/path-gateway/#content-range-response-header",
+			},
+			Gates: []string{"path-raw-gateway", "proxy-gateway"},
+			Requests: []catalog.Request{{
+				Method:  "GET",
+				Path:    "/ipfs/cid",
+				Query:   map[string][]string{"format": {"raw"}},
+				Headers: map[string]string{"Range": "bytes=0-1"},
+			}},
+			Status: "206",
+		},
+		{
+			Name:  "HEAD with any of",
+			Path:  t.Name() + "/HEAD_with_any_of",
+			Gates: []string{"path-raw-gateway", "proxy-gateway"},
+			Requests: []catalog.Request{
+				{Method: "HEAD", Path: "/ipfs/a", Query: map[string][]string{}},
+				{Method: "HEAD", Path: "/ipfs/b", Query: map[string][]string{}},
+			},
+			Status: "200|400-499",
+		},
+	}, entries)
+}
@@ -0,0 +1,300 @@
+package test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2"
+)
+
+// HTTP versions supported by ClientConfig.HTTPVersion.
+const (
+	// HTTP1 only uses HTTP/1.1, even when the gateway supports HTTP/2.
+	HTTP1 = "1.1"
+	// HTTP2 only uses HTTP/2 over TLS, negotiated with ALPN.
+	HTTP2 = "2"
+	// H2C only uses HTTP/2 over cleartext TCP, with prior knowledge.
+	H2C = "h2c"
+	// HTTP3 only uses HTTP/3 over QUIC.
+	HTTP3 = "3"
+)
+
+var HTTPVersions = []string{HTTP1, HTTP2, H2C, HTTP3}
+
+// ClientConfig configures how the tests send their requests.
+type ClientConfig struct {
+	// RequestTimeout bounds a single request, including reading the
+	// response body.
+	RequestTimeout time.Duration
+	// ConnectTimeout bounds establishing a connection to the gateway.
+	ConnectTimeout time.Duration
+	// Retries is the number of times a request that failed with a network
+	// error is sent again. Requests that got a response are never retried.
+	Retries int
+	// RetryBackoff is the delay before the first retry, it doubles with
+	// every retry.
+	RetryBackoff time.Duration
+	// MaxIdleConns is the maximum number of idle connections kept open to
+	// the gateway.
+	MaxIdleConns int
+	// HTTPVersion is one of HTTPVersions. When empty, HTTP/1.1 is used, or
+	// HTTP/2 when the gateway negotiates it over TLS.
+	HTTPVersion string
+	// CACert is the path of a PEM bundle of certificate authorities trusted
+	// in addition to the system ones.
+	CACert string
+	// ClientCert and ClientKey are the paths of the PEM certificate and key
+	// presented to gateways that require mutual TLS.
+	ClientCert string
+	ClientKey  string
+	// Insecure skips the verification of the gateway certificate.
+	Insecure bool
+	// TLSServerName overrides the server name sent with SNI and used to
+	// verify the gateway certificate.
+	TLSServerName string
+	// Auth, when set, provides the credentials attached to every request
+	// that does not set its own.
+	Auth Authenticator
+	// Headers are added to every request that does not set them itself.
+	Headers http.Header
+	// RecordDir, when set, is the directory where every response is
+	// recorded, so that it can be replayed later with ReplayDir.
+	RecordDir string
+	// ReplayDir, when set, is the directory of the responses served instead
+	// of sending the requests to the gateway.
+	ReplayDir string
+}
+
+var DefaultClientConfig = ClientConfig{
+	RequestTimeout: 2 * time.Minute,
+	ConnectTimeout: 30 * time.Second,
+	Retries:        0,
+	RetryBackoff:   time.Second,
+	MaxIdleConns:   100,
+}
+
+var (
+	clientConfig = DefaultClientConfig
+	// tlsConfig is used by every client, including the proxy clients.
+	tlsConfig, _ = newTLSConfig(DefaultClientConfig)
+	// transport is shared by every request that does not go through a
+	// proxy, so that connections to the gateway are reused across tests.
+	transport, _ = newTransport(DefaultClientConfig, tlsConfig)
+)
+
+// Configure sets how the tests send their requests. It must be called before
+// the tests run.
+func Configure(config ClientConfig) error {
+	tc, err := newTLSConfig(config)
+	if err != nil {
+		return err
+	}
+	t, err := newTransport(config, tc)
+	if err != nil {
+		return err
+	}
+	tp, err := newTape(config)
+	if err != nil {
+		return err
+	}
+	clientConfig = config
+	tlsConfig = tc
+	transport = t
+	activeTape = tp
+	return nil
+}
+
+func newTLSConfig(config ClientConfig) (*tls.Config, error) {
+	tc := &tls.Config{
+		ServerName:         config.TLSServerName,
+		InsecureSkipVerify: config.Insecure,
+	}
+
+	if config.CACert != "" {
+		pem, err := os.ReadFile(config.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA certificates: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", config.CACert)
+		}
+		tc.RootCAs = roots
+	}
+
+	if config.ClientCert != "" || config.ClientKey != "" {
+		if config.ClientCert == "" || config.ClientKey == "" {
+			return nil, fmt.Errorf("a client certificate requires both a certificate and a key")
+		}
+		cert, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading the client certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+
+	return tc, nil
+}
+
+func newTransport(config ClientConfig, tc *tls.Config) (http.RoundTripper, error) {
+	return newProxyTransport(config, tc, nil, false)
+}
+
+// newProxyTransport returns a transport sending the requests through proxy,
+// or through CONNECT tunnels opened on proxy when tunnel is set. A nil proxy
+// sends the requests to the gateway directly.
+func newProxyTransport(config ClientConfig, tc *tls.Config, proxy *url.URL, tunnel bool) (http.RoundTripper, error) {
+	dialer := &net.Dialer{
+		Timeout:   config.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	dial := dialer.DialContext
+	// proxyTC is the TLS configuration of the connections to the proxy
+	// itself, rather than to the gateway.
+	proxyTC := tc
+	if proxy != nil {
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, proxyAddr(proxy))
+			if err != nil || !tunnel {
+				return conn, err
+			}
+			return connectTunnel(conn, addr)
+		}
+		if !tunnel && tc.ServerName == "" {
+			proxyTC = tc.Clone()
+			proxyTC.ServerName = proxy.Hostname()
+		}
+	}
+
+	switch config.HTTPVersion {
+	case "", HTTP1:
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.DialContext = dial
+		t.MaxIdleConns = config.MaxIdleConns
+		t.MaxIdleConnsPerHost = config.MaxIdleConns
+		t.TLSClientConfig = tc.Clone()
+		if proxy != nil && !tunnel {
+			// Requests are sent to the proxy in absolute-form.
+			t.DialContext = dialer.DialContext
+			t.Proxy = http.ProxyURL(proxy)
+		}
+		if config.HTTPVersion == HTTP1 {
+			// A non-nil, empty TLSNextProto disables HTTP/2.
+			t.ForceAttemptHTTP2 = false
+			t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		}
+		return t, nil
+	case HTTP2:
+		return &http2.Transport{
+			TLSClientConfig: proxyTC.Clone(),
+			DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+				conn, err := dial(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				tlsConn := tls.Client(conn, cfg)
+				if err := tlsConn.HandshakeContext(ctx); err != nil {
+					conn.Close()
+					return nil, err
+				}
+				return tlsConn, nil
+			},
+		}, nil
+	case H2C:
+		return &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		}, nil
+	case HTTP3:
+		if tunnel {
+			// CONNECT tunnels are opened over TCP, the requests sent
+			// through them use HTTP/1.1.
+			config.HTTPVersion = HTTP1
+			return newProxyTransport(config, tc, proxy, tunnel)
+		}
+		t := &http3.Transport{
+			TLSClientConfig: proxyTC.Clone(),
+			QUICConfig: &quic.Config{
+				HandshakeIdleTimeout: config.ConnectTimeout,
+			},
+		}
+		if proxy != nil {
+			t.Dial = func(ctx context.Context, _ string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+				return quic.DialAddrEarly(ctx, proxyAddr(proxy), tlsCfg, cfg)
+			}
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unsupported HTTP version %q, expected one of %s", config.HTTPVersion, strings.Join(HTTPVersions, ", "))
+	}
+}
+
+// proxyAddr returns the host and port of the proxy.
+func proxyAddr(proxy *url.URL) string {
+	if proxy.Port() != "" {
+		return proxy.Host
+	}
+	if proxy.Scheme == "https" {
+		return net.JoinHostPort(proxy.Hostname(), "443")
+	}
+	return net.JoinHostPort(proxy.Hostname(), "80")
+}
+
+// newClient returns a client using the shared transport.
+func newClient() *http.Client {
+	return &http.Client{
+		Transport: transport,
+		Timeout:   clientConfig.RequestTimeout,
+	}
+}
+
+// Client returns a client sending requests to the gateway the way the tests
+// do, see Configure.
+func Client() *http.Client {
+	return newClient()
+}
+
+// send sends req, retrying on network errors as configured. It returns the
+// number of attempts made.
+func send(t *testing.T, client *http.Client, req *http.Request) (*http.Response, int, error) {
+	backoff := clientConfig.RetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		res, err := client.Do(req)
+		if err == nil || attempt > clientConfig.Retries || req.Context().Err() != nil {
+			return res, attempt, err
+		}
+
+		t.Logf("Querying %s failed, retrying in %s: %s", req.URL, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-req.Context().Done():
+			return nil, attempt, req.Context().Err()
+		}
+		backoff *= 2
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, attempt, err
+			}
+			req.Body = body
+		}
+	}
+}
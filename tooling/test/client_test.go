@@ -0,0 +1,389 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// flakyServer drops the connection of the first failures requests.
+func flakyServer(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if assert.NoError(t, err) {
+				conn.Close()
+			}
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func configure(t *testing.T, config ClientConfig) {
+	t.Cleanup(func() { Configure(DefaultClientConfig) })
+	require.NoError(t, Configure(config))
+}
+
+func TestSendRetriesNetworkErrors(t *testing.T) {
+	srv, requests := flakyServer(t, 2)
+
+	config := DefaultClientConfig
+	config.Retries = 3
+	config.RetryBackoff = time.Millisecond
+	configure(t, config)
+
+	req, err := http.NewRequest("POST", srv.URL, strings.NewReader("body"))
+	require.NoError(t, err)
+
+	res, attempts, err := send(t, newClient(), req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestSendGivesUpAfterRetries(t *testing.T) {
+	srv, requests := flakyServer(t, 10)
+
+	config := DefaultClientConfig
+	config.Retries = 1
+	config.RetryBackoff = time.Millisecond
+	configure(t, config)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+
+	_, attempts, err := send(t, newClient(), req)
+	assert.Error(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestSendRequestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	config := DefaultClientConfig
+	config.RequestTimeout = 20 * time.Millisecond
+	configure(t, config)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+
+	_, attempts, err := send(t, newClient(), req)
+	assert.ErrorContains(t, err, "Client.Timeout exceeded")
+	assert.Equal(t, 1, attempts)
+}
+
+func TestHTTPVersions(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	h2cServer := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer h2cServer.Close()
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	h3Server := &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: tlsServer.TLS.Certificates}),
+	}
+	go h3Server.Serve(udp)
+	defer h3Server.Close()
+
+	caCert := writePEM(t, "CERTIFICATE", tlsServer.Certificate().Raw)
+
+	for _, tc := range []struct {
+		version string
+		url     string
+		proto   string
+	}{
+		{"", tlsServer.URL, "HTTP/2.0"},
+		{HTTP1, tlsServer.URL, "HTTP/1.1"},
+		{HTTP2, tlsServer.URL, "HTTP/2.0"},
+		{H2C, h2cServer.URL, "HTTP/2.0"},
+		{HTTP3, "https://" + udp.LocalAddr().String(), "HTTP/3.0"},
+	} {
+		t.Run(tc.version, func(t *testing.T) {
+			config := DefaultClientConfig
+			config.HTTPVersion = tc.version
+			config.CACert = caCert
+			configure(t, config)
+
+			req, err := http.NewRequest("GET", tc.url, nil)
+			require.NoError(t, err)
+
+			res, _, err := send(t, newClient(), req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.proto, res.Proto)
+			assert.Equal(t, tc.proto, string(body))
+		})
+	}
+}
+
+func TestHTTPVersionUnsupported(t *testing.T) {
+	config := DefaultClientConfig
+	config.HTTPVersion = "1.0"
+	assert.ErrorContains(t, Configure(config), `unsupported HTTP version "1.0"`)
+}
+
+// connListener is a net.Listener accepting a single connection.
+type connListener struct {
+	conn net.Conn
+	once sync.Once
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	var conn net.Conn
+	l.once.Do(func() { conn = l.conn })
+	if conn == nil {
+		return nil, net.ErrClosed
+	}
+	return conn, nil
+}
+
+func (l *connListener) Close() error   { return nil }
+func (l *connListener) Addr() net.Addr { return l.conn.LocalAddr() }
+
+func TestProxyClients(t *testing.T) {
+	var handler http.Handler
+	handler = h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			fmt.Fprintf(w, "%s %s", r.Proto, r.Host)
+			return
+		}
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		_ = (&http.Server{Handler: handler}).Serve(&connListener{conn: conn})
+	}), &http2.Server{})
+	proxy := httptest.NewServer(handler)
+	defer proxy.Close()
+
+	for _, tc := range []struct {
+		version string
+		tunnel  bool
+		proto   string
+	}{
+		{"", false, "HTTP/1.1"},
+		{H2C, false, "HTTP/2.0"},
+		{HTTP1, true, "HTTP/1.1"},
+		{H2C, true, "HTTP/2.0"},
+		{HTTP3, true, "HTTP/1.1"},
+	} {
+		t.Run(fmt.Sprintf("%s tunnel=%t", tc.version, tc.tunnel), func(t *testing.T) {
+			config := DefaultClientConfig
+			config.HTTPVersion = tc.version
+			config.RequestTimeout = 10 * time.Second
+			configure(t, config)
+
+			client := NewProxyClient(proxy.URL)
+			if tc.tunnel {
+				client = NewProxyTunnelClient(proxy.URL)
+			}
+			assert.Equal(t, config.RequestTimeout, client.Timeout)
+
+			req, err := http.NewRequest("GET", "http://example.com/ipfs/cid", nil)
+			require.NoError(t, err)
+			res, _, err := send(t, client, req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.proto+" example.com", string(body))
+		})
+	}
+}
+
+// writePEM writes a single PEM block to a temporary file and returns its path.
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), strings.ToLower(strings.ReplaceAll(blockType, " ", "-"))+".pem")
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0644)
+	require.NoError(t, err)
+	return path
+}
+
+// clientCertificate generates a self-signed client certificate and returns
+// the paths of its certificate and key.
+func clientCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gateway-conformance"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	return writePEM(t, "CERTIFICATE", der), writePEM(t, "PRIVATE KEY", keyDER)
+}
+
+func TestTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.ServerName))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caCert := writePEM(t, "CERTIFICATE", srv.Certificate().Raw)
+	clientCert, clientKey := clientCertificate(t)
+
+	for _, tc := range []struct {
+		name       string
+		config     func(*ClientConfig)
+		serverName string
+		err        string
+	}{
+		{
+			name: "unknown authority",
+			config: func(c *ClientConfig) {
+				c.ClientCert, c.ClientKey = clientCert, clientKey
+			},
+			err: "certificate signed by unknown authority",
+		},
+		{
+			name: "missing client certificate",
+			config: func(c *ClientConfig) {
+				c.CACert = caCert
+			},
+			err: "certificate required",
+		},
+		{
+			name: "custom CA and client certificate",
+			config: func(c *ClientConfig) {
+				c.CACert = caCert
+				c.ClientCert, c.ClientKey = clientCert, clientKey
+			},
+		},
+		{
+			name: "insecure",
+			config: func(c *ClientConfig) {
+				c.Insecure = true
+				c.ClientCert, c.ClientKey = clientCert, clientKey
+			},
+		},
+		{
+			name: "server name",
+			config: func(c *ClientConfig) {
+				c.CACert = caCert
+				c.ClientCert, c.ClientKey = clientCert, clientKey
+				c.TLSServerName = "example.com"
+			},
+			serverName: "example.com",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := DefaultClientConfig
+			tc.config(&config)
+			configure(t, config)
+
+			req, err := http.NewRequest("GET", srv.URL, nil)
+			require.NoError(t, err)
+
+			res, _, err := send(t, newClient(), req)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.serverName, string(body))
+		})
+	}
+}
+
+func TestTLSInvalidOptions(t *testing.T) {
+	config := DefaultClientConfig
+	config.ClientCert = "cert.pem"
+	assert.ErrorContains(t, Configure(config), "requires both a certificate and a key")
+
+	config = DefaultClientConfig
+	config.CACert = filepath.Join(t.TempDir(), "missing.pem")
+	assert.ErrorContains(t, Configure(config), "reading CA certificates")
+}
+
+func TestHeaders(t *testing.T) {
+	var deployments, accepts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deployments = append(deployments, r.Header.Get("X-Deployment"))
+		accepts = append(accepts, r.Header.Get("Accept"))
+	}))
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+
+	config := DefaultClientConfig
+	config.Headers = http.Header{"X-Deployment": {"staging"}, "Accept": {"*/*"}}
+	configure(t, config)
+
+	t.Run("suite", func(t *testing.T) {
+		RunWithSpecs(t, SugarTests{
+			{
+				Name:     "GET",
+				Request:  Request().Path("/"),
+				Response: Expect(),
+			},
+			{
+				Name:     "GET with its own Accept header",
+				Request:  Request().Path("/").Header("accept", "application/vnd.ipld.raw"),
+				Response: Expect(),
+			},
+		})
+	})
+
+	assert.Equal(t, []string{"staging", "staging"}, deployments)
+	assert.Equal(t, []string{"*/*", "application/vnd.ipld.raw"}, accepts)
+}
@@ -0,0 +1,197 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling"
+)
+
+// Filter selects the SugarTests to run. A test runs when it matches every
+// criteria that is set.
+type Filter struct {
+	// Run matches the name or the full path of the tests to run.
+	Run *regexp.Regexp
+	// Skip matches the name or the full path of the tests not to run.
+	Skip *regexp.Regexp
+	// Groups lists the groups of the tests to run, see tooling.LogTestGroup.
+	// Groups are compared case-insensitively.
+	Groups []string
+	// SpecURLs lists prefixes of the spec URLs of the tests to run, it is
+	// matched against the specs of the test, of its top-level test, of its
+	// expected response and of its header checks.
+	SpecURLs []string
+	// Paths lists the full paths of the tests to run.
+	Paths []string
+	// SkipPaths lists the full paths of the tests not to run.
+	SkipPaths []string
+}
+
+var filter Filter
+
+// NewFilter returns the filter of the given patterns, group names and spec
+// URL prefixes, empty values are ignored.
+func NewFilter(run, skip string, groups, specURLs []string) (Filter, error) {
+	var f Filter
+	for _, group := range groups {
+		if group != "" {
+			f.Groups = append(f.Groups, group)
+		}
+	}
+	for _, url := range specURLs {
+		if url != "" {
+			f.SpecURLs = append(f.SpecURLs, url)
+		}
+	}
+
+	var err error
+	if run != "" {
+		f.Run, err = regexp.Compile(run)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid run pattern: %w", err)
+		}
+	}
+	if skip != "" {
+		f.Skip, err = regexp.Compile(skip)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid skip pattern: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// Select sets the filter of the SugarTests run by RunWithSpecs.
+func Select(f Filter) {
+	filter = f
+}
+
+// IsSet reports whether the filter excludes any test.
+func (f Filter) IsSet() bool {
+	return f.Run != nil || f.Skip != nil || len(f.Groups) > 0 || len(f.SpecURLs) > 0 ||
+		len(f.Paths) > 0 || len(f.SkipPaths) > 0
+}
+
+// Match reports whether the test, which runs as the subtest with the given
+// path, is selected by the filter. The path is the name of the running
+// subtest, as given by t.Name().
+func (f Filter) Match(path string, test SugarTest) bool {
+	return f.matchPath(path, test.Name) && f.matchTest(path, test)
+}
+
+// matchPath reports whether the test with the given name, running as the
+// subtest with the given path, is selected by the criteria on names and
+// paths.
+func (f Filter) matchPath(path, name string) bool {
+	if f.Run != nil && !f.Run.MatchString(name) && !f.Run.MatchString(path) {
+		return false
+	}
+	if f.Skip != nil && (f.Skip.MatchString(name) || f.Skip.MatchString(path)) {
+		return false
+	}
+	if len(f.Paths) > 0 && !slices.Contains(f.Paths, path) {
+		return false
+	}
+	if slices.Contains(f.SkipPaths, path) {
+		return false
+	}
+	return true
+}
+
+// matchTest reports whether the test is selected by the criteria that do
+// not depend on its own name, its groups and specs. parent is the path of
+// the test, or of any test under the same top-level test.
+func (f Filter) matchTest(parent string, test SugarTest) bool {
+	if len(f.Groups) > 0 {
+		group := tooling.TestGroup(parent)
+		found := false
+		for _, g := range f.Groups {
+			if strings.EqualFold(g, group) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.SpecURLs) > 0 {
+		found := false
+		for _, prefix := range f.SpecURLs {
+			for _, spec := range testSpecs(parent, test) {
+				if strings.HasPrefix(spec, prefix) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// notSelected is the reason of the SugarTests skipped by the filter.
+const notSelected = "not selected by the filter"
+
+// selections records, by test running SugarTests, whether the filter
+// selected any of them.
+var selections = struct {
+	sync.Mutex
+	selected map[*testing.T]bool
+}{selected: map[*testing.T]bool{}}
+
+// selected returns the tests of t that may be selected by the filter, the
+// names and paths are matched once the tests run, see isSelected. A
+// top-level test may call RunWithSpecs several times, t is skipped once it
+// completes when none of the tests of its calls was selected.
+func selected(t *testing.T, tests SugarTests) SugarTests {
+	if !filter.IsSet() {
+		return tests
+	}
+
+	selections.Lock()
+	if _, ok := selections.selected[t]; !ok {
+		selections.selected[t] = false
+		t.Cleanup(func() {
+			selections.Lock()
+			selected := selections.selected[t]
+			delete(selections.selected, t)
+			selections.Unlock()
+
+			if !selected {
+				t.Skip("no test matches the filter")
+			}
+		})
+	}
+	selections.Unlock()
+
+	var s SugarTests
+	for _, test := range tests {
+		if filter.matchTest(t.Name(), test) {
+			s = append(s, test)
+		}
+	}
+	return s
+}
+
+// isSelected reports whether the filter selects the test running as sub, a
+// subtest of parent, and records that parent selected a test.
+func isSelected(parent, sub *testing.T, test SugarTest) bool {
+	if !filter.IsSet() {
+		return true
+	}
+	if !filter.matchPath(sub.Name(), test.Name) {
+		return false
+	}
+
+	selections.Lock()
+	defer selections.Unlock()
+	selections.selected[parent] = true
+	return true
+}
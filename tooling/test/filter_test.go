@@ -0,0 +1,167 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	// Groups are logged by top-level tests.
+	tooling.LogTestGroup(t, "Block-CAR")
+
+	tests := SugarTests{
+		{
+			Name:     "GET raw block",
+			Spec:     "https://specs.ipfs.tech/http-gateways/trustless-gateway/#block-responses-application-vnd-ipld-raw",
+			Request:  Request().Path("/raw"),
+			Response: Expect(),
+		},
+		{
+			Name:    "GET with cache headers",
+			Request: Request().Path("/cache"),
+			Response: Expect().Headers(
+				Header("Cache-Control", "public").Spec("https://specs.ipfs.tech/http-gateways/path-gateway/#cache-control-response-header"),
+			),
+		},
+		{
+			Name:     "GET without spec",
+			Request:  Request().Path("/none"),
+			Response: Expect(),
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		run      string
+		skip     string
+		groups   []string
+		specURLs []string
+		// paths and skipPaths are relative to the suite test.
+		paths     []string
+		skipPaths []string
+		expected  []string
+	}{
+		{name: "no filter", expected: []string{"/raw", "/cache", "/none"}},
+		{name: "run name", run: "cache", expected: []string{"/cache"}},
+		{name: "run path", run: "^TestFilter/.*/GET_without", expected: []string{"/none"}},
+		{name: "skip", skip: "raw|cache", expected: []string{"/none"}},
+		{name: "group", groups: []string{"block-car"}, expected: []string{"/raw", "/cache", "/none"}},
+		{name: "other group", groups: []string{"IPNS"}, expected: nil},
+		{name: "test spec", specURLs: []string{"https://specs.ipfs.tech/http-gateways/trustless-gateway/"}, expected: []string{"/raw"}},
+		{name: "header spec", specURLs: []string{"https://specs.ipfs.tech/http-gateways/path-gateway/#cache-control"}, expected: []string{"/cache"}},
+		{name: "run and spec", run: "raw", specURLs: []string{"https://specs.ipfs.tech/http-gateways/path-gateway/"}, expected: nil},
+		{name: "paths", paths: []string{"GET_raw_block", "GET_without_spec"}, expected: []string{"/raw", "/none"}},
+		{name: "skip paths", skipPaths: []string{"GET_raw_block"}, expected: []string{"/cache", "/none"}},
+		{name: "run and paths", run: "cache", paths: []string{"GET_raw_block"}, expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var paths []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				paths = append(paths, r.URL.Path)
+				w.Header().Set("Cache-Control", "public")
+			}))
+			defer srv.Close()
+			t.Setenv("GATEWAY_URL", srv.URL)
+
+			f, err := NewFilter(tc.run, tc.skip, tc.groups, tc.specURLs)
+			require.NoError(t, err)
+			for _, p := range tc.paths {
+				f.Paths = append(f.Paths, t.Name()+"/suite/"+p)
+			}
+			for _, p := range tc.skipPaths {
+				f.SkipPaths = append(f.SkipPaths, t.Name()+"/suite/"+p)
+			}
+			defer Select(Filter{})
+			Select(f)
+
+			t.Run("suite", func(t *testing.T) {
+				RunWithSpecs(t, tests)
+			})
+			assert.Equal(t, tc.expected, paths)
+		})
+	}
+}
+
+func TestFilterSeveralCalls(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+	}))
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+
+	f, err := NewFilter("second", "", nil, nil)
+	require.NoError(t, err)
+	defer Select(Filter{})
+	Select(f)
+
+	suite := func(t *testing.T) {
+		RunWithSpecs(t, SugarTests{{Name: "GET first", Request: Request().Path("/first"), Response: Expect()}})
+		RunWithSpecs(t, SugarTests{{Name: "GET second", Request: Request().Path("/second"), Response: Expect()}})
+	}
+
+	// Only the tests of the second call match, the first call must not
+	// skip the suite.
+	var matching, other *testing.T
+	t.Run("suite", func(t *testing.T) {
+		matching = t
+		suite(t)
+	})
+	assert.Equal(t, []string{"/second"}, paths)
+	assert.False(t, matching.Skipped())
+
+	// A suite without any selected test is skipped, it does not pass
+	// without a check.
+	paths = nil
+	f, err = NewFilter("third", "", nil, nil)
+	require.NoError(t, err)
+	Select(f)
+	t.Run("other", func(t *testing.T) {
+		other = t
+		suite(t)
+	})
+	assert.Empty(t, paths)
+	assert.True(t, other.Skipped())
+}
+
+func TestFilterMatchesSubtestNames(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+	}))
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+
+	// The testing package makes the names of the subtests unique, the
+	// paths of the reports include the suffix.
+	defer Select(Filter{})
+	Select(Filter{Paths: []string{t.Name() + "/GET_same_name#01"}})
+
+	RunWithSpecs(t, SugarTests{
+		{Name: "GET same name", Request: Request().Path("/first"), Response: Expect()},
+		{Name: "GET same name", Request: Request().Path("/second"), Response: Expect()},
+	})
+	assert.Equal(t, []string{"/second"}, paths)
+}
+
+func TestNewFilterInvalidPattern(t *testing.T) {
+	_, err := NewFilter("(", "", nil, nil)
+	assert.Error(t, err)
+}
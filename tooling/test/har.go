@@ -0,0 +1,300 @@
+package test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ipfs/gateway-conformance/tooling"
+	"github.com/ipfs/gateway-conformance/tooling/har"
+)
+
+// harBodyLimit is the maximum number of bytes of a body recorded in the HAR
+// entries, recording is disabled when it is negative.
+var harBodyLimit = -1
+
+// RecordHAR records every request sent by the tests, and its response, so
+// that they can be exported with HAR. Bodies are truncated to bodyLimit
+// bytes. It must be called before the tests run.
+func RecordHAR(bodyLimit int) {
+	harBodyLimit = max(bodyLimit, 0)
+}
+
+// HAR returns the requests recorded so far, with a page per SugarTest.
+func HAR() har.HAR {
+	recorded.mu.Lock()
+	defer recorded.mu.Unlock()
+
+	log := har.Log{
+		Version: har.Version,
+		Creator: har.Creator{Name: "gateway-conformance", Version: tooling.Version},
+	}
+	for _, test := range recorded.tests {
+		entries := recorded.har[test.Path]
+		if len(entries) == 0 {
+			continue
+		}
+		log.Pages = append(log.Pages, har.Page{
+			StartedDateTime: entries[0].StartedDateTime,
+			ID:              test.Path,
+			Title:           test.Name,
+			PageTimings:     har.PageTimings{OnContentLoad: -1, OnLoad: -1},
+			Comment:         test.Hint,
+		})
+		log.Entries = append(log.Entries, entries...)
+	}
+	return har.HAR{Log: log}
+}
+
+// harTrace collects the timings of a request.
+type harTrace struct {
+	mu                      sync.Mutex
+	start                   time.Time
+	dnsStart, dnsDone       time.Time
+	connectStart, connected time.Time
+	tlsStart, tlsDone       time.Time
+	gotConn, wroteRequest   time.Time
+	firstByte, end          time.Time
+}
+
+func newHARTrace() *harTrace {
+	return &harTrace{start: time.Now()}
+}
+
+func (h *harTrace) set(at *time.Time) func() {
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		*at = time.Now()
+	}
+}
+
+func (h *harTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { h.set(&h.dnsStart)() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { h.set(&h.dnsDone)() },
+		ConnectStart:         func(string, string) { h.set(&h.connectStart)() },
+		ConnectDone:          func(string, string, error) { h.set(&h.connected)() },
+		TLSHandshakeStart:    h.set(&h.tlsStart),
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { h.set(&h.tlsDone)() },
+		GotConn:              func(httptrace.GotConnInfo) { h.set(&h.gotConn)() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { h.set(&h.wroteRequest)() },
+		GotFirstResponseByte: h.set(&h.firstByte),
+	}
+}
+
+// timings returns the HAR timings of the request, for the phases that were
+// traced. Transports that do not support tracing, such as HTTP/3, only
+// report the time spent waiting and receiving.
+func (h *harTrace) timings() (har.Timings, float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ms := func(from, to time.Time) float64 {
+		if from.IsZero() || to.IsZero() {
+			return -1
+		}
+		return float64(to.Sub(from).Microseconds()) / 1000
+	}
+
+	gotConn := h.gotConn
+	if gotConn.IsZero() {
+		gotConn = h.start
+	}
+	wroteRequest := h.wroteRequest
+	if wroteRequest.IsZero() {
+		wroteRequest = gotConn
+	}
+	firstByte := h.firstByte
+	if firstByte.IsZero() {
+		firstByte = wroteRequest
+	}
+
+	t := har.Timings{
+		DNS:     ms(h.dnsStart, h.dnsDone),
+		Connect: ms(h.connectStart, h.connected),
+		SSL:     ms(h.tlsStart, h.tlsDone),
+		Send:    ms(gotConn, wroteRequest),
+		Wait:    ms(wroteRequest, firstByte),
+		Receive: ms(firstByte, h.end),
+	}
+	if t.Connect >= 0 && t.SSL >= 0 {
+		// HAR includes the TLS handshake in the connect time.
+		t.Connect += t.SSL
+	}
+	t.Blocked = ms(h.start, gotConn) - max(t.DNS, 0) - max(t.Connect, 0)
+	if t.Blocked < 0 {
+		t.Blocked = 0
+	}
+
+	return t, ms(h.start, h.end)
+}
+
+// recordHAR records a HAR entry for the request sent by the SugarTest running as
+// t. The response body is read in full to be recorded, res is returned with
+// a body that can be read again.
+func (r *recorder) recordHAR(t *testing.T, req *http.Request, reqBody []byte, res *http.Response, err error, trace *harTrace) *http.Response {
+	var body []byte
+	if res != nil {
+		var readErr error
+		body, readErr = io.ReadAll(res.Body)
+		res.Body.Close()
+		res.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{readErr}))
+		if err == nil && readErr != nil {
+			err = readErr
+		}
+	}
+	trace.set(&trace.end)()
+
+	entry := newHAREntry(req, reqBody, res, body, trace)
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	test := r.lookup(t.Name())
+	if test == nil {
+		return res
+	}
+	entry.Pageref = test.Path
+	if r.har == nil {
+		r.har = map[string][]har.Entry{}
+	}
+	r.har[test.Path] = append(r.har[test.Path], entry)
+	return res
+}
+
+// errReader returns err once the body is read, or io.EOF when err is nil.
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	return 0, io.EOF
+}
+
+func newHAREntry(req *http.Request, reqBody []byte, res *http.Response, body []byte, trace *harTrace) har.Entry {
+	timings, total := trace.timings()
+
+	headers := redact(req.Header)
+	if req.Host != "" && req.Host != req.URL.Host {
+		headers.Set("Host", req.Host)
+	}
+
+	entry := har.Entry{
+		StartedDateTime: trace.start,
+		Time:            total,
+		Request: har.Request{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     []har.Cookie{},
+			Headers:     harHeaders(headers),
+			QueryString: []har.NameValue{},
+			HeadersSize: -1,
+			BodySize:    int64(len(reqBody)),
+		},
+		Response: har.Response{
+			Cookies:     []har.Cookie{},
+			Headers:     []har.NameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: timings,
+	}
+
+	for _, name := range sortedKeys(req.URL.Query()) {
+		for _, value := range req.URL.Query()[name] {
+			entry.Request.QueryString = append(entry.Request.QueryString, har.NameValue{Name: name, Value: value})
+		}
+	}
+
+	if len(reqBody) > 0 {
+		text, _, _ := harBody(reqBody)
+		entry.Request.PostData = &har.PostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     text,
+		}
+	}
+
+	if res == nil {
+		return entry
+	}
+
+	entry.Request.HTTPVersion = res.Proto
+	entry.Response.Status = res.StatusCode
+	entry.Response.StatusText = strings.TrimSpace(strings.TrimPrefix(res.Status, fmt.Sprint(res.StatusCode)))
+	entry.Response.HTTPVersion = res.Proto
+	entry.Response.Headers = harHeaders(res.Header)
+	entry.Response.RedirectURL = res.Header.Get("Location")
+	entry.Response.BodySize = int64(len(body))
+
+	text, encoding, kept := harBody(body)
+	entry.Response.Content = har.Content{
+		Size:     int64(len(body)),
+		MimeType: res.Header.Get("Content-Type"),
+		Text:     text,
+		Encoding: encoding,
+	}
+	if kept < len(body) {
+		entry.Response.Content.Comment = fmt.Sprintf("body truncated to %d of %d bytes", kept, len(body))
+	}
+
+	return entry
+}
+
+// harBody truncates body to the limit, and encodes it with base64 when it is
+// not valid UTF-8. It returns the number of bytes of body it kept.
+func harBody(body []byte) (string, string, int) {
+	if len(body) > harBodyLimit {
+		// A text is cut at the start of the rune the limit splits, so
+		// that it stays valid UTF-8.
+		cut := harBodyLimit
+		for i := cut; i > 0 && i > harBodyLimit-utf8.UTFMax; i-- {
+			if utf8.RuneStart(body[i]) {
+				cut = i
+				break
+			}
+		}
+		if utf8.Valid(body[:cut]) {
+			return string(body[:cut]), "", cut
+		}
+		body = body[:harBodyLimit]
+	}
+	if utf8.Valid(body) {
+		return string(body), "", len(body)
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64", len(body)
+}
+
+func harHeaders(header http.Header) []har.NameValue {
+	headers := []har.NameValue{}
+	for _, name := range sortedKeys(header) {
+		for _, value := range header[name] {
+			headers = append(headers, har.NameValue{Name: name, Value: value})
+		}
+	}
+	return headers
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling/har"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordHAR(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("hello world"))
+		case "/binary":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte{0xff, 0xfe, 0xfd})
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+
+	config := DefaultClientConfig
+	config.Auth = BearerToken("secret")
+	configure(t, config)
+
+	defer func(limit int) { harBodyLimit = limit }(harBodyLimit)
+	RecordHAR(5)
+
+	run(t, SugarTests{
+		{
+			Name:     "text",
+			Hint:     "a hint",
+			Request:  Request().Path("/text").Query("format", "raw"),
+			Response: Expect().Status(200).Body("hello world"),
+		},
+		{
+			Name:     "binary",
+			Request:  Request().Path("/binary"),
+			Response: Expect().Status(200).Body([]byte{0xff, 0xfe, 0xfd}),
+		},
+	})
+
+	var entries []har.Entry
+	var pages []har.Page
+	h := HAR()
+	for _, e := range h.Log.Entries {
+		if strings.HasPrefix(e.Pageref, t.Name()+"/") {
+			entries = append(entries, e)
+		}
+	}
+	for _, p := range h.Log.Pages {
+		if strings.HasPrefix(p.ID, t.Name()+"/") {
+			pages = append(pages, p)
+		}
+	}
+
+	require.Len(t, pages, 2)
+	assert.Equal(t, "text", pages[0].Title)
+	assert.Equal(t, "a hint", pages[0].Comment)
+	assert.Equal(t, "binary", pages[1].Title)
+
+	require.Len(t, entries, 2)
+	text := entries[0]
+	assert.Equal(t, pages[0].ID, text.Pageref)
+	assert.Equal(t, "GET", text.Request.Method)
+	assert.Equal(t, srv.URL+"/text?format=raw", text.Request.URL)
+	assert.Equal(t, []har.NameValue{{Name: "format", Value: "raw"}}, text.Request.QueryString)
+	assert.Contains(t, text.Request.Headers, har.NameValue{Name: "Authorization", Value: "REDACTED"})
+	assert.Equal(t, 200, text.Response.Status)
+	assert.Equal(t, "OK", text.Response.StatusText)
+	assert.Equal(t, "HTTP/1.1", text.Response.HTTPVersion)
+	assert.Contains(t, text.Response.Headers, har.NameValue{Name: "Content-Type", Value: "text/plain"})
+	assert.Equal(t, har.Content{
+		Size:     11,
+		MimeType: "text/plain",
+		Text:     "hello",
+		Comment:  "body truncated to 5 of 11 bytes",
+	}, text.Response.Content)
+	assert.GreaterOrEqual(t, text.Time, 0.0)
+	assert.GreaterOrEqual(t, text.Timings.Wait, 0.0)
+
+	binary := entries[1]
+	assert.Equal(t, "base64", binary.Response.Content.Encoding)
+	assert.Equal(t, "//79", binary.Response.Content.Text)
+
+	var buf bytes.Buffer
+	require.NoError(t, har.Write(&buf, h))
+	var decoded map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "1.2", decoded["log"]["version"])
+}
+
+func TestHARBodyKeepsRunesWhole(t *testing.T) {
+	defer func(limit int) { harBodyLimit = limit }(harBodyLimit)
+	RecordHAR(5)
+
+	// "é" is 2 bytes, the limit falls in the middle of the second one.
+	text, encoding, kept := harBody([]byte("abéé"))
+	assert.Equal(t, "abé", text)
+	assert.Empty(t, encoding)
+	assert.Equal(t, 4, kept)
+
+	// "€" is 3 bytes, the limit falls after its second one.
+	text, encoding, kept = harBody([]byte("abc€"))
+	assert.Equal(t, "abc", text)
+	assert.Empty(t, encoding)
+	assert.Equal(t, 3, kept)
+
+	// Binary bodies keep the limit.
+	text, encoding, kept = harBody([]byte{0xff, 0xfe, 0xfd, 0xfc, 0xfb, 0xfa})
+	assert.Equal(t, "//79/Ps=", text)
+	assert.Equal(t, "base64", encoding)
+	assert.Equal(t, 5, kept)
+}
@@ -2,8 +2,6 @@ package test
 
 import (
 	"bufio"
-	"context"
-	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
@@ -12,74 +10,61 @@ import (
 
 // NewProxyTunnelClient creates an HTTP client that routes requests through an HTTP proxy
 // using the CONNECT method, as described in RFC 7231 Section 4.3.6.
+//
+// The client is configured like the other clients of the suite, see Configure.
+// With HTTP/3, the requests sent through the tunnel use HTTP/1.1.
 func NewProxyTunnelClient(proxyURL string) *http.Client {
+	return newProxyClient(proxyURL, true)
+}
+
+// NewProxyClient creates an HTTP client that routes requests through an HTTP proxy.
+//
+// The client is configured like the other clients of the suite, see Configure.
+func NewProxyClient(proxyURL string) *http.Client {
+	return newProxyClient(proxyURL, false)
+}
+
+func newProxyClient(proxyURL string, tunnel bool) *http.Client {
 	proxy, err := url.Parse(proxyURL)
 	if err != nil {
 		panic(err)
 	}
 
-	dialer := &net.Dialer{}
-
-	transport := &http.Transport{
-		Proxy: http.ProxyURL(proxy),
-		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			// Connect to the proxy server
-			conn, err := dialer.DialContext(ctx, "tcp", proxy.Host)
-			if err != nil {
-				return nil, err
-			}
-
-			// Send the CONNECT request to establish a tunnel
-			connectReq := &http.Request{
-				Method: "CONNECT",
-				URL:    &url.URL{Opaque: addr},
-				Host:   addr,
-				Header: make(http.Header),
-			}
-			if err := connectReq.Write(conn); err != nil {
-				conn.Close()
-				return nil, err
-			}
-
-			// Read the CONNECT response from the proxy
-			resp, err := http.ReadResponse(bufio.NewReader(conn), connectReq)
-			if err != nil {
-				conn.Close()
-				return nil, err
-			}
-			if resp.StatusCode != 200 {
-				conn.Close()
-				return nil, fmt.Errorf("proxy error: %v", resp.Status)
-			}
-
-			return conn, nil
-		},
-		//  Skip TLS cert verification to make it easier to test on CI and dev envs
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	transport, err := newProxyTransport(clientConfig, tlsConfig, proxy, tunnel)
+	if err != nil {
+		panic(err)
 	}
 
-	client := &http.Client{
+	return &http.Client{
 		Transport: transport,
+		Timeout:   clientConfig.RequestTimeout,
 	}
-
-	return client
 }
 
-// NewProxyClient creates an HTTP client that routes requests through an HTTP proxy.
-func NewProxyClient(proxyURL string) *http.Client {
-	proxy, err := url.Parse(proxyURL)
-	if err != nil {
-		panic(err)
+// connectTunnel sends a CONNECT request for addr on conn, a connection to the
+// proxy, and returns the tunnel once the proxy accepts it.
+func connectTunnel(conn net.Conn, addr string) (net.Conn, error) {
+	connectReq := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
 	}
-
-	transport := &http.Transport{
-		Proxy:             http.ProxyURL(proxy),
-		ForceAttemptHTTP2: false,
+	if err := connectReq.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
 	}
 
-	client := &http.Client{
-		Transport: transport,
+	// Read the CONNECT response from the proxy
+	resp, err := http.ReadResponse(bufio.NewReader(conn), connectReq)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		conn.Close()
+		return nil, fmt.Errorf("proxy error: %v", resp.Status)
 	}
 
-	return client
+	return conn, nil
 }
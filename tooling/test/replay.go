@@ -0,0 +1,241 @@
+package test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// tape stores the responses received from the gateway, so that the suite can
+// be replayed later without a network.
+//
+// Responses are keyed by a canonical form of their request: the method, the
+// URL, the sorted headers and a hash of the body. Identical requests are
+// numbered in the order they were sent, and replayed in the same order.
+type tape struct {
+	dir    string
+	replay bool
+
+	mu    sync.Mutex
+	count map[string]int
+}
+
+// tapeHeaders are the request headers left out of the canonical requests,
+// since they change between runs without changing the response.
+var tapeHeaders = map[string]bool{
+	"User-Agent": true,
+}
+
+type tapeEntry struct {
+	Request  string       `json:"request"`
+	Response tapeResponse `json:"response"`
+}
+
+type tapeResponse struct {
+	StatusCode    int         `json:"statusCode"`
+	Proto         string      `json:"proto"`
+	Headers       http.Header `json:"headers"`
+	ContentLength int64       `json:"contentLength"`
+	Body          []byte      `json:"body"`
+}
+
+// activeTape is set when the suite runs in record or replay mode.
+var activeTape *tape
+
+func newTape(config ClientConfig) (*tape, error) {
+	switch {
+	case config.RecordDir != "" && config.ReplayDir != "":
+		return nil, fmt.Errorf("record and replay modes cannot be used together")
+	case config.RecordDir != "":
+		if err := os.MkdirAll(config.RecordDir, 0755); err != nil {
+			return nil, err
+		}
+		return &tape{dir: config.RecordDir, count: map[string]int{}}, nil
+	case config.ReplayDir != "":
+		if _, err := os.Stat(config.ReplayDir); err != nil {
+			return nil, err
+		}
+		return &tape{dir: config.ReplayDir, replay: true, count: map[string]int{}}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// wrap returns a RoundTripper recording the responses of next, or replaying
+// the recorded ones, depending on the mode of the tape.
+func (tp *tape) wrap(next http.RoundTripper) http.RoundTripper {
+	if tp == nil {
+		return next
+	}
+	return tapeTransport{tp, next}
+}
+
+type tapeTransport struct {
+	tape *tape
+	next http.RoundTripper
+}
+
+func (t tapeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	canonical := canonicalRequest(req, body)
+
+	if t.tape.replay {
+		return t.tape.load(req, canonical)
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	err = t.tape.save(canonical, tapeResponse{
+		StatusCode:    res.StatusCode,
+		Proto:         res.Proto,
+		Headers:       res.Header,
+		ContentLength: res.ContentLength,
+		Body:          resBody,
+	})
+	return res, err
+}
+
+// requestBody reads the body of req, without consuming it.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// canonicalRequest returns the canonical form of req, used as the key of its
+// response.
+func canonicalRequest(req *http.Request, body []byte) string {
+	var b strings.Builder
+
+	// Requests to the gateway are keyed by their path, so that recordings
+	// can be replayed with a different gateway URL.
+	u := req.URL.String()
+	gateway := GatewayURL()
+	if req.URL.Scheme == gateway.Scheme && req.URL.Host == gateway.Host {
+		u = req.URL.RequestURI()
+	}
+	fmt.Fprintf(&b, "%s %s\n", req.Method, u)
+	if req.Host != "" && req.Host != req.URL.Host {
+		fmt.Fprintf(&b, "Host: %s\n", req.Host)
+	}
+
+	ignored := map[string]bool{}
+	if clientConfig.Auth != nil {
+		// Credentials expire, and must not end up in the recordings.
+		if name, _, err := clientConfig.Auth.Header(); err == nil {
+			ignored[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+	for _, name := range sortedKeys(req.Header) {
+		if tapeHeaders[name] || ignored[name] {
+			continue
+		}
+		for _, value := range req.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+
+	sum := sha256.Sum256(body)
+	fmt.Fprintf(&b, "Body-Sha256: %x\n", sum)
+
+	return b.String()
+}
+
+// path returns the path of the nth response to the canonical request.
+func (tp *tape) path(canonical string, n int) string {
+	sum := sha256.Sum256([]byte(canonical))
+	return filepath.Join(tp.dir, fmt.Sprintf("%s-%d.json", hex.EncodeToString(sum[:16]), n))
+}
+
+// next returns the number of times the canonical request was seen before.
+func (tp *tape) next(canonical string) int {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	n := tp.count[canonical]
+	tp.count[canonical]++
+	return n
+}
+
+func (tp *tape) save(canonical string, res tapeResponse) error {
+	data, err := json.MarshalIndent(tapeEntry{Request: canonical, Response: res}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(tp.path(canonical, tp.next(canonical)), data, 0644)
+}
+
+func (tp *tape) load(req *http.Request, canonical string) (*http.Response, error) {
+	n := tp.next(canonical)
+
+	// Requests sent more times than recorded get the last recorded response.
+	var data []byte
+	var err error
+	for ; n >= 0; n-- {
+		data, err = os.ReadFile(tp.path(canonical, n))
+		if err == nil || !os.IsNotExist(err) {
+			break
+		}
+	}
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no recorded response in %s for:\n%s", tp.dir, canonical)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var entry tapeEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+
+	res := entry.Response
+	major, minor, ok := http.ParseHTTPVersion(res.Proto)
+	if !ok {
+		major, minor = 1, 1
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode)),
+		StatusCode:    res.StatusCode,
+		Proto:         res.Proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        res.Headers,
+		ContentLength: res.ContentLength,
+		Body:          io.NopCloser(bytes.NewReader(res.Body)),
+		Request:       req,
+	}, nil
+}
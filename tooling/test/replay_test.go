@@ -0,0 +1,93 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordReplay(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Request", fmt.Sprintf("%d", requests.Add(1)))
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.URL.Path, body)
+	}))
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+
+	dir := t.TempDir()
+	send := func(method, path, body string, headers ...string) (*http.Response, string, error) {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		client := newClient()
+		client.Transport = activeTape.wrap(client.Transport)
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, "", err
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(b), nil
+	}
+
+	config := DefaultClientConfig
+	config.RecordDir = dir
+	configure(t, config)
+
+	_, body, err := send("GET", "/a", "", "User-Agent", "v1")
+	require.NoError(t, err)
+	assert.Equal(t, "GET /a ", body)
+	res, _, err := send("GET", "/a", "", "User-Agent", "v1")
+	require.NoError(t, err)
+	assert.Equal(t, "2", res.Header.Get("X-Request"))
+	_, _, err = send("POST", "/b", "payload", "Accept", "text/plain")
+	require.NoError(t, err)
+
+	srv.Close()
+
+	config = DefaultClientConfig
+	config.ReplayDir = dir
+	configure(t, config)
+
+	// Identical requests are replayed in order, whatever the User-Agent.
+	res, body, err = send("GET", "/a", "", "User-Agent", "v2")
+	require.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "GET /a ", body)
+	assert.Equal(t, "1", res.Header.Get("X-Request"))
+	res, _, err = send("GET", "/a", "")
+	require.NoError(t, err)
+	assert.Equal(t, "2", res.Header.Get("X-Request"))
+	res, _, err = send("GET", "/a", "")
+	require.NoError(t, err)
+	assert.Equal(t, "2", res.Header.Get("X-Request"), "extra requests get the last response")
+
+	_, body, err = send("POST", "/b", "payload", "Accept", "text/plain")
+	require.NoError(t, err)
+	assert.Equal(t, "POST /b payload", body)
+
+	// Requests differing by their body or headers were not recorded.
+	_, _, err = send("POST", "/b", "other", "Accept", "text/plain")
+	assert.ErrorContains(t, err, "no recorded response")
+	_, _, err = send("POST", "/b", "payload", "Accept", "text/html")
+	assert.ErrorContains(t, err, "no recorded response")
+}
+
+func TestRecordReplayExclusive(t *testing.T) {
+	config := DefaultClientConfig
+	config.RecordDir = t.TempDir()
+	config.ReplayDir = t.TempDir()
+	assert.ErrorContains(t, Configure(config), "cannot be used together")
+}
@@ -64,7 +64,9 @@ func report(t *testing.T, test SugarTest, req *http.Request, res *http.Response,
 					return "nil" // golang does not catch the nil case above
 				}
 
-				b, err = httputil.DumpRequestOut(v, true)
+				redacted := *v
+				redacted.Header = redact(v.Header)
+				b, err = httputil.DumpRequestOut(&redacted, true)
 			case *http.Response:
 				if v == nil {
 					return "nil" // golang does not catch the nil case above
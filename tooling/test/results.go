@@ -0,0 +1,208 @@
+package test
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/gateway-conformance/tooling"
+	"github.com/ipfs/gateway-conformance/tooling/har"
+	"github.com/ipfs/gateway-conformance/tooling/results"
+)
+
+// recorder collects the structured results of the SugarTests as they run.
+type recorder struct {
+	mu     sync.Mutex
+	tests  []*results.Test
+	byPath map[string]*results.Test
+	// har holds the HAR entries of every test, by path.
+	har map[string][]har.Entry
+	// onFinish is called with the result of every SugarTest that finishes.
+	onFinish func(results.Test)
+}
+
+var recorded = &recorder{byPath: map[string]*results.Test{}}
+
+// Results returns the results of every SugarTest run so far, in the order
+// they started.
+func Results() []results.Test {
+	recorded.mu.Lock()
+	defer recorded.mu.Unlock()
+
+	tests := make([]results.Test, 0, len(recorded.tests))
+	for _, t := range recorded.tests {
+		tests = append(tests, *t)
+	}
+	return tests
+}
+
+// ResetResults forgets the results, and HAR entries, of the SugarTests run so
+// far, e.g. before the suite runs again.
+func ResetResults() {
+	recorded.mu.Lock()
+	defer recorded.mu.Unlock()
+
+	recorded.tests = nil
+	recorded.byPath = map[string]*results.Test{}
+	recorded.har = nil
+}
+
+// OnResult sets a function called with the result of every SugarTest as soon
+// as it finishes, nil disables it. The function may be called concurrently
+// when tests run in parallel.
+func OnResult(f func(results.Test)) {
+	recorded.mu.Lock()
+	defer recorded.mu.Unlock()
+
+	recorded.onFinish = f
+}
+
+// start records the beginning of a SugarTest running as t, gates are the
+// names of the specs required to run it.
+func (r *recorder) start(t *testing.T, test SugarTest, gates []string) {
+	// Built before locking, AllSpecs panics when the test is invalid.
+	entry := &results.Test{
+		Name:  test.Name,
+		Path:  t.Name(),
+		Group: tooling.TestGroup(t.Name()),
+		Specs: test.AllSpecs(),
+		Gates: gates,
+		Hint:  test.Hint,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tests = append(r.tests, entry)
+	r.byPath[entry.Path] = entry
+}
+
+// skip records the SugarTests of t that do not run because a spec they
+// require is disabled, so that the summary counts them as skipped.
+func (r *recorder) skip(t *testing.T, tests SugarTests, gates []string, reason string) {
+	entries := make([]*results.Test, 0, len(tests))
+	for _, test := range tests {
+		entries = append(entries, &results.Test{
+			Name:    test.Name,
+			Path:    t.Name() + "/" + strings.ReplaceAll(safeName(test.Name), " ", "_"),
+			Group:   tooling.TestGroup(t.Name()),
+			Specs:   test.AllSpecs(),
+			Gates:   gates,
+			Hint:    test.Hint,
+			Outcome: results.Skip,
+			Reason:  reason,
+		})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tests = append(r.tests, entries...)
+}
+
+// finish records the outcome of the SugarTest running as t.
+func (r *recorder) finish(t *testing.T) {
+	r.mu.Lock()
+	entry := r.lookup(t.Name())
+	if entry == nil {
+		r.mu.Unlock()
+		return
+	}
+	switch {
+	case t.Skipped():
+		entry.Outcome = results.Skip
+	case t.Failed():
+		entry.Outcome = results.Fail
+		if entry.Reason == "" {
+			var reasons []string
+			for _, c := range entry.Checks {
+				if c.Outcome == results.Fail {
+					reasons = append(reasons, c.Reason)
+				}
+			}
+			entry.Reason = strings.Join(reasons, "; ")
+		}
+	default:
+		entry.Outcome = results.Pass
+	}
+	result, onFinish := *entry, r.onFinish
+	r.mu.Unlock()
+
+	// Called without the lock, so that the function can read the results.
+	if onFinish != nil {
+		onFinish(result)
+	}
+}
+
+// exchange records a request sent by the SugarTest running as t.
+func (r *recorder) exchange(t *testing.T, req *http.Request, res *http.Response, err error, attempts int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := r.lookup(t.Name())
+	if entry == nil || req == nil {
+		return
+	}
+	exchange := results.Exchange{
+		Request:  results.NewRequest(req),
+		Response: results.NewResponse(res),
+		Attempts: attempts,
+	}
+	exchange.Request.Headers = redact(req.Header)
+	if err != nil {
+		exchange.Error = err.Error()
+	}
+	entry.Exchanges = append(entry.Exchanges, exchange)
+}
+
+// check records the outcome of a check run as t.
+func (r *recorder) check(t *testing.T, c testCheckOutput) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := r.lookup(t.Name())
+	if entry == nil {
+		return
+	}
+	check := results.Check{
+		Name:    c.testName,
+		Path:    t.Name(),
+		Specs:   c.specs,
+		Hint:    c.hint,
+		Outcome: results.Pass,
+	}
+	if !c.checkOutput.Success {
+		check.Outcome = results.Fail
+		check.Reason = c.checkOutput.Reason
+	}
+	entry.Checks = append(entry.Checks, check)
+}
+
+// fail records why the SugarTest running as t failed, when the failure is
+// not the outcome of a check, e.g. the request could not be sent.
+func (r *recorder) fail(t *testing.T, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := r.lookup(t.Name())
+	if entry == nil || entry.Reason != "" {
+		return
+	}
+	entry.Reason = err.Error()
+}
+
+// lookup returns the SugarTest entry of the test, or subtest, with the given
+// name. The caller must hold r.mu.
+func (r *recorder) lookup(name string) *results.Test {
+	for {
+		if entry, ok := r.byPath[name]; ok {
+			return entry
+		}
+		i := strings.LastIndex(name, "/")
+		if i < 0 {
+			return nil
+		}
+		name = name[:i]
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"testing"
 
@@ -21,7 +22,7 @@ func runRequest(ctx context.Context, t *testing.T, test SugarTest, builder Reque
 	}
 
 	// Prepare a client,
-	client := &http.Client{}
+	client := newClient()
 
 	// HTTP proxy tests require additional prep
 	if builder.UseProxyTunnel_ {
@@ -34,6 +35,9 @@ func runRequest(ctx context.Context, t *testing.T, test SugarTest, builder Reque
 		client = NewProxyClient(builder.Proxy_)
 	}
 
+	// Record or replay the responses
+	client.Transport = activeTape.wrap(client.Transport)
+
 	// Handle redirect tests
 	if !builder.FollowRedirects_ {
 		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
@@ -55,6 +59,7 @@ func runRequest(ctx context.Context, t *testing.T, test SugarTest, builder Reque
 			panic("msg must be string or error")
 		}
 
+		recorded.fail(t, err)
 		report(t, test, req, res, err)
 	}
 
@@ -112,12 +117,35 @@ func runRequest(ctx context.Context, t *testing.T, test SugarTest, builder Reque
 		req.Header.Set("User-Agent", "ipfs/gateway-conformance/"+tooling.Version)
 	}
 
+	// Add the configured headers, unless the test set them itself
+	for key, values := range clientConfig.Headers {
+		if !hasHeader(builder, key) {
+			req.Header[key] = values
+		}
+	}
+
+	if err := authorize(req, builder); err != nil {
+		t.Fatal(err)
+	}
+
 	// Send request
 	log.Debugf("Querying %s", url)
 	req = req.WithContext(ctx)
 
-	res, err = client.Do(req)
-	if err != nil {
+	var trace *harTrace
+	if harBodyLimit >= 0 {
+		trace = newHARTrace()
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+	}
+
+	res, attempts, err := send(t, client, req)
+	if trace != nil {
+		res = recorded.recordHAR(t, req, builder.Body_, res, err, trace)
+	}
+	recorded.exchange(t, req, res, err, attempts)
+	if err != nil && attempts > 1 {
+		localReport(t, "Querying %s failed after %d attempts: %s", url, attempts, err)
+	} else if err != nil {
 		localReport(t, "Querying %s failed: %s", url, err)
 	}
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/ipfs/gateway-conformance/tooling"
@@ -241,6 +242,8 @@ func (e ExpectBuilder) Validate(t *testing.T, res *http.Response, localReport Re
 	checks := validateResponse(t, e, res)
 	for _, c := range checks {
 		t.Run(c.testName, func(t *testing.T) {
+			defer recoverPanic(t)
+			recorded.check(t, c)
 			tooling.LogSpecs(t, c.specs...)
 			if !c.checkOutput.Success {
 				localReport(t, c.checkOutput.Reason)
@@ -299,6 +302,7 @@ func (e AllOfExpectBuilder) Validate(t *testing.T, res *http.Response, localRepo
 	for i, expect := range e.Expect_ {
 		t.Run(
 			fmt.Sprintf("Check %d", i), func(t *testing.T) {
+				defer recoverPanic(t)
 				expect.Validate(t, res, localReport)
 			})
 	}
@@ -347,8 +351,22 @@ func (e AnyOfExpectBuilder) Validate(t *testing.T, res *http.Response, localRepo
 			hadASuccessfulResponse = true
 		}
 
-		t.Run(fmt.Sprintf("Check %d", i),
+		name := fmt.Sprintf("Check %d", i)
+		t.Run(name,
 			func(t *testing.T) {
+				defer recoverPanic(t)
+				option := testCheckOutput{testName: name, checkOutput: check.CheckOutput{Success: responseSucceeded}}
+				if !responseSucceeded {
+					var reasons []string
+					for _, c := range checks {
+						if !c.checkOutput.Success {
+							reasons = append(reasons, c.checkOutput.Reason)
+						}
+					}
+					option.checkOutput.Reason = strings.Join(reasons, "; ")
+				}
+				recorded.check(t, option)
+
 				if !responseSucceeded {
 					for _, c := range checks {
 						if c.checkOutput.Success {
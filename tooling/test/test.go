@@ -2,11 +2,13 @@ package test
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
+	"runtime/debug"
 	"strings"
+	"sync"
 	"testing"
-	"time"
 
 	"github.com/ipfs/gateway-conformance/tooling"
 	"github.com/ipfs/gateway-conformance/tooling/specs"
@@ -21,6 +23,10 @@ type SugarTest struct {
 	Requests  []RequestBuilder
 	Response  ExpectValidator
 	Responses ExpectsBuilder
+	// Sequential marks a test that shares state with other tests, such as the
+	// gateway cache, and must not run concurrently with them when Parallel is
+	// set. Tests with multiple Requests always run sequentially.
+	Sequential bool
 }
 
 type SugarTests []SugarTest
@@ -30,6 +36,16 @@ func (s SugarTests) Append(tests ...SugarTest) SugarTests {
 	return s
 }
 
+// Sequentially returns a copy of the tests with every test marked Sequential.
+func (s SugarTests) Sequentially() SugarTests {
+	tests := make(SugarTests, len(s))
+	for i, test := range s {
+		test.Sequential = true
+		tests[i] = test
+	}
+	return tests
+}
+
 func (s *SugarTest) AllSpecs() []string {
 	if len(s.Specs) > 0 && s.Spec != "" {
 		panic("cannot have both Spec and Specs")
@@ -53,54 +69,137 @@ func RunWithSpecs(
 ) {
 	t.Helper()
 
+	tests = selected(t, tests)
+	if len(tests) == 0 && filter.IsSet() {
+		return
+	}
+
+	if ListOnly {
+		listed.list(t, tests, required)
+		return
+	}
+
+	var gates []string
 	missing := []specs.Spec{}
 	for _, spec := range required {
+		gates = append(gates, spec.Name())
 		if !spec.IsEnabled() {
 			missing = append(missing, spec)
 		}
 	}
 
 	if len(missing) > 0 {
-		t.Skipf("skipping tests, missing specs: %v", missing)
+		reason := fmt.Sprintf("skipping tests, missing specs: %v", missing)
+		recorded.skip(t, tests, gates, reason)
+		t.Skip(reason)
 		return
 	}
 
-	run(t, tests)
+	run(t, tests, gates...)
 }
 
-func run(t *testing.T, tests SugarTests) {
+// Parallel is the maximum number of SugarTests of a single RunWithSpecs call
+// that run concurrently. The tests still start in order, and every test of a
+// call completes before the call returns, so that later calls can depend on
+// the outcome of earlier ones.
+var Parallel = 1
+
+// run runs the tests as subtests of t, gates are the names of the specs
+// required to run them.
+func run(t *testing.T, tests SugarTests, gates ...string) {
 	t.Helper()
 
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(Parallel, 1))
+
 	for _, test := range tests {
-		timeout, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-		defer cancel()
-
-		name := safeName(test.Name)
-
-		if len(test.Requests) > 0 {
-			t.Run(name, func(t *testing.T) {
-				tooling.LogSpecs(t, test.AllSpecs()...)
-				responses := make([]*http.Response, 0, len(test.Requests))
-
-				for _, req := range test.Requests {
-					_, res, localReport := runRequest(timeout, t, test, req)
-					if test.Response != nil {
-						test.Response.Validate(t, res, localReport)
-					}
-					responses = append(responses, res)
-				}
+		if Parallel < 2 || test.Sequential || len(test.Requests) > 0 {
+			wg.Wait()
+			runTest(t, test, gates, func() {})
+			continue
+		}
+
+		// Wait for the test to start before starting the next one, this keeps
+		// the order of the tests in the reports deterministic.
+		started := make(chan struct{})
+		var once sync.Once
+		start := func() { once.Do(func() { close(started) }) }
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			// t.Run does not call the test function when it is filtered out.
+			defer start()
+			runTest(t, test, gates, start)
+		}()
+		<-started
+	}
 
-				validateResponses(t, test.Responses, responses)
-			})
-		} else {
-			t.Run(name, func(t *testing.T) {
-				tooling.LogSpecs(t, test.AllSpecs()...)
-				_, res, localReport := runRequest(timeout, t, test, test.Request)
+	wg.Wait()
+}
+
+// runTest runs a single SugarTest as a subtest of t, start is called as soon
+// as the subtest is running.
+func runTest(t *testing.T, test SugarTest, gates []string, start func()) {
+	t.Helper()
+
+	name := safeName(test.Name)
+	parent := t
+
+	if len(test.Requests) > 0 {
+		t.Run(name, func(t *testing.T) {
+			if !isSelected(parent, t, test) {
+				t.Skip(notSelected)
+			}
+			defer recorded.finish(t)
+			defer recoverPanic(t)
+			recorded.start(t, test, gates)
+			start()
+
+			tooling.LogSpecs(t, test.AllSpecs()...)
+			tooling.LogHint(t, test.Hint)
+			responses := make([]*http.Response, 0, len(test.Requests))
+
+			for _, req := range test.Requests {
+				_, res, localReport := runRequest(context.Background(), t, test, req)
 				if test.Response != nil {
 					test.Response.Validate(t, res, localReport)
 				}
-			})
-		}
+				responses = append(responses, res)
+			}
+
+			validateResponses(t, test.Responses, responses)
+		})
+	} else {
+		t.Run(name, func(t *testing.T) {
+			if !isSelected(parent, t, test) {
+				t.Skip(notSelected)
+			}
+			defer recorded.finish(t)
+			defer recoverPanic(t)
+			recorded.start(t, test, gates)
+			start()
+
+			tooling.LogSpecs(t, test.AllSpecs()...)
+			tooling.LogHint(t, test.Hint)
+			_, res, localReport := runRequest(context.Background(), t, test, test.Request)
+			if test.Response != nil {
+				test.Response.Validate(t, res, localReport)
+			}
+		})
+	}
+}
+
+// recoverPanic, deferred in a subtest, records a panic of the subtest as its
+// failure. The suite runs in the process of the CLI, a panic that is not
+// recovered would crash it before the reports are written.
+func recoverPanic(t *testing.T) {
+	if r := recover(); r != nil {
+		err := fmt.Errorf("panic: %v", r)
+		recorded.fail(t, err)
+		t.Errorf("%v\n%s", err, debug.Stack())
 	}
 }
 
@@ -0,0 +1,174 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ipfs/gateway-conformance/tooling/check"
+	"github.com/ipfs/gateway-conformance/tooling/results"
+	"github.com/ipfs/gateway-conformance/tooling/runner"
+	"github.com/ipfs/gateway-conformance/tooling/specs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunParallel(t *testing.T) {
+	var inFlight, maxInFlight, sequentialOverlap atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		if r.URL.Path == "/sequential" && n > 1 {
+			sequentialOverlap.Add(1)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+
+	defer func(parallel int) { Parallel = parallel }(Parallel)
+	Parallel = 4
+
+	tests := SugarTests{}
+	for i := 0; i < 8; i++ {
+		tests = append(tests, SugarTest{
+			Name:     fmt.Sprintf("concurrent %d", i),
+			Request:  Request().Path("/concurrent"),
+			Response: Expect().Status(200),
+		})
+	}
+	tests = append(tests, SugarTests{
+		{
+			Name:     "sequential 0",
+			Request:  Request().Path("/sequential"),
+			Response: Expect().Status(200),
+		},
+		{
+			Name:     "sequential 1",
+			Request:  Request().Path("/sequential"),
+			Response: Expect().Status(200),
+		},
+	}.Sequentially()...)
+
+	before := len(Results())
+	run(t, tests)
+
+	assert.Greater(t, maxInFlight.Load(), int32(1))
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(4))
+	assert.Zero(t, sequentialOverlap.Load())
+
+	var names []string
+	for _, test := range Results()[before:] {
+		names = append(names, test.Name)
+		assert.Equal(t, "pass", string(test.Outcome))
+	}
+	var expected []string
+	for _, test := range tests {
+		expected = append(expected, test.Name)
+	}
+	assert.Equal(t, expected, names)
+}
+
+func TestRunWithSpecsRecordsGates(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+
+	specs.ProxyGateway.Disable()
+	defer specs.ProxyGateway.Enable()
+
+	tests := SugarTests{{
+		Name:     "GET a block",
+		Request:  Request().Path("/ipfs/bafy"),
+		Response: Expect().Status(200),
+	}}
+
+	before := len(Results())
+	t.Run("enabled", func(t *testing.T) {
+		RunWithSpecs(t, tests, specs.PathGatewayRaw)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		RunWithSpecs(t, tests, specs.PathGatewayRaw, specs.ProxyGateway)
+	})
+
+	recorded := Results()[before:]
+	assert.Len(t, recorded, 2)
+	assert.Equal(t, "pass", string(recorded[0].Outcome))
+	assert.Equal(t, []string{"path-raw-gateway"}, recorded[0].Gates)
+	assert.Equal(t, "skip", string(recorded[1].Outcome))
+	assert.Equal(t, t.Name()+"/disabled/GET_a_block", recorded[1].Path)
+	assert.Equal(t, []string{"path-raw-gateway", "proxy-gateway"}, recorded[1].Gates)
+}
+
+func TestOnResult(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+
+	var finished []results.Test
+	OnResult(func(r results.Test) {
+		finished = append(finished, r)
+	})
+	defer OnResult(nil)
+
+	ResetResults()
+	t.Run("suite", func(t *testing.T) {
+		RunWithSpecs(t, SugarTests{{
+			Name:     "GET a block",
+			Request:  Request().Path("/ipfs/bafy"),
+			Response: Expect().Status(200),
+		}})
+	})
+
+	require.Len(t, finished, 1)
+	assert.Equal(t, results.Pass, finished[0].Outcome)
+	assert.Len(t, finished[0].Checks, 1)
+	assert.Equal(t, finished, Results())
+
+	ResetResults()
+	assert.Empty(t, Results())
+}
+
+func TestRunRecoversPanics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	t.Setenv("GATEWAY_URL", srv.URL)
+
+	before := len(Results())
+	// A failing test fails its parent, it runs in a separate suite.
+	code, err := runner.Run(io.Discard, []testing.InternalTest{{
+		Name: "TestPanics",
+		F: func(t *testing.T) {
+			run(t, SugarTests{
+				{
+					Name:     "panics",
+					Request:  Request().Path("/"),
+					Response: Expect().Body(check.Checks("", func([]byte) bool { panic("boom") })),
+				},
+				{
+					Name:     "passes",
+					Request:  Request().Path("/"),
+					Response: Expect().Status(200),
+				},
+			})
+		},
+	}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, code)
+
+	tests := Results()[before:]
+	require.Len(t, tests, 2)
+	assert.Equal(t, results.Fail, tests[0].Outcome)
+	assert.Equal(t, "panic: boom", tests[0].Reason)
+	assert.Equal(t, results.Pass, tests[1].Outcome)
+}
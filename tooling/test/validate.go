@@ -14,6 +14,7 @@ import (
 type testCheckOutput struct {
 	testName    string
 	specs       []string
+	hint        string
 	checkOutput check.CheckOutput
 }
 
@@ -81,7 +82,7 @@ func validateResponse(
 			}
 		}
 
-		outputs = append(outputs, testCheckOutput{testName: testName, checkOutput: output, specs: header.Specs_})
+		outputs = append(outputs, testCheckOutput{testName: testName, checkOutput: output, specs: header.Specs_, hint: header.Hint_})
 	}
 
 	if expected.Body_ != nil {
@@ -119,7 +120,7 @@ func validateResponse(
 			}
 		}
 
-		outputs = append(outputs, testCheckOutput{testName: "Body", checkOutput: output})
+		outputs = append(outputs, testCheckOutput{testName: "Body", checkOutput: output, hint: output.Hint})
 	}
 	return outputs
 }
@@ -0,0 +1,293 @@
+// Package test2json converts the output of a test binary run with
+// `-test.v=test2json` into the JSON event stream produced by
+// `go tool test2json -t`. See `go doc test2json` for the format.
+package test2json
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a single test2json event.
+type Event struct {
+	Time       *time.Time `json:",omitempty"`
+	Action     string
+	Package    string   `json:",omitempty"`
+	Test       string   `json:",omitempty"`
+	Elapsed    *float64 `json:",omitempty"`
+	Output     string   `json:",omitempty"`
+	OutputType string   `json:",omitempty"`
+}
+
+const (
+	markFraming    = '\x16' // ^V, prefixes lines emitted by the testing framework
+	markErrorBegin = '\x0f' // ^O, starts a t.Error / t.Fatal message
+	markErrorEnd   = '\x0e' // ^N, ends a t.Error / t.Fatal message
+)
+
+// Converter is an io.Writer that accepts the output of a test binary and
+// writes the corresponding test2json events to the underlying writer.
+type Converter struct {
+	w       *json.Encoder
+	pkg     string
+	start   time.Time
+	buf     []byte
+	test    string
+	inError bool
+	result  string
+	err     error
+}
+
+// NewConverter returns a Converter writing events for the package pkg to w.
+func NewConverter(w io.Writer, pkg string) *Converter {
+	c := &Converter{
+		w:     json.NewEncoder(w),
+		pkg:   pkg,
+		start: time.Now(),
+	}
+	c.emit(Event{Action: "start"})
+	return c
+}
+
+// Convert reads the whole output of a test binary from r and writes the
+// corresponding test2json events to w.
+func Convert(w io.Writer, r io.Reader, pkg string) error {
+	c := NewConverter(w, pkg)
+	if _, err := io.Copy(c, r); err != nil {
+		return err
+	}
+	return c.Close()
+}
+
+func (c *Converter) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+	for {
+		i := bytes.IndexByte(c.buf, '\n')
+		if i < 0 {
+			break
+		}
+		c.line(string(c.buf[:i+1]))
+		c.buf = c.buf[i+1:]
+	}
+	return len(p), c.err
+}
+
+// Close flushes any partial line and emits the final package event.
+func (c *Converter) Close() error {
+	if len(c.buf) > 0 {
+		c.line(string(c.buf) + "\n")
+		c.buf = nil
+	}
+
+	result := c.result
+	if result == "" {
+		// The binary did not report a result, most likely it crashed.
+		result = "fail"
+	}
+	elapsed := time.Since(c.start).Seconds()
+	c.emit(Event{Action: result, Elapsed: &elapsed})
+
+	return c.err
+}
+
+func (c *Converter) line(line string) {
+	if line[0] != markFraming {
+		output, outputType := c.errorOutput(line)
+		c.output(c.test, output, outputType)
+		return
+	}
+
+	frame := line[1:]
+	text := strings.TrimLeft(frame, " ")
+	switch {
+	case strings.HasPrefix(text, "=== NAME"):
+		c.test = strings.TrimSpace(strings.TrimPrefix(text, "=== NAME"))
+	case strings.HasPrefix(text, "=== RUN"):
+		c.test = strings.TrimSpace(strings.TrimPrefix(text, "=== RUN"))
+		c.emit(Event{Action: "run", Test: c.test})
+		c.frame(c.test, frame)
+	case strings.HasPrefix(text, "=== PAUSE"):
+		c.test = strings.TrimSpace(strings.TrimPrefix(text, "=== PAUSE"))
+		c.frame(c.test, frame)
+		c.emit(Event{Action: "pause", Test: c.test})
+	case strings.HasPrefix(text, "=== CONT"):
+		c.test = strings.TrimSpace(strings.TrimPrefix(text, "=== CONT"))
+		c.frame(c.test, frame)
+		c.emit(Event{Action: "cont", Test: c.test})
+	case strings.HasPrefix(text, "--- PASS: "),
+		strings.HasPrefix(text, "--- FAIL: "),
+		strings.HasPrefix(text, "--- SKIP: "):
+		action := strings.ToLower(text[4:8])
+		name, elapsed := parseResult(strings.TrimSpace(text[10:]))
+		c.frame(name, frame)
+		c.emit(Event{Action: action, Test: name, Elapsed: elapsed})
+	case text == "PASS\n", text == "FAIL\n":
+		c.result = strings.ToLower(strings.TrimSpace(text))
+		c.frame("", frame)
+	default:
+		c.frame(c.test, frame)
+	}
+}
+
+// parseResult splits "TestName (0.01s)" into its name and elapsed seconds.
+func parseResult(s string) (string, *float64) {
+	i := strings.LastIndex(s, " (")
+	if i < 0 || !strings.HasSuffix(s, "s)") {
+		return s, nil
+	}
+	elapsed, err := strconv.ParseFloat(s[i+2:len(s)-2], 64)
+	if err != nil {
+		return s, nil
+	}
+	return s[:i], &elapsed
+}
+
+// errorOutput strips the markers surrounding t.Error messages and returns
+// the matching output type.
+func (c *Converter) errorOutput(line string) (string, string) {
+	outputType := ""
+	if strings.IndexByte(line, markErrorBegin) >= 0 {
+		line = strings.ReplaceAll(line, string(markErrorBegin), "")
+		c.inError = true
+		outputType = "error"
+	} else if c.inError {
+		outputType = "error-continue"
+	}
+	if strings.IndexByte(line, markErrorEnd) >= 0 {
+		line = strings.ReplaceAll(line, string(markErrorEnd), "")
+		c.inError = false
+	}
+	return line, outputType
+}
+
+func (c *Converter) output(test, output, outputType string) {
+	c.emit(Event{Action: "output", Test: test, Output: output, OutputType: outputType})
+}
+
+func (c *Converter) frame(test, output string) {
+	c.output(test, output, "frame")
+}
+
+func (c *Converter) emit(e Event) {
+	if c.err != nil {
+		return
+	}
+	now := time.Now()
+	e.Time = &now
+	e.Package = c.pkg
+	c.err = c.w.Encode(e)
+}
+
+// ReadEvents parses a test2json stream, as written by a Converter or by
+// `go tool test2json`.
+func ReadEvents(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// Sort reorders the events of tests that ran concurrently, so that the
+// stream reads as if they had run one after the other: the events of every
+// test are contiguous, tests are in the order they started, and the result of
+// a test comes after the events of its subtests.
+func Sort(events []Event) []Event {
+	var (
+		head, tail []Event
+		body       = map[string][]Event{}
+		results    = map[string][]Event{}
+		children   = map[string][]string{}
+	)
+
+	for _, e := range events {
+		if e.Test == "" {
+			if len(body) == 0 {
+				head = append(head, e)
+			} else {
+				tail = append(tail, e)
+			}
+			continue
+		}
+
+		if _, ok := body[e.Test]; !ok {
+			parent := parentOf(e.Test)
+			if _, ok := body[parent]; !ok {
+				parent = ""
+			}
+			children[parent] = append(children[parent], e.Test)
+			body[e.Test] = nil
+		}
+
+		if isResult(e) {
+			results[e.Test] = append(results[e.Test], e)
+		} else {
+			body[e.Test] = append(body[e.Test], e)
+		}
+	}
+
+	sorted := make([]Event, 0, len(events))
+	sorted = append(sorted, head...)
+
+	var visit func(test string)
+	visit = func(test string) {
+		sorted = append(sorted, body[test]...)
+		for _, child := range children[test] {
+			visit(child)
+		}
+		sorted = append(sorted, results[test]...)
+	}
+	for _, test := range children[""] {
+		visit(test)
+	}
+
+	return append(sorted, tail...)
+}
+
+// isResult reports whether e is the final result of a test, or the framing
+// line reporting it.
+func isResult(e Event) bool {
+	switch e.Action {
+	case "pass", "fail", "skip":
+		return true
+	case "output":
+		return e.OutputType == "frame" &&
+			(strings.HasPrefix(e.Output, "--- PASS") ||
+				strings.HasPrefix(e.Output, "--- FAIL") ||
+				strings.HasPrefix(e.Output, "--- SKIP"))
+	}
+	return false
+}
+
+func parentOf(test string) string {
+	if i := strings.LastIndex(test, "/"); i >= 0 {
+		return test[:i]
+	}
+	return ""
+}
+
+// WriteEvents writes events as a test2json stream.
+func WriteEvents(w io.Writer, events []Event) error {
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
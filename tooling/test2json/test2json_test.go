@@ -0,0 +1,121 @@
+package test2json
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const output = "\x16=== RUN   TestA\n" +
+	"    a_test.go:3: hello\n" +
+	"\x16=== RUN   TestA/sub_one\n" +
+	"\x0f    a_test.go:3: bad\n" +
+	"        more details\x0e\n" +
+	"\x16--- FAIL: TestA/sub_one (0.25s)\n" +
+	"\x16=== NAME  TestA\n" +
+	"\x16=== RUN   TestA/s2\n" +
+	"    a_test.go:3: nah\n" +
+	"\x16--- SKIP: TestA/s2 (0.00s)\n" +
+	"\x16=== NAME  TestA\n" +
+	"\x16--- FAIL: TestA (0.50s)\n" +
+	"\x16=== NAME  \n" +
+	"\x16=== RUN   TestB\n" +
+	"\x16--- PASS: TestB (0.00s)\n" +
+	"\x16=== NAME  \n" +
+	"\x16FAIL\n"
+
+func TestConvert(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Convert(&buf, strings.NewReader(output), "Gateway Tests"))
+
+	events, err := ReadEvents(&buf)
+	require.NoError(t, err)
+
+	type short struct {
+		Action, Test, Output, OutputType string
+	}
+	var actual []short
+	for _, e := range events {
+		assert.Equal(t, "Gateway Tests", e.Package)
+		assert.NotNil(t, e.Time)
+		actual = append(actual, short{e.Action, e.Test, e.Output, e.OutputType})
+	}
+
+	assert.Equal(t, []short{
+		{"start", "", "", ""},
+		{"run", "TestA", "", ""},
+		{"output", "TestA", "=== RUN   TestA\n", "frame"},
+		{"output", "TestA", "    a_test.go:3: hello\n", ""},
+		{"run", "TestA/sub_one", "", ""},
+		{"output", "TestA/sub_one", "=== RUN   TestA/sub_one\n", "frame"},
+		{"output", "TestA/sub_one", "    a_test.go:3: bad\n", "error"},
+		{"output", "TestA/sub_one", "        more details\n", "error-continue"},
+		{"output", "TestA/sub_one", "--- FAIL: TestA/sub_one (0.25s)\n", "frame"},
+		{"fail", "TestA/sub_one", "", ""},
+		{"run", "TestA/s2", "", ""},
+		{"output", "TestA/s2", "=== RUN   TestA/s2\n", "frame"},
+		{"output", "TestA/s2", "    a_test.go:3: nah\n", ""},
+		{"output", "TestA/s2", "--- SKIP: TestA/s2 (0.00s)\n", "frame"},
+		{"skip", "TestA/s2", "", ""},
+		{"output", "TestA", "--- FAIL: TestA (0.50s)\n", "frame"},
+		{"fail", "TestA", "", ""},
+		{"run", "TestB", "", ""},
+		{"output", "TestB", "=== RUN   TestB\n", "frame"},
+		{"output", "TestB", "--- PASS: TestB (0.00s)\n", "frame"},
+		{"pass", "TestB", "", ""},
+		{"output", "", "FAIL\n", "frame"},
+		{"fail", "", "", ""},
+	}, actual)
+
+	require.NotNil(t, events[9].Elapsed)
+	assert.Equal(t, 0.25, *events[9].Elapsed)
+	require.NotNil(t, events[16].Elapsed)
+	assert.Equal(t, 0.5, *events[16].Elapsed)
+}
+
+func TestSort(t *testing.T) {
+	e := func(action, test, output string) Event {
+		outputType := ""
+		if strings.HasPrefix(output, "---") || strings.HasPrefix(output, "===") {
+			outputType = "frame"
+		}
+		return Event{Action: action, Test: test, Output: output, OutputType: outputType}
+	}
+
+	events := []Event{
+		e("start", "", ""),
+		e("run", "TestA", ""),
+		e("run", "TestA/one", ""),
+		e("run", "TestA/two", ""),
+		e("output", "TestA/two", "two\n"),
+		e("output", "TestA/one", "one\n"),
+		e("output", "TestA/two", "--- PASS: TestA/two (0.00s)\n"),
+		e("pass", "TestA/two", ""),
+		e("output", "TestA/one", "--- FAIL: TestA/one (0.00s)\n"),
+		e("fail", "TestA/one", ""),
+		e("output", "TestA", "--- FAIL: TestA (0.00s)\n"),
+		e("fail", "TestA", ""),
+		e("output", "", "FAIL\n"),
+		e("fail", "", ""),
+	}
+
+	assert.Equal(t, []Event{
+		e("start", "", ""),
+		e("run", "TestA", ""),
+		e("run", "TestA/one", ""),
+		e("output", "TestA/one", "one\n"),
+		e("output", "TestA/one", "--- FAIL: TestA/one (0.00s)\n"),
+		e("fail", "TestA/one", ""),
+		e("run", "TestA/two", ""),
+		e("output", "TestA/two", "two\n"),
+		e("output", "TestA/two", "--- PASS: TestA/two (0.00s)\n"),
+		e("pass", "TestA/two", ""),
+		e("output", "TestA", "--- FAIL: TestA (0.00s)\n"),
+		e("fail", "TestA", ""),
+		e("output", "", "FAIL\n"),
+		e("fail", "", ""),
+	}, Sort(events))
+}
@@ -0,0 +1,247 @@
+// Package watch re-runs the conformance tests when the gateway under test
+// restarts, and tracks the checks whose outcome flipped from one run to the
+// next.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/gateway-conformance/tooling/results"
+)
+
+// Health returns a channel receiving a value every time the gateway answers
+// at url after it stopped answering, i.e. after it restarted. The url is
+// polled every interval until ctx is done. The gateway answers when it
+// responds with a status below 500.
+func Health(ctx context.Context, client *http.Client, url string, interval time.Duration) <-chan struct{} {
+	restarts := make(chan struct{})
+	go func() {
+		defer close(restarts)
+
+		up := true
+		for wait(ctx, interval) {
+			ok := answers(ctx, client, url)
+			if ok && !up {
+				select {
+				case restarts <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			up = ok
+		}
+	}()
+	return restarts
+}
+
+func answers(ctx context.Context, client *http.Client, url string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	res.Body.Close()
+	return res.StatusCode < http.StatusInternalServerError
+}
+
+// File returns a channel receiving a value every time the modification time
+// of the file at path changes, e.g. when the build script of the gateway
+// touches it. The file is checked every interval until ctx is done, the
+// creation of a file that does not exist yet is a change.
+func File(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	changes := make(chan struct{})
+	last := modTime(path)
+	go func() {
+		defer close(changes)
+
+		for wait(ctx, interval) {
+			mod := modTime(path)
+			if mod.IsZero() || mod.Equal(last) {
+				continue
+			}
+			last = mod
+			select {
+			case changes <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// wait waits for d and reports whether ctx is still running.
+func wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Flip is a check whose outcome changed since the previous run.
+type Flip struct {
+	// Test is the path of the SugarTest of the check.
+	Test string
+	// Check is the name of the check, it is empty when the test failed
+	// without running the check that flipped, e.g. when the request
+	// failed.
+	Check string
+	From  results.Outcome
+	To    results.Outcome
+	// Reason explains why the check fails, when it does.
+	Reason string
+}
+
+// Fixed reports whether the check failed and now passes.
+func (f Flip) Fixed() bool {
+	return f.To == results.Pass
+}
+
+func (f Flip) String() string {
+	name := f.Test
+	if f.Check != "" {
+		name += ": " + f.Check
+	}
+	if f.Fixed() {
+		return fmt.Sprintf("✅ %s now passes", name)
+	}
+	if f.Reason == "" {
+		return fmt.Sprintf("❌ %s now fails", name)
+	}
+	return fmt.Sprintf("❌ %s now fails: %s", name, f.Reason)
+}
+
+// Tracker compares the outcomes of the checks of every run with the ones of
+// the previous run. It is safe for concurrent use.
+type Tracker struct {
+	mu       sync.Mutex
+	previous map[string]results.Outcome
+	current  map[string]results.Outcome
+	failing  []string
+	flips    []Flip
+	// last holds the results of the current run, by test path.
+	last map[string]results.Test
+}
+
+// NewTracker returns a tracker without previous run, nothing flips in its
+// first run.
+func NewTracker() *Tracker {
+	return &Tracker{current: map[string]results.Outcome{}, last: map[string]results.Test{}}
+}
+
+// Add records the result of a test of the current run and returns the
+// checks that flipped. Skipped tests and checks never flip.
+func (t *Tracker) Add(test results.Test) []Flip {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.last[test.Path] = test
+
+	var flips []Flip
+	seen := map[string]int{}
+	for _, c := range test.Checks {
+		// Checks are identified by their name, and their rank among
+		// the checks with the same name.
+		seen[c.Name]++
+		key := fmt.Sprintf("%s\x00%s\x00%d", test.Path, c.Name, seen[c.Name])
+		t.current[key] = c.Outcome
+		if from, ok := t.previous[key]; ok && flipped(from, c.Outcome) {
+			flips = append(flips, Flip{Test: test.Path, Check: c.Name, From: from, To: c.Outcome, Reason: c.Reason})
+		}
+	}
+
+	t.current[test.Path] = test.Outcome
+	if from, ok := t.previous[test.Path]; ok && len(flips) == 0 && flipped(from, test.Outcome) {
+		flips = append(flips, Flip{Test: test.Path, From: from, To: test.Outcome, Reason: test.Reason})
+	}
+
+	t.flips = append(t.flips, flips...)
+	return flips
+}
+
+func flipped(from, to results.Outcome) bool {
+	return from != to && from != results.Skip && to != results.Skip
+}
+
+// Next ends the current run and returns every check that flipped during the
+// run.
+func (t *Tracker) Next() []Flip {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// The tests that failed and did not run again, e.g. when a filter
+	// left them out, are still failing.
+	var failing []string
+	for _, path := range t.failing {
+		if _, ran := t.last[path]; !ran {
+			failing = append(failing, path)
+		}
+	}
+	for path, test := range t.last {
+		if test.Outcome == results.Fail {
+			failing = append(failing, path)
+		}
+	}
+	sort.Strings(failing)
+	t.failing = failing
+
+	// Likewise, the outcomes of the tests and checks that did not run
+	// are compared with their next run.
+	for key, outcome := range t.previous {
+		if _, ran := t.current[key]; !ran {
+			t.current[key] = outcome
+		}
+	}
+
+	flips := t.flips
+	t.previous, t.current = t.current, map[string]results.Outcome{}
+	t.last = map[string]results.Test{}
+	t.flips = nil
+	return flips
+}
+
+// Failing returns the paths of the tests that failed in the previous run, or
+// that failed before and did not run since.
+func (t *Tracker) Failing() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]string(nil), t.failing...)
+}
+
+// TopLevel returns the names of the top-level tests of the test paths.
+func TopLevel(paths []string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, path := range paths {
+		name, _, _ := strings.Cut(path, "/")
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
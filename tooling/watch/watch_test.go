@@ -0,0 +1,123 @@
+package watch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ipfs/gateway-conformance/tooling/results"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func result(path string, outcome results.Outcome, checks ...results.Outcome) results.Test {
+	t := results.Test{Path: path, Outcome: outcome}
+	for _, c := range checks {
+		t.Checks = append(t.Checks, results.Check{Name: "Header Etag", Outcome: c})
+	}
+	return t
+}
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker()
+
+	// Nothing flips in the first run.
+	assert.Empty(t, tracker.Add(result("TestA/GET", results.Fail, results.Pass, results.Fail)))
+	assert.Empty(t, tracker.Add(result("TestB/GET", results.Pass, results.Pass)))
+	assert.Empty(t, tracker.Add(result("TestC/GET", results.Pass, results.Pass)))
+	assert.Empty(t, tracker.Next())
+	assert.Equal(t, []string{"TestA/GET"}, tracker.Failing())
+	assert.Equal(t, []string{"TestA"}, TopLevel(tracker.Failing()))
+
+	// Checks with the same name are told apart by their rank.
+	assert.Equal(t, []Flip{
+		{Test: "TestA/GET", Check: "Header Etag", From: results.Fail, To: results.Pass},
+	}, tracker.Add(result("TestA/GET", results.Pass, results.Pass, results.Pass)))
+	// A test failing before its checks run flips itself.
+	assert.Equal(t, []Flip{
+		{Test: "TestB/GET", From: results.Pass, To: results.Fail},
+	}, tracker.Add(results.Test{Path: "TestB/GET", Outcome: results.Fail}))
+	// Skipped tests do not flip.
+	assert.Empty(t, tracker.Add(result("TestC/GET", results.Skip)))
+
+	flips := tracker.Next()
+	require.Len(t, flips, 2)
+	assert.True(t, flips[0].Fixed())
+	assert.Equal(t, "✅ TestA/GET: Header Etag now passes", flips[0].String())
+	assert.Equal(t, "❌ TestB/GET now fails", flips[1].String())
+	assert.Equal(t, []string{"TestB/GET"}, tracker.Failing())
+}
+
+func TestTrackerKeepsFailingTestsThatDidNotRun(t *testing.T) {
+	tracker := NewTracker()
+	tracker.Add(result("TestA/GET", results.Fail))
+	tracker.Add(result("TestB/GET", results.Fail))
+	tracker.Next()
+	require.Equal(t, []string{"TestA/GET", "TestB/GET"}, tracker.Failing())
+
+	// TestA/GET does not run in the next pass, it is still failing.
+	tracker.Add(result("TestB/GET", results.Pass))
+	assert.Len(t, tracker.Next(), 1)
+	assert.Equal(t, []string{"TestA/GET"}, tracker.Failing())
+
+	// Until it runs and passes.
+	assert.Equal(t, []Flip{
+		{Test: "TestA/GET", From: results.Fail, To: results.Pass},
+	}, tracker.Add(result("TestA/GET", results.Pass)))
+	tracker.Next()
+	assert.Empty(t, tracker.Failing())
+}
+
+func TestHealth(t *testing.T) {
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	restarts := Health(ctx, srv.Client(), srv.URL, 10*time.Millisecond)
+
+	select {
+	case <-restarts:
+		t.Fatal("restart detected while the gateway is up")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	down.Store(true)
+	time.Sleep(50 * time.Millisecond)
+	down.Store(false)
+	select {
+	case <-restarts:
+	case <-time.After(time.Second):
+		t.Fatal("restart not detected")
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trigger")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := File(ctx, path, 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("creation not detected")
+	}
+
+	cancel()
+	_, ok := <-changes
+	assert.False(t, ok)
+}